@@ -0,0 +1,34 @@
+package singleopen
+
+// RefCount returns the number of references to the shared
+// file of name, zero if the file isn't shared.
+func (fsys *FS) RefCount(name string) int {
+	fsys.mu.Lock()
+	key := fsys.sharedKey(name)
+	fsys.mu.Unlock()
+	f, ok := fsys.shard(key).load(key)
+	if !ok {
+		return 0
+	}
+	return int(f.refc.Load())
+}
+
+// Len returns the number of shared files.
+func (fsys *FS) Len() int {
+	n := 0
+	fsys.shard("") // initialize
+	for i := range fsys.shards {
+		fsys.shards[i].each(func(*file) { n++ })
+	}
+	return n
+}
+
+// CacheLen returns the number of files in the close cache.
+func (fsys *FS) CacheLen() int {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if fsys.cache == nil {
+		return 0
+	}
+	return fsys.cache.len()
+}
@@ -0,0 +1,40 @@
+package singleopen
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestAccessors(t *testing.T) {
+	fsys, err := New(fstest.MapFS{
+		"a": &fstest.MapFile{},
+		"b": &fstest.MapFile{},
+	}, WithKeepLast(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a1, _ := fsys.Open("a")
+	a2, _ := fsys.Open("a")
+	b, _ := fsys.Open("b")
+	if n := fsys.RefCount("a"); n != 2 {
+		t.Errorf("got ref count %d, want 2", n)
+	}
+	if n := fsys.Len(); n != 2 {
+		t.Errorf("got %d shared files, want 2", n)
+	}
+	b.Close()
+	if n, m := fsys.Len(), fsys.CacheLen(); n != 1 || m != 1 {
+		t.Errorf("got %d shared and %d cached files, want 1 and 1", n, m)
+	}
+	if n := fsys.RefCount("b"); n != 0 {
+		t.Errorf("got ref count %d of cached file, want 0", n)
+	}
+	a1.Close()
+	a2.Close()
+	if n, m := fsys.Len(), fsys.CacheLen(); n != 0 || m != 2 {
+		t.Errorf("got %d shared and %d cached files, want 0 and 2", n, m)
+	}
+	if n := fsys.RefCount("missing"); n != 0 {
+		t.Errorf("got ref count %d of missing file, want 0", n)
+	}
+}
@@ -0,0 +1,195 @@
+package singleopen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dwlnetnl/singleopen/internal/compat"
+)
+
+// SyncPolicy is when appenders sync the shared file, see
+// WithAppendSync.
+type SyncPolicy int
+
+const (
+	SyncNever   SyncPolicy = iota // only by Appender.Sync, the default
+	SyncOnClose                   // when the last appender is closed
+	SyncAlways                    // after every write
+)
+
+// WithAppendSync returns an Option that sets when the files
+// opened by OpenAppend are synced.
+func WithAppendSync(p SyncPolicy) Option {
+	return func(f *FS) error {
+		if p < SyncNever || p > SyncAlways {
+			return fmt.Errorf("singleopen: invalid sync policy %d", p)
+		}
+		f.appendSync = p
+		return nil
+	}
+}
+
+// appendFile is a file opened for appending shared by
+// appenders.
+type appendFile struct {
+	name string
+	key  string
+	refc int // protected by FS.mu
+
+	mu  sync.Mutex // held while opening, serializes writes
+	f   fs.File
+	err error // of opening
+}
+
+// An Appender appends to a file shared with the other
+// appenders of the file, see OpenAppend.
+type Appender struct {
+	fsys   *FS
+	a      *appendFile
+	closed atomic.Bool
+}
+
+// OpenAppend opens the named file for appending, creating it
+// with perm if needed. The underlying file system must
+// implement WriteFS. All appenders of a file share one handle
+// opened with os.O_APPEND and every Write is written as a
+// whole, writes of appenders are not interleaved. The handle
+// is closed when the last appender is closed and the file is
+// invalidated, see Invalidate. While the file has appenders,
+// OpenFile can't open it for writing.
+func (fsys *FS) OpenAppend(name string, perm fs.FileMode) (*Appender, error) {
+	if fsys.closed.Load() {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrClosed}
+	}
+	wfs, ok := fsys.base().(WriteFS)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: compat.ErrUnsupported}
+	}
+	key := fsys.key(name)
+	fsys.mu.Lock()
+	if a, ok := fsys.appends[key]; ok {
+		a.refc++
+		fsys.mu.Unlock()
+		a.mu.Lock()
+		err := a.err
+		a.mu.Unlock()
+		if err != nil {
+			fsys.releaseAppend(a)
+			return nil, err
+		}
+		fsys.debug("reuse file for appending", name)
+		return &Appender{fsys: fsys, a: a}, nil
+	}
+	if _, ok := fsys.writers[key]; ok {
+		fsys.mu.Unlock()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrWriting}
+	}
+	a := &appendFile{name: name, key: key, refc: 1}
+	a.mu.Lock()
+	if fsys.appends == nil {
+		fsys.appends = make(map[string]*appendFile)
+	}
+	if fsys.writers == nil {
+		fsys.writers = make(map[string]struct{})
+	}
+	fsys.appends[key] = a
+	fsys.writers[key] = struct{}{}
+	fsys.mu.Unlock()
+
+	if a.err = fsys.checkReaders(name); a.err == nil {
+		a.f, a.err = wfs.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, perm)
+	}
+	err := a.err
+	a.mu.Unlock()
+	if err != nil {
+		fsys.releaseAppend(a)
+		return nil, err
+	}
+	fsys.debug("open file for appending", name)
+	return &Appender{fsys: fsys, a: a}, nil
+}
+
+// releaseAppend releases a reference to a and reports whether
+// it was the last one. The file isn't shared anymore then.
+func (fsys *FS) releaseAppend(a *appendFile) bool {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	a.refc--
+	if a.refc > 0 {
+		return false
+	}
+	delete(fsys.appends, a.key)
+	delete(fsys.writers, a.key)
+	return true
+}
+
+// Name returns the name of the file.
+func (w *Appender) Name() string {
+	return w.a.name
+}
+
+// Write appends p to the file as a whole.
+func (w *Appender) Write(p []byte) (int, error) {
+	if w.closed.Load() {
+		return 0, &fs.PathError{Op: "write", Path: w.a.name, Err: fs.ErrClosed}
+	}
+	fw, ok := w.a.f.(io.Writer)
+	if !ok {
+		return 0, &fs.PathError{Op: "write", Path: w.a.name, Err: compat.ErrUnsupported}
+	}
+	w.a.mu.Lock()
+	defer w.a.mu.Unlock()
+	if err := w.fsys.lockWrite(context.Background(), w.a.name, w.a.f, lockExclusive); err != nil {
+		return 0, err
+	}
+	n, err := fw.Write(p)
+	if err == nil && w.fsys.appendSync == SyncAlways {
+		err = w.a.sync()
+	}
+	if lerr := w.fsys.lockWrite(context.Background(), w.a.name, w.a.f, lockNone); err == nil {
+		err = lerr
+	}
+	return n, err
+}
+
+// Sync commits the file to stable storage.
+func (w *Appender) Sync() error {
+	if w.closed.Load() {
+		return &fs.PathError{Op: "sync", Path: w.a.name, Err: fs.ErrClosed}
+	}
+	w.a.mu.Lock()
+	defer w.a.mu.Unlock()
+	return w.a.sync()
+}
+
+// sync syncs the file, a.mu must be held.
+func (a *appendFile) sync() error {
+	s, ok := a.f.(interface{ Sync() error })
+	if !ok {
+		return &fs.PathError{Op: "sync", Path: a.name, Err: compat.ErrUnsupported}
+	}
+	return s.Sync()
+}
+
+// Close closes the appender. The shared file is closed when
+// it's the last appender of the file.
+func (w *Appender) Close() error {
+	if w.closed.Swap(true) {
+		return fs.ErrClosed
+	}
+	if !w.fsys.releaseAppend(w.a) {
+		return nil
+	}
+	var err error
+	if w.fsys.appendSync == SyncOnClose {
+		err = w.a.sync()
+	}
+	err = errors.Join(err, w.a.f.Close())
+	return errors.Join(err, w.fsys.Invalidate(w.a.name))
+}
@@ -0,0 +1,82 @@
+//go:build go1.25
+
+package singleopen
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+func TestOpenAppend(t *testing.T) {
+	root, err := os.OpenRoot(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+	fsys, err := New(RootFS(root), WithAppendSync(SyncOnClose))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	const n = 8
+	line := strings.Repeat("x", 100) + "\n"
+	var wg sync.WaitGroup
+	appenders := make([]*Appender, n)
+	for i := range appenders {
+		w, err := fsys.OpenAppend("log", 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		appenders[i] = w
+	}
+	if _, err := fsys.OpenFile("log", os.O_WRONLY, 0); !errors.Is(err, ErrWriting) {
+		t.Errorf("writer while appending: got %v, want ErrWriting", err)
+	}
+	for _, w := range appenders {
+		wg.Add(1)
+		go func(w *Appender) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if _, err := io.WriteString(w, line); err != nil {
+					t.Error(err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	if err := appenders[0].Sync(); err != nil {
+		t.Error(err)
+	}
+	for _, w := range appenders {
+		if err := w.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := appenders[0].Write([]byte(line)); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("write after close: got %v, want ErrClosed", err)
+	}
+
+	b, err := fsys.ReadFile("log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Repeat(line, n*100); string(b) != want {
+		t.Errorf("got %d bytes, want %d", len(b), len(want))
+	}
+	w, err := fsys.OpenFile("log", os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("writer after appending: %v", err)
+	}
+	w.Close()
+
+	if _, err := New(fstest.MapFS{}, WithAppendSync(SyncAlways+1)); err == nil {
+		t.Error("invalid sync policy: got nil error")
+	}
+}
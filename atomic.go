@@ -0,0 +1,98 @@
+package singleopen
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/dwlnetnl/singleopen/internal/compat"
+)
+
+// RenameFS is a WriteFS that renames and removes files.
+type RenameFS interface {
+	WriteFS
+
+	// Rename renames oldname to newname like os.Rename.
+	Rename(oldname, newname string) error
+
+	// Remove removes the named file like os.Remove.
+	Remove(name string) error
+}
+
+// WriteFileAtomic writes the data read from r to the named
+// file, creating it with perm if needed. The data is written
+// to a temporary file in the same directory that is synced
+// and renamed to name, so readers see either the old or the
+// new file. The underlying file system must implement
+// RenameFS. On success the file is invalidated, see
+// Invalidate: later opens read the new file while current
+// readers keep reading the old one. Like OpenFile, the file
+// has no other writer meanwhile.
+func (fsys *FS) WriteFileAtomic(name string, r io.Reader, perm fs.FileMode) error {
+	if fsys.closed.Load() {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrClosed}
+	}
+	rfs, ok := fsys.base().(RenameFS)
+	if !ok {
+		return &fs.PathError{Op: "write", Path: name, Err: compat.ErrUnsupported}
+	}
+	key := fsys.key(name)
+	if err := fsys.lockWriter(name, key); err != nil {
+		return err
+	}
+	defer fsys.unlockWriter(key)
+
+	tmp, err := writeTemp(rfs, name, r, perm)
+	if err != nil {
+		return err
+	}
+	if err := rfs.Rename(tmp, name); err != nil {
+		rfs.Remove(tmp)
+		return err
+	}
+	fsys.debug("write file atomically", name)
+	return fsys.Invalidate(name)
+}
+
+// writeTemp writes r to a new temporary file next to name and
+// returns its name. The file is removed on error.
+func writeTemp(rfs RenameFS, name string, r io.Reader, perm fs.FileMode) (_ string, err error) {
+	dir, base := path.Split(name)
+	var tmp string
+	var f fs.File
+	for i := 0; i < 10000; i++ {
+		tmp = dir + "." + base + ".tmp" + strconv.FormatUint(rand.Uint64(), 36)
+		f, err = rfs.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+		if !errors.Is(err, fs.ErrExist) {
+			break
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			rfs.Remove(tmp)
+		}
+	}()
+	w, ok := f.(io.Writer)
+	if !ok {
+		return "", &fs.PathError{Op: "write", Path: tmp, Err: compat.ErrUnsupported}
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		return "", err
+	}
+	if s, ok := f.(interface{ Sync() error }); ok {
+		if err := s.Sync(); err != nil {
+			return "", err
+		}
+	}
+	return tmp, nil
+}
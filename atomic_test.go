@@ -0,0 +1,68 @@
+//go:build go1.25
+
+package singleopen
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"testing/iotest"
+
+	"github.com/dwlnetnl/singleopen/internal/compat"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+	fsys, err := New(RootFS(root), WithKeepLast(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+	old, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer old.Close()
+
+	if err := fsys.WriteFileAtomic("file", strings.NewReader("new"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := fsys.ReadFile("file"); err != nil || string(b) != "new" {
+		t.Errorf("got %q, %v, want new", b, err)
+	}
+	if b, err := io.ReadAll(old); err != nil || string(b) != "old" {
+		t.Errorf("got %q, %v from old file, want old", b, err)
+	}
+
+	errRead := errors.New("read failed")
+	r := io.MultiReader(strings.NewReader("partial"), iotest.ErrReader(errRead))
+	if err := fsys.WriteFileAtomic("file", r, 0o644); !errors.Is(err, errRead) {
+		t.Errorf("got %v, want %v", err, errRead)
+	}
+	if b, err := fsys.ReadFile("file"); err != nil || string(b) != "new" {
+		t.Errorf("got %q, %v after failed write, want new", b, err)
+	}
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 1 {
+		t.Errorf("got %v, %v in directory, want only file", entries, err)
+	}
+
+	mfs, err := New(fstest.MapFS{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mfs.WriteFileAtomic("file", strings.NewReader(""), 0o644); !errors.Is(err, compat.ErrUnsupported) {
+		t.Errorf("got %v, want ErrUnsupported", err)
+	}
+}
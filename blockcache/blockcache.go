@@ -0,0 +1,200 @@
+// Package blockcache provides a file system that caches blocks
+// of files read at offsets in memory. Use it as the underlying
+// file system of a singleopen.FS to serve hot regions of shared
+// files from memory:
+//
+//	bc, err := blockcache.New(os.DirFS(dir), 64<<10, 256<<20)
+//	fsys, err := singleopen.New(bc)
+//
+// Blocks are keyed by the name, size and modification time of
+// a file when it's opened, so a file that changed and is opened
+// again doesn't read blocks of its old contents.
+package blockcache
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dwlnetnl/singleopen/cache"
+)
+
+// FS is a file system that caches blocks of files opened from
+// an underlying file system. The capacity is shared by all
+// files.
+type FS struct {
+	fsys      fs.FS
+	blockSize int64
+
+	mu     sync.Mutex // protects blocks
+	blocks *cache.Cache[Key, []byte]
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// Key identifies a block of a version of a file.
+type Key struct {
+	Space   string // empty for the blocks of files of the FS
+	Name    string
+	Size    int64
+	ModTime time.Time
+	Block   int64
+}
+
+var _ fs.FS = (*FS)(nil)
+
+// New returns a FS that caches blocks of blockSize bytes of
+// files of fsys up to a total of capacity bytes.
+func New(fsys fs.FS, blockSize int, capacity int64) (*FS, error) {
+	if fsys == nil {
+		return nil, errors.New("blockcache: nil file system")
+	}
+	if blockSize <= 0 {
+		return nil, fmt.Errorf("blockcache: invalid block size %d", blockSize)
+	}
+	if capacity < int64(blockSize) {
+		return nil, fmt.Errorf("blockcache: capacity %d below block size", capacity)
+	}
+	blocks := cache.New[Key, []byte](0)
+	blocks.MaxSize = capacity
+	blocks.SizeOf = func(_ Key, b []byte) int64 { return int64(cap(b)) }
+	return &FS{fsys: fsys, blockSize: int64(blockSize), blocks: blocks}, nil
+}
+
+// Stats holds statistics of a FS.
+type Stats struct {
+	Hits   int64 // blocks read from the cache
+	Misses int64 // blocks read from files or loaded
+	Blocks int   // blocks in the cache
+	Size   int64 // total size of blocks in the cache
+}
+
+// Stats returns statistics of c.
+func (c *FS) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Stats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+		Blocks: c.blocks.Len(),
+		Size:   c.blocks.Size(),
+	}
+}
+
+// Purge drops all cached blocks.
+func (c *FS) Purge() {
+	c.mu.Lock()
+	c.blocks.Clear()
+	c.mu.Unlock()
+}
+
+// Open opens the named file. If the file is a regular file
+// that implements io.ReaderAt, its reads at offsets are cached.
+func (c *FS) Open(name string) (fs.File, error) {
+	f, err := c.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	ra, ok := f.(io.ReaderAt)
+	if !ok {
+		return f, nil
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if !fi.Mode().IsRegular() {
+		return f, nil
+	}
+	return &file{
+		File: f,
+		ra:   ra,
+		fs:   c,
+		name: name,
+		size: fi.Size(),
+		mod:  fi.ModTime(),
+	}, nil
+}
+
+// file reads at offsets through the block cache.
+type file struct {
+	fs.File
+	ra   io.ReaderAt
+	fs   *FS
+	name string
+	size int64 // at open
+	mod  time.Time
+}
+
+// Unwrap returns the file of the underlying file system.
+func (f *file) Unwrap() fs.File {
+	return f.File
+}
+
+// ReadAt reads len(p) bytes at offset off from cached blocks,
+// reading blocks that are not cached from the file.
+func (f *file) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrInvalid}
+	}
+	bs := f.fs.blockSize
+	for n < len(p) {
+		pos := off + int64(n)
+		b, err := f.block(pos / bs)
+		skip := pos % bs
+		if skip < int64(len(b)) {
+			n += copy(p[n:], b[skip:])
+		}
+		if n == len(p) {
+			break
+		}
+		if err != nil {
+			return n, err
+		}
+		if int64(len(b)) < bs {
+			return n, io.EOF // short block ends the file
+		}
+	}
+	return n, nil
+}
+
+// block returns block i of the file. A block at the end of the
+// file is short.
+func (f *file) block(i int64) ([]byte, error) {
+	bs := f.fs.blockSize
+	return f.fs.Load(Key{Name: f.name, Size: f.size, ModTime: f.mod, Block: i}, func() ([]byte, error) {
+		b := make([]byte, bs)
+		n, err := f.ra.ReadAt(b, i*bs)
+		return b[:n:n], err
+	})
+}
+
+// Load returns the block k from the cache or, if it isn't
+// cached, from load. A block is cached unless load returns an
+// error other than io.EOF. Packages that derive blocks from
+// files, like decompressed frames, cache them in the capacity
+// of c by a Key with a Space of their own.
+func (c *FS) Load(k Key, load func() ([]byte, error)) ([]byte, error) {
+	c.mu.Lock()
+	b, ok := c.blocks.Get(k)
+	c.mu.Unlock()
+	if ok {
+		c.hits.Add(1)
+		return b, nil
+	}
+	c.misses.Add(1)
+	b, err := load()
+	if err != nil && err != io.EOF {
+		return b, err
+	}
+	c.mu.Lock()
+	c.blocks.Add(k, b)
+	c.mu.Unlock()
+	return b, err
+}
@@ -0,0 +1,62 @@
+package blockcache
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dwlnetnl/singleopen"
+)
+
+func TestBlockCache(t *testing.T) {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	mfs := fstest.MapFS{"file": &fstest.MapFile{Data: data}}
+	bc, err := New(mfs, 64, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys, err := singleopen.New(bc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		for _, tc := range []struct{ off, n int }{{0, 10}, {60, 10}, {100, 200}, {990, 20}} {
+			p := make([]byte, tc.n)
+			n, err := fsys.ReadAt("file", p, int64(tc.off))
+			want := data[tc.off:]
+			if len(want) > tc.n {
+				want = want[:tc.n]
+			}
+			if err != nil && err != io.EOF || !bytes.Equal(p[:n], want) {
+				t.Errorf("read %d at %d: got %d bytes, %v", tc.n, tc.off, n, err)
+			}
+		}
+	}
+	s := bc.Stats()
+	if s.Misses != 6 || s.Hits != 10 || s.Blocks != 6 || s.Size != 5*64+40 {
+		t.Errorf("got %+v", s)
+	}
+
+	got, err := fsys.ReadFile("file")
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("got %d bytes, %v", len(got), err)
+	}
+	if s := bc.Stats(); s.Size > 512 {
+		t.Errorf("got size %d over capacity", s.Size)
+	}
+	bc.Purge()
+	if s := bc.Stats(); s.Blocks != 0 {
+		t.Errorf("got %d blocks after purge", s.Blocks)
+	}
+	if err := fstest.TestFS(bc, "file"); err != nil {
+		t.Error(err)
+	}
+	if _, err := New(mfs, 64, 10); err == nil {
+		t.Error("expected error for capacity below block size")
+	}
+}
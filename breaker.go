@@ -0,0 +1,145 @@
+package singleopen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned (wrapped) by opens of files
+// whose circuit breaker tripped.
+var ErrCircuitOpen = errors.New("singleopen: circuit open")
+
+// CircuitBreaker configures failing fast for files that fail
+// to open or read repeatedly. Hooks are called without
+// holding internal locks.
+type CircuitBreaker struct {
+	// Threshold is the number of consecutive failures of a
+	// key that trips its breaker.
+	Threshold int
+
+	// Cooldown is the time opens fail fast after a trip.
+	// After the cooldown, a single failure trips the breaker
+	// again until an open or read succeeds.
+	Cooldown time.Duration
+
+	// Key returns the key whose failures are counted for the
+	// named file, like a directory prefix. If nil, failures
+	// are counted per file.
+	Key func(name string) string
+
+	// OnTrip is called when the breaker of key trips.
+	OnTrip func(key string, err error)
+
+	// OnReset is called when the breaker of key closes again
+	// after a success.
+	OnReset func(key string)
+}
+
+// WithCircuitBreaker returns an Option that fails opens fast
+// as configured by cb.
+func WithCircuitBreaker(cb CircuitBreaker) Option {
+	return func(f *FS) error {
+		if cb.Threshold < 1 {
+			return fmt.Errorf("singleopen: invalid circuit breaker threshold %d", cb.Threshold)
+		}
+		if cb.Cooldown <= 0 {
+			return fmt.Errorf("singleopen: invalid circuit breaker cooldown %v", cb.Cooldown)
+		}
+		f.breaker = &breaker{cfg: cb}
+		return nil
+	}
+}
+
+// breaker tracks failures per key. It has its own lock so
+// reads can report without taking FS.mu.
+type breaker struct {
+	cfg      CircuitBreaker
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+type circuit struct {
+	fails   int
+	tripped bool
+	until   time.Time // fail fast until
+}
+
+func (b *breaker) key(name string) string {
+	if b.cfg.Key != nil {
+		return b.cfg.Key(name)
+	}
+	return name
+}
+
+// allow returns ErrCircuitOpen if opens of name fail fast.
+func (b *breaker) allow(name string) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.circuits[b.key(name)]
+	if ok && time.Now().Before(c.until) {
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// report counts a failed (err not nil) or successful
+// operation on name.
+func (b *breaker) report(name string, err error) {
+	if b == nil || err != nil && !isFailure(err) {
+		return
+	}
+	key := b.key(name)
+	b.mu.Lock()
+	c, ok := b.circuits[key]
+	if err == nil {
+		if !ok {
+			b.mu.Unlock()
+			return
+		}
+		delete(b.circuits, key)
+		b.mu.Unlock()
+		if c.tripped && b.cfg.OnReset != nil {
+			b.cfg.OnReset(key)
+		}
+		return
+	}
+	if !ok {
+		if b.circuits == nil {
+			b.circuits = make(map[string]*circuit)
+		}
+		c = &circuit{}
+		b.circuits[key] = c
+	}
+	c.fails++
+	trip := c.fails >= b.cfg.Threshold || c.tripped
+	if trip {
+		c.tripped = true
+		c.until = time.Now().Add(b.cfg.Cooldown)
+	}
+	b.mu.Unlock()
+	if trip && b.cfg.OnTrip != nil {
+		b.cfg.OnTrip(key, err)
+	}
+}
+
+// isFailure reports whether err indicates a failing file
+// system rather than a bad request or end of file.
+func isFailure(err error) bool {
+	return !errors.Is(err, io.EOF) &&
+		!errors.Is(err, fs.ErrNotExist) &&
+		!errors.Is(err, fs.ErrPermission) &&
+		!errors.Is(err, fs.ErrInvalid) &&
+		!errors.Is(err, fs.ErrClosed) &&
+		!errors.Is(err, ErrCircuitOpen) &&
+		!errors.Is(err, ErrOpenBusy) &&
+		!errors.Is(err, context.Canceled) &&
+		!errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,56 @@
+package singleopen
+
+import (
+	"errors"
+	"path"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	ffs := &flakyFS{FS: fstest.MapFS{
+		"dir/file1": &fstest.MapFile{},
+		"dir/file2": &fstest.MapFile{},
+	}}
+	ffs.n.Store(2)
+	var trips, resets atomic.Int32
+	fsys, err := New(ffs, WithCircuitBreaker(CircuitBreaker{
+		Threshold: 2,
+		Cooldown:  time.Hour,
+		Key:       path.Dir,
+		OnTrip:    func(string, error) { trips.Add(1) },
+		OnReset:   func(string) { resets.Add(1) },
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := fsys.Open("dir/file1"); !errors.Is(err, syscall.EIO) {
+			t.Fatalf("got %v, want EIO", err)
+		}
+	}
+	if _, err := fsys.Open("dir/file2"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("got %v, want circuit open", err)
+	}
+	if n := ffs.opens.Load(); n != 2 {
+		t.Errorf("got %d opens, want 2", n)
+	}
+
+	// end the cooldown
+	fsys.breaker.mu.Lock()
+	for _, c := range fsys.breaker.circuits {
+		c.until = time.Time{}
+	}
+	fsys.breaker.mu.Unlock()
+	f, err := fsys.Open("dir/file2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if trips.Load() != 1 || resets.Load() != 1 {
+		t.Errorf("got %d trips and %d resets, want 1 each", trips.Load(), resets.Load())
+	}
+}
@@ -0,0 +1,194 @@
+package singleopen
+
+import "time"
+
+// closeCache holds the files that are kept open after their
+// last reference is closed. It's protected by FS.mu.
+type closeCache struct {
+	policy     CachePolicy
+	maxEntries int   // zero means no limit
+	maxSize    int64 // zero means no limit
+	files      map[string]*file
+	size       int64
+	parts      []*cachePart // see WithCachePartition
+	low, high  CachePolicy  // files by priority, see WithPriority
+
+	// onEvicted is called when a file is evicted.
+	onEvicted func(f *file)
+}
+
+// newCloseCache returns a close cache with policy p, newPolicy
+// creates the policies of the other priorities and of the
+// partitions. If p is nil, NewLRU is used.
+func newCloseCache(p CachePolicy, newPolicy func() CachePolicy, parts []partition, onEvicted func(f *file)) *closeCache {
+	if p == nil {
+		p, newPolicy = NewLRU(), NewLRU
+	}
+	if newPolicy == nil {
+		newPolicy = NewLRU // priorities and partitions are rejected by New
+	}
+	c := &closeCache{
+		policy:    p,
+		files:     make(map[string]*file),
+		low:       newPolicy(),
+		high:      newPolicy(),
+		onEvicted: onEvicted,
+	}
+	for _, pt := range parts {
+		c.parts = append(c.parts, newCachePart(pt.prefix, pt.max, newPolicy()))
+	}
+	return c
+}
+
+// get returns the cached file of key.
+func (c *closeCache) get(key string) (*file, bool) {
+	f, ok := c.files[key]
+	return f, ok
+}
+
+// add adds f to the cache and evicts files if a limit is
+// exceeded. Files larger than maxSize are evicted immediately.
+func (c *closeCache) add(f *file) {
+	c.remove(f.key)
+	if c.maxSize != 0 && f.size > c.maxSize {
+		c.onEvicted(f)
+		return
+	}
+	c.files[f.key] = f
+	c.size += f.size
+	e := CacheEntry{Name: f.key, Size: f.size, Opens: int(f.opens.Load())}
+	if p := c.part(f); p != nil {
+		p.policy.Add(e)
+		p.n++
+		for p.n > p.max {
+			if !c.evictFrom(p.policy) {
+				break
+			}
+		}
+	} else {
+		c.tier(f).Add(e)
+	}
+	c.trim()
+}
+
+// remove removes the file of key without evicting it.
+func (c *closeCache) remove(key string) {
+	if f, ok := c.files[key]; ok {
+		delete(c.files, key)
+		c.size -= f.size
+		if p := c.part(f); p != nil {
+			p.policy.Remove(key)
+			p.n--
+		} else {
+			c.tier(f).Remove(key)
+		}
+	}
+}
+
+// evictFile evicts f, which must be cached.
+func (c *closeCache) evictFile(f *file) {
+	c.remove(f.key)
+	c.onEvicted(f)
+}
+
+// evict evicts the file chosen by the policy or, if there
+// is none, by the policy of a partition. Low priority files
+// are evicted first and high priority files last. It reports
+// false if the cache is empty.
+func (c *closeCache) evict() bool {
+	if c.evictFrom(c.low) || c.evictFrom(c.policy) {
+		return true
+	}
+	for _, p := range c.parts {
+		if c.evictFrom(p.policy) {
+			return true
+		}
+	}
+	return c.evictFrom(c.high)
+}
+
+// evictUnpartitioned evicts a file that is not in a partition
+// by priority. It reports false if there is none.
+func (c *closeCache) evictUnpartitioned() bool {
+	return c.evictFrom(c.low) || c.evictFrom(c.policy) || c.evictFrom(c.high)
+}
+
+// evictFrom evicts the file chosen by p. It reports false if
+// p is empty.
+func (c *closeCache) evictFrom(p CachePolicy) bool {
+	name, ok := p.Evict()
+	if !ok {
+		return false
+	}
+	f := c.files[name]
+	delete(c.files, name)
+	c.size -= f.size
+	if pt := c.part(f); pt != nil {
+		pt.n--
+	}
+	c.onEvicted(f)
+	return true
+}
+
+// evictIdle evicts the files that are added before t, except
+// those of high priority, and returns the number of evicted
+// files.
+func (c *closeCache) evictIdle(t time.Time) int {
+	files := c.removeIdle(t, false)
+	for _, f := range files {
+		c.onEvicted(f)
+	}
+	return len(files)
+}
+
+// removeIdle removes the files that are added before t
+// without evicting them. Files of high priority are removed
+// if high is true.
+func (c *closeCache) removeIdle(t time.Time, high bool) []*file {
+	var files []*file
+	for key, f := range c.files {
+		if f.idle.Before(t) && (high || f.prio <= PriorityNormal) {
+			c.remove(key)
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// trim evicts files until the limits are met.
+func (c *closeCache) trim() {
+	for {
+		var ok bool
+		switch {
+		case c.maxEntries != 0 && c.unpartitioned() > c.maxEntries:
+			ok = c.evictUnpartitioned()
+		case c.maxSize != 0 && c.size > c.maxSize:
+			ok = c.evict()
+		}
+		if !ok {
+			return
+		}
+	}
+}
+
+func (c *closeCache) len() int {
+	return len(c.files)
+}
+
+// unpartitioned returns the number of files that are not in a
+// partition.
+func (c *closeCache) unpartitioned() int {
+	n := len(c.files)
+	for _, p := range c.parts {
+		n -= p.n
+	}
+	return n
+}
+
+// clear removes all files and calls fn for each of them.
+func (c *closeCache) clear(fn func(f *file)) {
+	for key, f := range c.files {
+		c.remove(key)
+		fn(f)
+	}
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2013 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cache implements a typed LRU cache.
+package cache
+
+// Cache is an LRU cache. It is not safe for concurrent access.
+type Cache[K comparable, V any] struct {
+	// MaxEntries is the maximum number of cache entries before
+	// an item is evicted. Zero means no limit.
+	MaxEntries int
+
+	// MaxSize is the maximum total size of cache entries
+	// before an item is evicted. Items larger than MaxSize
+	// are evicted when added. Zero means no limit.
+	MaxSize int64
+
+	// SizeOf optionally specifies a function that returns the
+	// size of an entry. Without it entries have a size of zero.
+	SizeOf func(key K, value V) int64
+
+	// OnEvicted optionally specifies a callback function to be
+	// executed when an entry is purged from the cache.
+	OnEvicted func(key K, value V)
+
+	root  entry[K, V] // sentinel, root.next is newest
+	cache map[K]*entry[K, V]
+	size  int64
+}
+
+type entry[K comparable, V any] struct {
+	prev, next *entry[K, V]
+	key        K
+	value      V
+	size       int64
+}
+
+// New creates a new Cache.
+// If maxEntries is zero, the cache has no limit and it's assumed
+// that eviction is done by the caller.
+func New[K comparable, V any](maxEntries int) *Cache[K, V] {
+	c := &Cache[K, V]{MaxEntries: maxEntries}
+	c.init(maxEntries)
+	return c
+}
+
+func (c *Cache[K, V]) init(hint int) {
+	c.root.next = &c.root
+	c.root.prev = &c.root
+	c.cache = make(map[K]*entry[K, V], hint)
+}
+
+func (c *Cache[K, V]) pushFront(e *entry[K, V]) {
+	e.prev = &c.root
+	e.next = c.root.next
+	e.prev.next = e
+	e.next.prev = e
+}
+
+func (c *Cache[K, V]) unlink(e *entry[K, V]) {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	e.prev = nil
+	e.next = nil
+}
+
+// Add adds a value to the cache.
+func (c *Cache[K, V]) Add(key K, value V) {
+	if c.cache == nil {
+		c.init(0)
+	}
+	var size int64
+	if c.SizeOf != nil {
+		size = c.SizeOf(key, value)
+	}
+	if c.MaxSize != 0 && size > c.MaxSize {
+		// never fits, evict immediately
+		c.Remove(key)
+		if c.OnEvicted != nil {
+			c.OnEvicted(key, value)
+		}
+		return
+	}
+	if e, ok := c.cache[key]; ok {
+		c.unlink(e)
+		c.pushFront(e)
+		c.size += size - e.size
+		e.value = value
+		e.size = size
+	} else {
+		e := &entry[K, V]{key: key, value: value, size: size}
+		c.pushFront(e)
+		c.cache[key] = e
+		c.size += size
+	}
+	if c.MaxEntries != 0 && len(c.cache) > c.MaxEntries {
+		c.RemoveOldest()
+	}
+	c.Trim()
+}
+
+// Trim removes the oldest items from the cache until the
+// total size is at most MaxSize.
+func (c *Cache[K, V]) Trim() {
+	for c.MaxSize != 0 && c.size > c.MaxSize && c.Len() > 0 {
+		c.RemoveOldest()
+	}
+}
+
+// Get looks up a key's value from the cache.
+func (c *Cache[K, V]) Get(key K) (value V, ok bool) {
+	if c.cache == nil {
+		return
+	}
+	if e, hit := c.cache[key]; hit {
+		c.unlink(e)
+		c.pushFront(e)
+		return e.value, true
+	}
+	return
+}
+
+// Peek looks up a key's value from the cache without
+// marking it as recently used.
+func (c *Cache[K, V]) Peek(key K) (value V, ok bool) {
+	if c.cache == nil {
+		return
+	}
+	if e, hit := c.cache[key]; hit {
+		return e.value, true
+	}
+	return
+}
+
+// Remove removes the provided key from the cache.
+func (c *Cache[K, V]) Remove(key K) {
+	if c.cache == nil {
+		return
+	}
+	if e, hit := c.cache[key]; hit {
+		c.removeEntry(e)
+	}
+}
+
+// Oldest returns the oldest item of the cache.
+func (c *Cache[K, V]) Oldest() (key K, value V, ok bool) {
+	if c.Len() == 0 {
+		return
+	}
+	e := c.root.prev
+	return e.key, e.value, true
+}
+
+// RemoveOldest removes the oldest item from the cache.
+func (c *Cache[K, V]) RemoveOldest() {
+	if c.Len() == 0 {
+		return
+	}
+	c.removeEntry(c.root.prev)
+}
+
+func (c *Cache[K, V]) removeEntry(e *entry[K, V]) {
+	c.unlink(e)
+	delete(c.cache, e.key)
+	c.size -= e.size
+	if c.OnEvicted != nil {
+		c.OnEvicted(e.key, e.value)
+	}
+}
+
+// Len returns the number of items in the cache.
+func (c *Cache[K, V]) Len() int {
+	return len(c.cache)
+}
+
+// Size returns the total size of the items in the cache.
+func (c *Cache[K, V]) Size() int64 {
+	return c.size
+}
+
+// Range calls fn for each item in the cache from the most to
+// the least recently used until fn returns false. The cache
+// must not be modified by fn.
+func (c *Cache[K, V]) Range(fn func(key K, value V) bool) {
+	if c.cache == nil {
+		return
+	}
+	for e := c.root.next; e != &c.root; e = e.next {
+		if !fn(e.key, e.value) {
+			return
+		}
+	}
+}
+
+// Clear purges all stored items from the cache.
+func (c *Cache[K, V]) Clear() {
+	if c.OnEvicted != nil {
+		for _, e := range c.cache {
+			c.OnEvicted(e.key, e.value)
+		}
+	}
+	c.root.next = nil
+	c.root.prev = nil
+	c.cache = nil
+	c.size = 0
+}
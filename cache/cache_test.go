@@ -0,0 +1,182 @@
+/*
+Copyright 2013 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+type simpleStruct struct {
+	int
+	string
+}
+
+type complexStruct struct {
+	int
+	simpleStruct
+}
+
+var getTests = []struct {
+	name       string
+	keyToAdd   interface{}
+	keyToGet   interface{}
+	expectedOk bool
+}{
+	{"string_hit", "myKey", "myKey", true},
+	{"string_miss", "myKey", "nonsense", false},
+	{"simple_struct_hit", simpleStruct{1, "two"}, simpleStruct{1, "two"}, true},
+	{"simple_struct_miss", simpleStruct{1, "two"}, simpleStruct{0, "noway"}, false},
+	{"complex_struct_hit", complexStruct{1, simpleStruct{2, "three"}},
+		complexStruct{1, simpleStruct{2, "three"}}, true},
+}
+
+func TestGet(t *testing.T) {
+	for _, tt := range getTests {
+		lru := New[interface{}, int](0)
+		lru.Add(tt.keyToAdd, 1234)
+		val, ok := lru.Get(tt.keyToGet)
+		if ok != tt.expectedOk {
+			t.Fatalf("%s: cache hit = %v; want %v", tt.name, ok, !ok)
+		} else if ok && val != 1234 {
+			t.Fatalf("%s expected get to return 1234 but got %v", tt.name, val)
+		}
+	}
+}
+
+func TestRemove(t *testing.T) {
+	lru := New[string, int](0)
+	lru.Add("myKey", 1234)
+	if val, ok := lru.Get("myKey"); !ok {
+		t.Fatal("TestRemove returned no match")
+	} else if val != 1234 {
+		t.Fatalf("TestRemove failed.  Expected %d, got %v", 1234, val)
+	}
+
+	lru.Remove("myKey")
+	if _, ok := lru.Get("myKey"); ok {
+		t.Fatal("TestRemove returned a removed entry")
+	}
+}
+
+func TestEvict(t *testing.T) {
+	evictedKeys := make([]string, 0)
+	onEvictedFun := func(key string, value int) {
+		evictedKeys = append(evictedKeys, key)
+	}
+
+	lru := New[string, int](20)
+	lru.OnEvicted = onEvictedFun
+	for i := 0; i < 22; i++ {
+		lru.Add(fmt.Sprintf("myKey%d", i), 1234)
+	}
+
+	if len(evictedKeys) != 2 {
+		t.Fatalf("got %d evicted keys; want 2", len(evictedKeys))
+	}
+	if evictedKeys[0] != "myKey0" {
+		t.Fatalf("got %v in first evicted key; want %s", evictedKeys[0], "myKey0")
+	}
+	if evictedKeys[1] != "myKey1" {
+		t.Fatalf("got %v in second evicted key; want %s", evictedKeys[1], "myKey1")
+	}
+}
+
+func TestMaxSize(t *testing.T) {
+	evictedKeys := make([]string, 0)
+	lru := New[string, int](0)
+	lru.MaxSize = 10
+	lru.SizeOf = func(key string, value int) int64 {
+		return int64(value)
+	}
+	lru.OnEvicted = func(key string, value int) {
+		evictedKeys = append(evictedKeys, key)
+	}
+	lru.Add("a", 4)
+	lru.Add("b", 4)
+	lru.Add("c", 4)
+	if len(evictedKeys) != 1 || evictedKeys[0] != "a" {
+		t.Fatalf("got evicted keys %v; want [a]", evictedKeys)
+	}
+	if lru.Size() != 8 {
+		t.Fatalf("got size %d; want 8", lru.Size())
+	}
+	lru.Add("b", 1)
+	if lru.Size() != 5 {
+		t.Fatalf("got size %d after update; want 5", lru.Size())
+	}
+	lru.MaxSize = 2
+	lru.Trim()
+	if lru.Len() != 1 || lru.Size() != 1 {
+		t.Fatalf("got %d items of size %d after trim; want 1 of size 1", lru.Len(), lru.Size())
+	}
+}
+
+func TestMaxSizeTooLarge(t *testing.T) {
+	var evicted []string
+	lru := New[string, int](0)
+	lru.MaxSize = 10
+	lru.SizeOf = func(key string, value int) int64 {
+		return int64(value)
+	}
+	lru.OnEvicted = func(key string, value int) {
+		evicted = append(evicted, key)
+	}
+	lru.Add("a", 4)
+	lru.Add("b", 11)
+	if len(evicted) != 1 || evicted[0] != "b" {
+		t.Fatalf("got evicted keys %v; want [b]", evicted)
+	}
+	if lru.Len() != 1 || lru.Size() != 4 {
+		t.Fatalf("got %d items of size %d; want 1 of size 4", lru.Len(), lru.Size())
+	}
+}
+
+func TestOldest(t *testing.T) {
+	lru := New[string, int](0)
+	if _, _, ok := lru.Oldest(); ok {
+		t.Fatal("got oldest item of empty cache")
+	}
+	lru.Add("a", 1)
+	lru.Add("b", 2)
+	lru.Get("a")
+	if key, val, ok := lru.Oldest(); !ok || key != "b" || val != 2 {
+		t.Fatalf("got oldest %v=%v (%v); want b=2", key, val, ok)
+	}
+}
+
+func TestRange(t *testing.T) {
+	lru := New[string, int](0)
+	lru.Add("a", 1)
+	lru.Add("b", 2)
+	lru.Add("c", 3)
+	lru.Get("a")
+	var keys []string
+	lru.Range(func(key string, value int) bool {
+		keys = append(keys, key)
+		return len(keys) < 2
+	})
+	if fmt.Sprint(keys) != "[a c]" {
+		t.Fatalf("got keys %v; want [a c]", keys)
+	}
+	if val, ok := lru.Peek("b"); !ok || val != 2 {
+		t.Fatalf("got %v (%v) from Peek; want 2", val, ok)
+	}
+	if key, _, _ := lru.Oldest(); key != "b" {
+		t.Fatalf("Peek marked %s as recently used", key)
+	}
+}
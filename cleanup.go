@@ -0,0 +1,19 @@
+//go:build go1.24
+
+package singleopen
+
+import "runtime"
+
+// cleanup is the cleanup of a handle added by addCleanup.
+type cleanup = runtime.Cleanup
+
+// addCleanup calls leaked with ref when h is garbage
+// collected, until the cleanup is stopped by stopCleanup.
+func addCleanup[T any](h *T, ref handleRef) cleanup {
+	return runtime.AddCleanup(h, leaked, ref)
+}
+
+// stopCleanup stops the cleanup c of h.
+func stopCleanup[T any](h *T, c cleanup) {
+	c.Stop()
+}
@@ -0,0 +1,21 @@
+//go:build !go1.24
+
+package singleopen
+
+import "runtime"
+
+// cleanup is the cleanup of a handle added by addCleanup,
+// runtime.AddCleanup is not available so it's a finalizer.
+type cleanup struct{}
+
+// addCleanup calls leaked with ref when h is garbage
+// collected, until the cleanup is stopped by stopCleanup.
+func addCleanup[T any](h *T, ref handleRef) cleanup {
+	runtime.SetFinalizer(h, func(*T) { leaked(ref) })
+	return cleanup{}
+}
+
+// stopCleanup stops the cleanup c of h.
+func stopCleanup[T any](h *T, c cleanup) {
+	runtime.SetFinalizer(h, nil)
+}
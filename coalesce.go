@@ -0,0 +1,68 @@
+package singleopen
+
+import (
+	"context"
+	"sync"
+)
+
+// WithReadCoalescing returns an Option that coalesces
+// concurrent reads of the same range of a shared file into
+// a single read of the underlying file, the data is copied to
+// each reader. It helps when many readers read a hot range,
+// like an index page, at the same time.
+func WithReadCoalescing() Option {
+	return func(f *FS) error {
+		f.coalesce = true
+		return nil
+	}
+}
+
+// readRange is the range of a read.
+type readRange struct {
+	off int64
+	n   int
+}
+
+// readCall is a read in progress that others wait for.
+type readCall struct {
+	wg      sync.WaitGroup
+	waiters int // protected by file.rmu
+	data    []byte
+	err     error
+}
+
+// readAt reads from the shared file at off, coalescing the
+// read with concurrent reads of the same range if enabled.
+func (f *file) readAt(ctx context.Context, p []byte, off int64) (int, error) {
+	if !f.fsys.coalesce || len(p) == 0 {
+		return f.pread(ctx, p, off)
+	}
+	r := readRange{off, len(p)}
+	f.rmu.Lock()
+	if c, ok := f.reads[r]; ok {
+		c.waiters++
+		f.rmu.Unlock()
+		c.wg.Wait()
+		return copy(p, c.data), c.err
+	}
+	c := new(readCall)
+	c.wg.Add(1)
+	if f.reads == nil {
+		f.reads = make(map[readRange]*readCall)
+	}
+	f.reads[r] = c
+	f.rmu.Unlock()
+
+	n, err := f.pread(ctx, p, off)
+
+	f.rmu.Lock()
+	delete(f.reads, r)
+	if c.waiters > 0 {
+		// p belongs to the caller, waiters copy from a clone
+		c.data = append([]byte(nil), p[:n]...)
+		c.err = err
+	}
+	f.rmu.Unlock()
+	c.wg.Done()
+	return n, err
+}
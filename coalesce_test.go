@@ -0,0 +1,55 @@
+package singleopen
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadCoalescing(t *testing.T) {
+	sfs := newSlowFS(fstest.MapFS{"file": &fstest.MapFile{Data: []byte("0123456789")}}, 20)
+	fsys, err := New(sfs, WithReadCoalescing())
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var wg sync.WaitGroup
+	read := func() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := make([]byte, 4)
+			n, err := fsys.ReadAt("file", p, 3)
+			if err != nil || string(p[:n]) != "3456" {
+				t.Errorf("got %q, %v, want 3456", p[:n], err)
+			}
+		}()
+	}
+	read()
+	<-sfs.started
+	for i := 0; i < 19; i++ {
+		read()
+	}
+	// wait for the reads to join the first one
+	sf := f.(*fileReaderAt).file
+	for {
+		sf.rmu.Lock()
+		waiters := sf.reads[readRange{3, 4}].waiters
+		sf.rmu.Unlock()
+		if waiters == 19 {
+			break
+		}
+		runtime.Gosched()
+	}
+	close(sfs.release)
+	wg.Wait()
+	if n := sfs.reads.Load(); n != 1 {
+		t.Errorf("got %d reads, want 1 coalesced read", n)
+	}
+}
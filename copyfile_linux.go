@@ -0,0 +1,77 @@
+package singleopen
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// copyFileRange copies src from off to w using
+// copy_file_range(2) if both are an *os.File. It reports
+// whether the copy is handled. The file offset of src is not
+// changed, the file offset of w is advanced.
+func copyFileRange(w io.Writer, src fs.File, off int64) (n int64, handled bool, err error) {
+	dst, ok := w.(*os.File)
+	if !ok {
+		return 0, false, nil
+	}
+	of, ok := osFile(src)
+	if !ok {
+		return 0, false, nil
+	}
+	wc, err := dst.SyscallConn()
+	if err != nil {
+		return 0, false, nil
+	}
+	rc, err := of.SyscallConn()
+	if err != nil {
+		return 0, false, nil
+	}
+
+	var serr error
+	handled = true
+	cerr := rc.Control(func(infd uintptr) {
+		werr := wc.Control(func(outfd uintptr) {
+			for {
+				m, err := unix.CopyFileRange(int(infd), &off, int(outfd), nil, maxSendfile, 0)
+				if m > 0 {
+					n += int64(m)
+				}
+				switch {
+				case errors.Is(err, unix.EINTR):
+					continue
+				case err != nil:
+					if n == 0 && unsupportedCopy(err) {
+						handled = false // fall back to other copies
+						return
+					}
+					serr = os.NewSyscallError("copy_file_range", err)
+					return
+				case m == 0:
+					return // end of file
+				}
+			}
+		})
+		if serr == nil {
+			serr = werr
+		}
+	})
+	if serr == nil {
+		serr = cerr
+	}
+	return n, handled, serr
+}
+
+// unsupportedCopy reports whether err says copy_file_range
+// can't copy between the files.
+func unsupportedCopy(err error) bool {
+	return errors.Is(err, unix.ENOSYS) ||
+		errors.Is(err, unix.EXDEV) ||
+		errors.Is(err, unix.EINVAL) ||
+		errors.Is(err, unix.EOPNOTSUPP) ||
+		errors.Is(err, unix.EBADF) ||
+		errors.Is(err, unix.EPERM)
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package singleopen
+
+import (
+	"io"
+	"io/fs"
+)
+
+// copyFileRange reports that the copy is not handled, there
+// is no kernel support for this platform.
+func copyFileRange(w io.Writer, src fs.File, off int64) (int64, bool, error) {
+	return 0, false, nil
+}
@@ -0,0 +1,192 @@
+// Package cryptfs provides a file system that decrypts files
+// encrypted at rest with AES-CTR. Use it as the underlying
+// file system of a singleopen.FS to share a single decrypting
+// file, and a single key lookup, among all readers of a file:
+//
+//	c, err := cryptfs.New(os.DirFS(dir), keys)
+//	fsys, err := singleopen.New(c, singleopen.WithKeepLast(128))
+//
+// An encrypted file starts with a random IV of aes.BlockSize
+// bytes followed by the contents encrypted in CTR mode, as
+// written by Encrypt. CTR mode doesn't authenticate the
+// contents, changes of the encrypted file go undetected.
+package cryptfs
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+
+	"github.com/dwlnetnl/singleopen/internal/compat"
+)
+
+// KeyProvider provides the keys of files.
+type KeyProvider interface {
+	// Key returns the AES key of the named file, of 16, 24 or
+	// 32 bytes.
+	Key(name string) ([]byte, error)
+}
+
+// KeyFunc is a KeyProvider function.
+type KeyFunc func(name string) ([]byte, error)
+
+func (f KeyFunc) Key(name string) ([]byte, error) {
+	return f(name)
+}
+
+// FS is a file system that decrypts regular files opened from
+// an underlying file system.
+type FS struct {
+	fsys fs.FS
+	keys KeyProvider
+}
+
+var _ fs.FS = (*FS)(nil)
+
+// New returns a FS that decrypts the regular files of fsys
+// with the keys of keys. The key of a file is looked up once
+// per open of the file on fsys.
+func New(fsys fs.FS, keys KeyProvider) (*FS, error) {
+	if fsys == nil {
+		return nil, errors.New("cryptfs: nil file system")
+	}
+	if keys == nil {
+		return nil, errors.New("cryptfs: nil key provider")
+	}
+	return &FS{fsys: fsys, keys: keys}, nil
+}
+
+// Open opens the named file, a regular file must implement
+// io.ReaderAt.
+func (c *FS) Open(name string) (fs.File, error) {
+	f, err := c.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if !fi.Mode().IsRegular() {
+		return f, nil
+	}
+	cf, err := c.open(f, name, fi)
+	if err != nil {
+		f.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return cf, nil
+}
+
+func (c *FS) open(f fs.File, name string, fi fs.FileInfo) (*file, error) {
+	ra, ok := f.(io.ReaderAt)
+	if !ok {
+		return nil, compat.ErrUnsupported
+	}
+	if fi.Size() < aes.BlockSize {
+		return nil, errors.New("cryptfs: file too short")
+	}
+	key, err := c.keys.Key(name)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, aes.BlockSize)
+	if _, err := ra.ReadAt(iv, 0); err != nil {
+		return nil, err
+	}
+	return &file{
+		File:  f,
+		ra:    ra,
+		block: block,
+		ivHi:  binary.BigEndian.Uint64(iv),
+		ivLo:  binary.BigEndian.Uint64(iv[8:]),
+		fi:    sizeInfo{fi, fi.Size() - aes.BlockSize},
+	}, nil
+}
+
+// file decrypts the contents of a file. It doesn't have an
+// Unwrap method, so the encrypted file is never copied as is.
+type file struct {
+	fs.File
+	ra     io.ReaderAt
+	block  cipher.Block
+	ivHi   uint64
+	ivLo   uint64
+	fi     fs.FileInfo
+	offset int64 // of Read
+}
+
+var _ io.ReaderAt = (*file)(nil)
+
+// ReadAt reads and decrypts len(p) bytes at offset off.
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, &fs.PathError{Op: "read", Path: f.fi.Name(), Err: fs.ErrInvalid}
+	}
+	n, err := f.ra.ReadAt(p, off+aes.BlockSize)
+	f.stream(off).XORKeyStream(p[:n], p[:n])
+	return n, err
+}
+
+// stream returns the key stream starting at offset off.
+func (f *file) stream(off int64) cipher.Stream {
+	// add the block number to the 128-bit counter, it wraps
+	// like the counter of CTR mode
+	hi, lo := f.ivHi, f.ivLo+uint64(off/aes.BlockSize)
+	if lo < f.ivLo {
+		hi++
+	}
+	var counter [aes.BlockSize]byte
+	binary.BigEndian.PutUint64(counter[:], hi)
+	binary.BigEndian.PutUint64(counter[8:], lo)
+	s := cipher.NewCTR(f.block, counter[:])
+	var skip [aes.BlockSize]byte
+	s.XORKeyStream(skip[:off%aes.BlockSize], skip[:off%aes.BlockSize])
+	return s
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	n, err := f.ReadAt(p, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	return f.fi, nil
+}
+
+// sizeInfo is a file info with the size of the contents.
+type sizeInfo struct {
+	fs.FileInfo
+	size int64
+}
+
+func (s sizeInfo) Size() int64 { return s.size }
+
+// Encrypt encrypts src with key and writes it to dst in the
+// format read by FS.
+func Encrypt(dst io.Writer, src io.Reader, key []byte) error {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return err
+	}
+	if _, err := dst.Write(iv); err != nil {
+		return err
+	}
+	w := cipher.StreamWriter{S: cipher.NewCTR(block, iv), W: dst}
+	_, err = io.Copy(w, src)
+	return err
+}
@@ -0,0 +1,95 @@
+package cryptfs
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dwlnetnl/singleopen"
+)
+
+func TestDecrypt(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	var data []byte
+	for i := 0; i < 1000; i++ {
+		data = fmt.Appendf(data, "secret %d\n", i)
+	}
+	var enc bytes.Buffer
+	if err := Encrypt(&enc, bytes.NewReader(data), key); err != nil {
+		t.Fatal(err)
+	}
+
+	// a counter that wraps
+	iv := bytes.Repeat([]byte{0xff}, aes.BlockSize)
+	block, _ := aes.NewCipher(key)
+	wrapped := append([]byte(nil), iv...)
+	wrapped = append(wrapped, make([]byte, len(data))...)
+	cipher.NewCTR(block, iv).XORKeyStream(wrapped[aes.BlockSize:], data)
+
+	var lookups atomic.Int32
+	c, err := New(fstest.MapFS{
+		"file":    &fstest.MapFile{Data: enc.Bytes()},
+		"wrapped": &fstest.MapFile{Data: wrapped},
+		"short":   &fstest.MapFile{Data: []byte("short")},
+	}, KeyFunc(func(name string) ([]byte, error) {
+		lookups.Add(1)
+		return key, nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys, err := singleopen.New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"file", "wrapped"} {
+		lookups.Store(0)
+		f1, err := fsys.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f2, err := fsys.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := lookups.Load(); n != 1 {
+			t.Errorf("%s: got %d key lookups, want 1", name, n)
+		}
+		got, err := io.ReadAll(f1)
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("%s: decrypted contents differ: %v", name, err)
+		}
+		fi, err := f2.Stat()
+		if err != nil || fi.Size() != int64(len(data)) {
+			t.Errorf("%s: got size %v, %v, want %d", name, fi.Size(), err, len(data))
+		}
+		for i := 0; i < 100; i++ {
+			off := rand.Intn(len(data))
+			n := rand.Intn(100)
+			p := make([]byte, n)
+			m, _ := f2.(io.ReaderAt).ReadAt(p, int64(off))
+			want := data[off:]
+			if len(want) > n {
+				want = want[:n]
+			}
+			if !bytes.Equal(p[:m], want) {
+				t.Fatalf("%s: read of %d bytes at %d differs", name, n, off)
+			}
+		}
+		f1.Close()
+		f2.Close()
+	}
+	if _, err := fs.ReadFile(fsys, "short"); err == nil {
+		t.Error("expected error for file without IV")
+	}
+	if _, err := New(fstest.MapFS{}, nil); err == nil {
+		t.Error("expected error for nil key provider")
+	}
+}
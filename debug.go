@@ -0,0 +1,68 @@
+package singleopen
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+// debugState is the state of a FS shown by DebugHandler.
+type debugState struct {
+	Stats Stats              `json:"stats"`
+	Files []FileInfoSnapshot `json:"files"`
+}
+
+func (fsys *FS) debugState() debugState {
+	return debugState{Stats: fsys.Stats(), Files: fsys.OpenFiles()}
+}
+
+// DebugHandler returns an http.Handler that shows the stats
+// of fsys and its shared and cached files, as HTML or, if
+// the query has format=json or JSON is accepted, as JSON. It's
+// meant to be served under a path like /debug/singleopen.
+func (fsys *FS) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		st := fsys.debugState()
+		if r.URL.Query().Get("format") == "json" ||
+			strings.Contains(r.Header.Get("Accept"), "application/json") {
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			enc.Encode(st)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		debugTemplate.Execute(w, st)
+	})
+}
+
+var debugTemplate = template.Must(template.New("debug").Parse(`<!DOCTYPE html>
+<html>
+<head><title>singleopen</title></head>
+<body>
+<h1>singleopen</h1>
+<h2>Stats</h2>
+<table>
+<tr><td>Opens</td><td>{{.Stats.Opens}}</td></tr>
+<tr><td>Hits</td><td>{{.Stats.Hits}}</td></tr>
+<tr><td>Cache hits</td><td>{{.Stats.CacheHits}}</td></tr>
+<tr><td>Misses</td><td>{{.Stats.Misses}}</td></tr>
+<tr><td>Evictions</td><td>{{.Stats.Evictions}}</td></tr>
+<tr><td>Close errors</td><td>{{.Stats.CloseErrs}}</td></tr>
+<tr><td>Open files</td><td>{{.Stats.OpenFiles}}</td></tr>
+<tr><td>Shared</td><td>{{.Stats.Shared}}</td></tr>
+<tr><td>Pinned</td><td>{{.Stats.Pinned}}</td></tr>
+<tr><td>Cached</td><td>{{.Stats.Cached}} ({{.Stats.CachedSize}} bytes)</td></tr>
+<tr><td>References</td><td>{{.Stats.RefCount}}</td></tr>
+<tr><td>Inlined</td><td>{{.Stats.Inlined}} ({{.Stats.InlineSize}} bytes)</td></tr>
+</table>
+<h2>Files</h2>
+<table>
+<tr><th>Name</th><th>Refs</th><th>Opens</th><th>Size</th><th>State</th><th>Age</th><th>Idle</th></tr>
+{{range .Files}}<tr><td>{{.Name}}</td><td>{{.Refs}}</td><td>{{.Opens}}</td><td>{{.Size}}</td><td>{{if .Cached}}cached{{else}}shared{{end}}{{if .Pinned}}, pinned{{end}}{{if .Inline}}, inline{{end}}</td><td>{{.Age}}</td><td>{{if .Cached}}{{.Idle}}{{end}}</td></tr>
+{{range .Sites}}<tr><td colspan="7"><details><summary>{{.Count}} open at</summary><pre>{{.Stack}}</pre></details></td></tr>
+{{end}}{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,55 @@
+package singleopen
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDebugHandler(t *testing.T) {
+	fsys, err := New(fstest.MapFS{
+		"a": &fstest.MapFile{Data: []byte("a")},
+		"b": &fstest.MapFile{Data: []byte("bb")},
+	}, WithKeepLast(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := fsys.Open("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := fsys.Open("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Close()
+
+	w := httptest.NewRecorder()
+	fsys.DebugHandler().ServeHTTP(w, httptest.NewRequest("GET", "/debug/singleopen?format=json", nil))
+	var st debugState
+	if err := json.Unmarshal(w.Body.Bytes(), &st); err != nil {
+		t.Fatal(err)
+	}
+	if len(st.Files) != 2 || st.Stats.Opens != 2 {
+		t.Fatalf("got %+v, want 2 files", st)
+	}
+	fa, fb := st.Files[0], st.Files[1]
+	if fa.Name != "a" || fa.Refs != 1 || fa.Cached || fa.Age <= 0 {
+		t.Errorf("got %+v, want shared a", fa)
+	}
+	if fb.Name != "b" || fb.Refs != 0 || !fb.Cached || fb.Size != 2 {
+		t.Errorf("got %+v, want cached b", fb)
+	}
+
+	w = httptest.NewRecorder()
+	fsys.DebugHandler().ServeHTTP(w, httptest.NewRequest("GET", "/debug/singleopen", nil))
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("got Content-Type %q, want HTML", ct)
+	}
+	if body := w.Body.String(); !strings.Contains(body, "<td>b</td>") || !strings.Contains(body, "cached") {
+		t.Errorf("HTML doesn't show cached file b:\n%s", body)
+	}
+}
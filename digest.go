@@ -0,0 +1,93 @@
+package singleopen
+
+import (
+	"bytes"
+	"crypto"
+	"io"
+	"io/fs"
+	"strconv"
+	"time"
+
+	"github.com/dwlnetnl/singleopen/cache"
+	"github.com/dwlnetnl/singleopen/internal/compat"
+)
+
+// maxDigests is the maximum number of files whose digests are
+// cached.
+const maxDigests = 4096
+
+// digestEntry is a cached digest of a version of a file.
+type digestEntry struct {
+	size int64
+	mod  time.Time
+	sum  []byte
+}
+
+// Digest returns the digest of the named file computed with
+// hash h, like to use as a strong ETag. The digest is computed
+// once by reading the shared file and cached until the file
+// is invalidated or its size or modification time changes.
+// Digests of at most 4096 files are cached, the least recently
+// used are dropped first. Concurrent calls for the same file
+// and hash share the computation. The hash function must be
+// linked into the binary. It's an error if the file does not
+// implement io.ReaderAt.
+func (fsys *FS) Digest(name string, h crypto.Hash) ([]byte, error) {
+	if !h.Available() {
+		return nil, &fs.PathError{Op: "digest", Path: name, Err: compat.ErrUnsupported}
+	}
+	key := strconv.Itoa(int(h)) + "\x00" + name
+	v, err, _ := fsys.digester.Do(key, func() (interface{}, error) {
+		return fsys.digest(name, h)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Clone(v.([]byte)), nil
+}
+
+// digest returns the cached digest of the named file or
+// computes it, see Digest. The digest must not be modified.
+func (fsys *FS) digest(name string, h crypto.Hash) ([]byte, error) {
+	ra, err := fsys.OpenReaderAt(name)
+	if err != nil {
+		return nil, err
+	}
+	defer ra.Close()
+	fi, err := ra.(fs.File).Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	var e digestEntry
+	var ok bool
+	fsys.mu.Lock()
+	if fsys.digests != nil {
+		if sums, hit := fsys.digests.Get(name); hit {
+			e, ok = sums[h]
+		}
+	}
+	fsys.mu.Unlock()
+	if ok && e.size == fi.Size() && e.mod.Equal(fi.ModTime()) {
+		return e.sum, nil
+	}
+
+	hh := h.New()
+	if _, err := io.Copy(hh, io.NewSectionReader(ra, 0, fi.Size())); err != nil {
+		return nil, err
+	}
+	sum := hh.Sum(nil)
+	fsys.mu.Lock()
+	if fsys.digests == nil {
+		fsys.digests = cache.New[string, map[crypto.Hash]digestEntry](maxDigests)
+	}
+	sums, ok := fsys.digests.Get(name)
+	if !ok {
+		sums = make(map[crypto.Hash]digestEntry)
+		fsys.digests.Add(name, sums)
+	}
+	sums[h] = digestEntry{fi.Size(), fi.ModTime(), sum}
+	fsys.mu.Unlock()
+	fsys.debug("compute digest", name, "hash", h)
+	return sum, nil
+}
@@ -0,0 +1,106 @@
+package singleopen
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/dwlnetnl/singleopen/internal/compat"
+)
+
+func TestDigest(t *testing.T) {
+	mfs := fstest.MapFS{
+		"file": &fstest.MapFile{Data: []byte("content")},
+	}
+	cfs := &countFS{FS: mfs, max: 10}
+	fsys, err := New(cfs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := sha256.Sum256([]byte("content"))
+	for i := 0; i < 2; i++ {
+		sum, err := fsys.Digest("file", crypto.SHA256)
+		if err != nil || !bytes.Equal(sum, want[:]) {
+			t.Errorf("got %x, %v, want %x", sum, err, want)
+		}
+	}
+	if s := fsys.Stats(); s.Misses != 2 {
+		t.Errorf("got %d misses, want 2", s.Misses)
+	}
+	fsys.mu.Lock()
+	n := fsys.digests.Len()
+	fsys.mu.Unlock()
+	if n != 1 {
+		t.Errorf("got %d cached digests, want 1", n)
+	}
+
+	// a changed file is hashed again
+	mfs["file"] = &fstest.MapFile{Data: []byte("changed"), ModTime: time.Now()}
+	fsys.Invalidate("file")
+	want = sha256.Sum256([]byte("changed"))
+	sum, err := fsys.Digest("file", crypto.SHA256)
+	if err != nil || !bytes.Equal(sum, want[:]) {
+		t.Errorf("got %x, %v, want %x", sum, err, want)
+	}
+	if _, err := fsys.Digest("file", crypto.Hash(0)); !errors.Is(err, compat.ErrUnsupported) {
+		t.Errorf("got %v, want ErrUnsupported", err)
+	}
+	if _, err := fsys.Digest("missing", crypto.SHA256); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %v, want ErrNotExist", err)
+	}
+}
+
+func TestDigestLimit(t *testing.T) {
+	mfs := fstest.MapFS{}
+	for i := 0; i < maxDigests+10; i++ {
+		mfs[fmt.Sprint("file", i)] = &fstest.MapFile{}
+	}
+	fsys, err := New(mfs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name := range mfs {
+		if _, err := fsys.Digest(name, crypto.SHA256); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := fsys.digests.Len(); n != maxDigests {
+		t.Errorf("got %d cached digests, want %d", n, maxDigests)
+	}
+}
+
+func TestDigestCoalesce(t *testing.T) {
+	sfs := newSlowFS(fstest.MapFS{
+		"file": &fstest.MapFile{Data: []byte("content")},
+	}, 4)
+	fsys, err := New(sfs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := sha256.Sum256([]byte("content"))
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sum, err := fsys.Digest("file", crypto.SHA256)
+			if err != nil || !bytes.Equal(sum, want[:]) {
+				t.Errorf("got %x, %v, want %x", sum, err, want)
+			}
+		}()
+	}
+	<-sfs.started
+	time.Sleep(20 * time.Millisecond) // let the other calls join
+	close(sfs.release)
+	wg.Wait()
+	if n := sfs.reads.Load(); n != 1 {
+		t.Errorf("got %d reads, want 1", n)
+	}
+}
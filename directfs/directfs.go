@@ -0,0 +1,183 @@
+//go:build go1.24
+
+// Package directfs provides a file system of a directory whose
+// files are opened with O_DIRECT to bypass the page cache. Use
+// it as the underlying file system of a singleopen.FS to reuse
+// the handles:
+//
+//	d, err := directfs.New(dir)
+//	fsys, err := singleopen.New(d)
+//
+// Reads with O_DIRECT must be aligned, files returned by Open
+// read through an aligned buffer when a read is not aligned.
+// On platforms without O_DIRECT files are opened normally. The
+// package requires Go 1.24 for os.Root.
+package directfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"unsafe"
+)
+
+// Align is the alignment of offsets, lengths and buffer
+// addresses of reads that don't need an aligned buffer.
+const Align = 4096
+
+// FS is a file system of a directory whose regular files are
+// opened with O_DIRECT.
+type FS struct {
+	root *os.Root
+}
+
+var _ fs.FS = (*FS)(nil)
+
+// New returns a FS of the files in dir.
+func New(dir string) (*FS, error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &FS{root: root}, nil
+}
+
+// Open opens the named file. Regular files are opened with
+// O_DIRECT, it's an error if the file system of the directory
+// does not support it. Directories are opened normally.
+func (d *FS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	fi, err := d.root.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.Mode().IsRegular() {
+		return d.root.Open(name)
+	}
+	f, err := d.root.OpenFile(name, os.O_RDONLY|oDirect, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &file{File: f}, nil
+}
+
+// Close closes the directory.
+func (d *FS) Close() error {
+	return d.root.Close()
+}
+
+// AlignedBuffer returns a buffer of n bytes whose address is
+// aligned to Align. Reads into an aligned buffer at an aligned
+// offset and of an aligned length don't copy.
+func AlignedBuffer(n int) []byte {
+	b := make([]byte, n+Align)
+	off := Align - int(uintptr(unsafe.Pointer(&b[0]))%Align)
+	if off == Align {
+		off = 0
+	}
+	return b[off : off+n : off+n]
+}
+
+// isAligned reports whether a read of p at off is aligned.
+func isAligned(p []byte, off int64) bool {
+	return off%Align == 0 && len(p)%Align == 0 &&
+		(len(p) == 0 || uintptr(unsafe.Pointer(&p[0]))%Align == 0)
+}
+
+// bouncePool holds aligned buffers for unaligned reads.
+var bouncePool = sync.Pool{
+	New: func() any {
+		b := AlignedBuffer(64 * 1024)
+		return &b
+	},
+}
+
+// file is an *os.File opened with O_DIRECT that reads
+// unaligned through aligned buffers.
+type file struct {
+	*os.File
+	mu     sync.Mutex // protects offset
+	offset int64
+}
+
+// Unwrap returns the underlying *os.File.
+func (f *file) Unwrap() fs.File {
+	return f.File
+}
+
+// ReadAt reads len(p) bytes at offset off.
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, &fs.PathError{Op: "read", Path: f.Name(), Err: fs.ErrInvalid}
+	}
+	if isAligned(p, off) {
+		return f.File.ReadAt(p, off)
+	}
+	bp := bouncePool.Get().(*[]byte)
+	defer bouncePool.Put(bp)
+	buf := *bp
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		start := pos &^ (Align - 1)
+		skip := int(pos - start)
+		want := (skip + len(p) - n + Align - 1) &^ (Align - 1)
+		if want > len(buf) {
+			want = len(buf)
+		}
+		m, err := f.File.ReadAt(buf[:want], start)
+		if m > skip {
+			n += copy(p[n:], buf[skip:m])
+		}
+		if n == len(p) {
+			break
+		}
+		if err != nil {
+			return n, err
+		}
+		if m < want {
+			return n, io.EOF
+		}
+	}
+	return n, nil
+}
+
+// Read reads from the current offset of f.
+func (f *file) Read(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n, err := f.ReadAt(p, f.offset)
+	f.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Seek sets the offset of the next Read.
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		fi, err := f.Stat()
+		if err != nil {
+			return 0, err
+		}
+		offset += fi.Size()
+	default:
+		offset = -1
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.Name(), Err: errors.New("invalid offset")}
+	}
+	f.offset = offset
+	return offset, nil
+}
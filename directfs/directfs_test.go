@@ -0,0 +1,70 @@
+//go:build go1.24
+
+package directfs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dwlnetnl/singleopen"
+)
+
+func TestDirect(t *testing.T) {
+	dir := t.TempDir()
+	data := make([]byte, 3*Align+100)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	os.WriteFile(filepath.Join(dir, "file"), data, 0o644)
+	os.Mkdir(filepath.Join(dir, "dir"), 0o755)
+	d, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	f, err := d.Open("file")
+	if errors.Is(err, syscall.EINVAL) {
+		t.Skip("O_DIRECT not supported by file system")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	fsys, err := singleopen.New(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := fsys.ReadFile("file")
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("got %d bytes, %v", len(got), err)
+	}
+	for _, tc := range []struct{ off, n int }{
+		{0, Align}, {1, 10}, {Align - 5, 10}, {Align, 2 * Align}, {3*Align + 90, 20},
+	} {
+		p := make([]byte, tc.n)
+		if tc.n%Align == 0 {
+			p = AlignedBuffer(tc.n)
+		}
+		n, err := fsys.ReadAt("file", p, int64(tc.off))
+		want := data[tc.off:]
+		if len(want) > tc.n {
+			want = want[:tc.n]
+		}
+		if err != nil && err != io.EOF || !bytes.Equal(p[:n], want) {
+			t.Errorf("read %d at %d: got %d bytes, %v", tc.n, tc.off, n, err)
+		}
+	}
+	if err := fstest.TestFS(d, "file", "dir"); err != nil {
+		t.Error(err)
+	}
+	if _, err := d.Open("../file"); err == nil {
+		t.Error("expected error for invalid path")
+	}
+}
@@ -0,0 +1,7 @@
+//go:build go1.24
+
+package directfs
+
+import "syscall"
+
+const oDirect = syscall.O_DIRECT
@@ -0,0 +1,6 @@
+//go:build !linux && go1.24
+
+package directfs
+
+// oDirect is not supported, files are opened normally.
+const oDirect = 0
@@ -0,0 +1,343 @@
+package singleopen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/dwlnetnl/singleopen/internal/compat"
+)
+
+// WithDirHandles returns an Option that shares the handles of
+// directories opened by Open for ttl after they are opened,
+// like files are shared. Every open returns a directory with
+// its own position, so concurrent walks don't interfere. If
+// listings is true, the entries of a directory are read once
+// per handle and served to every open of it, otherwise every
+// open rereads the directory by seeking the shared handle to
+// the start, which the handle must support. A directory is
+// closed ttl after it's opened, or when its last reference is
+// released after that, or when it's invalidated. Directories
+// are counted by MaxOpen, unreferenced ones are closed when
+// the limit is reached.
+func WithDirHandles(ttl time.Duration, listings bool) Option {
+	return func(f *FS) error {
+		if ttl <= 0 {
+			return fmt.Errorf("singleopen: invalid directory handle ttl %v", ttl)
+		}
+		f.dirHandleTTL = ttl
+		f.dirListings = listings
+		return nil
+	}
+}
+
+// dirHandle is a shared directory.
+type dirHandle struct {
+	key     string
+	name    string
+	fi      fs.FileInfo
+	expires time.Time
+	refc    int         // protected by FS.mu
+	stale   bool        // not in FS.dirHandles, protected by FS.mu
+	timer   *time.Timer // expires h, protected by FS.mu
+
+	mu      sync.Mutex // protects below
+	dir     fs.File
+	read    bool // dir is read, seek before reading again
+	entries []fs.DirEntry
+	listed  bool // entries are cached
+}
+
+// openDir returns a directory file of the shared directory
+// name, which is opened if needed. The directory d is opened
+// already and reserved from the limiter if it isn't nil, it's
+// closed if it's not needed.
+func (fsys *FS) openDir(ctx context.Context, name string, fi fs.FileInfo, d fs.File) (fs.File, error) {
+	key := fsys.key(name)
+	now := time.Now()
+	fsys.mu.Lock()
+	if h, ok := fsys.dirHandles[key]; ok && now.Before(h.expires) {
+		h.refc++
+		fsys.mu.Unlock()
+		if d != nil {
+			d.Close()
+			fsys.limiter().release()
+		}
+		fsys.debug("reuse directory", name)
+		return &dirFile{fsys: fsys, h: h}, nil
+	}
+	fsys.mu.Unlock()
+
+	if d == nil {
+		if err := fsys.limiter().acquire(ctx, fsys.evict); err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		var err error
+		if d, err = fsys.openFile(ctx, name); err != nil {
+			fsys.limiter().release()
+			return nil, err
+		}
+	}
+	h := &dirHandle{key: key, name: name, fi: fi, expires: now.Add(fsys.dirHandleTTL), refc: 1, dir: d}
+	fsys.mu.Lock()
+	if fsys.closed.Load() {
+		// not shared, closed on release
+		h.stale = true
+		fsys.mu.Unlock()
+		return &dirFile{fsys: fsys, h: h}, nil
+	}
+	if fsys.dirHandles == nil {
+		fsys.dirHandles = make(map[string]*dirHandle)
+	}
+	idle := fsys.removeDirs(func(old *dirHandle) bool {
+		return old.key == key || !now.Before(old.expires)
+	})
+	fsys.dirHandles[key] = h
+	h.timer = time.AfterFunc(fsys.dirHandleTTL, func() {
+		fsys.expireDir(h)
+	})
+	fsys.mu.Unlock()
+	if err := fsys.closeDirs(idle); err != nil {
+		fsys.closeError(err)
+	}
+	fsys.debug("open directory", name)
+	return &dirFile{fsys: fsys, h: h}, nil
+}
+
+// removeDirs removes the shared directories for which fn
+// reports true and returns those that are not referenced.
+// fsys.mu must be held.
+func (fsys *FS) removeDirs(fn func(h *dirHandle) bool) []*dirHandle {
+	var idle []*dirHandle
+	for _, h := range fsys.dirHandles {
+		if !fn(h) {
+			continue
+		}
+		fsys.unshareDir(h)
+		if h.refc == 0 {
+			idle = append(idle, h)
+		}
+	}
+	return idle
+}
+
+// invalidateDir closes the shared directory of name and of
+// its parent, whose listing changes with name.
+func (fsys *FS) invalidateDir(name string) error {
+	fsys.mu.Lock()
+	var idle []*dirHandle
+	for _, key := range []string{fsys.key(name), fsys.key(path.Dir(name))} {
+		if h, ok := fsys.dirHandles[key]; ok {
+			fsys.unshareDir(h)
+			if h.refc == 0 {
+				idle = append(idle, h)
+			}
+		}
+	}
+	fsys.mu.Unlock()
+	return fsys.closeDirs(idle)
+}
+
+// invalidateDirs closes all shared directories.
+func (fsys *FS) invalidateDirs() error {
+	fsys.mu.Lock()
+	idle := fsys.removeDirs(func(*dirHandle) bool { return true })
+	fsys.mu.Unlock()
+	return fsys.closeDirs(idle)
+}
+
+// unshareDir removes h from the shared directories. fsys.mu
+// must be held.
+func (fsys *FS) unshareDir(h *dirHandle) {
+	h.stale = true
+	delete(fsys.dirHandles, h.key)
+	h.timer.Stop()
+}
+
+// expireDir closes h when its ttl has passed, or marks it to
+// be closed when its last reference is released.
+func (fsys *FS) expireDir(h *dirHandle) {
+	fsys.mu.Lock()
+	if h.stale {
+		// closed by whoever removed it
+		fsys.mu.Unlock()
+		return
+	}
+	fsys.unshareDir(h)
+	idle := h.refc == 0
+	fsys.mu.Unlock()
+	if idle {
+		if err := fsys.closeDirs([]*dirHandle{h}); err != nil {
+			fsys.closeError(err)
+		}
+	}
+}
+
+// evictDir closes a shared directory that is not referenced.
+// It reports whether a directory is closed.
+func (fsys *FS) evictDir() bool {
+	fsys.mu.Lock()
+	var idle *dirHandle
+	for _, h := range fsys.dirHandles {
+		if h.refc == 0 {
+			idle = h
+			break
+		}
+	}
+	if idle == nil {
+		fsys.mu.Unlock()
+		return false
+	}
+	fsys.unshareDir(idle)
+	fsys.mu.Unlock()
+	if err := fsys.closeDirs([]*dirHandle{idle}); err != nil {
+		fsys.closeError(err)
+	}
+	return true
+}
+
+// readDir reads the named directory opened by Open, sorted
+// by name like fs.ReadDir.
+func (fsys *FS) readDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(openFS{fsys}, name)
+}
+
+// openFS hides the methods of FS other than Open.
+type openFS struct {
+	fsys *FS
+}
+
+func (o openFS) Open(name string) (fs.File, error) {
+	return o.fsys.Open(name)
+}
+
+// closeDirs closes dirs and releases them from the limiter.
+func (fsys *FS) closeDirs(dirs []*dirHandle) error {
+	var errs []error
+	for _, h := range dirs {
+		err := h.dir.Close()
+		fsys.limiter().release()
+		if err != nil {
+			errs = append(errs, &fs.PathError{Op: "close", Path: h.name, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// releaseDir releases a reference to h and closes it if it's
+// not shared anymore or expired.
+func (fsys *FS) releaseDir(h *dirHandle) error {
+	fsys.mu.Lock()
+	h.refc--
+	if h.refc > 0 || !h.stale && time.Now().Before(h.expires) {
+		fsys.mu.Unlock()
+		return nil
+	}
+	if !h.stale {
+		fsys.unshareDir(h)
+	}
+	fsys.mu.Unlock()
+	return fsys.closeDirs([]*dirHandle{h})
+}
+
+// list returns the entries of the directory.
+func (h *dirHandle) list(cache bool) ([]fs.DirEntry, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.listed {
+		return h.entries, nil
+	}
+	rd, ok := h.dir.(fs.ReadDirFile)
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: h.name, Err: compat.ErrUnsupported}
+	}
+	if h.read {
+		s, ok := h.dir.(io.Seeker)
+		if !ok {
+			return nil, &fs.PathError{Op: "readdir", Path: h.name, Err: compat.ErrUnsupported}
+		}
+		if _, err := s.Seek(0, io.SeekStart); err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: h.name, Err: err}
+		}
+	}
+	h.read = true
+	entries, err := rd.ReadDir(-1)
+	if err != nil {
+		return nil, err
+	}
+	if cache {
+		h.entries = entries
+		h.listed = true
+	}
+	return entries, nil
+}
+
+// dirFile is an open of a shared directory.
+type dirFile struct {
+	fsys    *FS
+	h       *dirHandle
+	entries []fs.DirEntry // nil until read
+	read    bool
+	closed  bool
+}
+
+var _ fs.ReadDirFile = (*dirFile)(nil)
+
+func (d *dirFile) Stat() (fs.FileInfo, error) {
+	if d.closed {
+		return nil, &fs.PathError{Op: "stat", Path: d.h.name, Err: fs.ErrClosed}
+	}
+	if d.h.fi != nil {
+		return d.h.fi, nil
+	}
+	d.h.mu.Lock()
+	defer d.h.mu.Unlock()
+	return d.h.dir.Stat()
+}
+
+func (d *dirFile) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.h.name, Err: syscall.EISDIR}
+}
+
+func (d *dirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if d.closed {
+		return nil, &fs.PathError{Op: "readdir", Path: d.h.name, Err: fs.ErrClosed}
+	}
+	if !d.read {
+		entries, err := d.h.list(d.fsys.dirListings)
+		if err != nil {
+			return nil, err
+		}
+		// callers may sort the entries, like fs.ReadDir
+		d.entries = append([]fs.DirEntry(nil), entries...)
+		d.read = true
+	}
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries := d.entries[:n:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+func (d *dirFile) Close() error {
+	if d.closed {
+		return fs.ErrClosed
+	}
+	d.closed = true
+	return d.fsys.releaseDir(d.h)
+}
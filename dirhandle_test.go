@@ -0,0 +1,182 @@
+package singleopen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// dirCountFS counts opens and closes of directories.
+type dirCountFS struct {
+	fs.StatFS
+	opens  atomic.Int32
+	closes atomic.Int32
+}
+
+func (c *dirCountFS) Open(name string) (fs.File, error) {
+	f, err := c.StatFS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if fi, err := f.Stat(); err == nil && fi.IsDir() {
+		c.opens.Add(1)
+		return &countDir{f.(fs.ReadDirFile), &c.closes}, nil
+	}
+	return f, nil
+}
+
+type countDir struct {
+	fs.ReadDirFile
+	closes *atomic.Int32
+}
+
+func (d *countDir) Seek(offset int64, whence int) (int64, error) {
+	return d.ReadDirFile.(io.Seeker).Seek(offset, whence)
+}
+
+func (d *countDir) Close() error {
+	d.closes.Add(1)
+	return d.ReadDirFile.Close()
+}
+
+func TestDirHandles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b", "c"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, listings := range []bool{false, true} {
+		t.Run(fmt.Sprint("listings=", listings), func(t *testing.T) {
+			cfs := &dirCountFS{StatFS: os.DirFS(dir).(fs.StatFS)}
+			fsys, err := New(cfs, WithDirHandles(time.Minute, listings))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer fsys.Close()
+			d1, err := fsys.Open(".")
+			if err != nil {
+				t.Fatal(err)
+			}
+			d2, err := fsys.Open(".")
+			if err != nil {
+				t.Fatal(err)
+			}
+			// interleaved reads have their own position
+			var names [2][]string
+			for {
+				done := 0
+				for i, d := range []fs.File{d1, d2} {
+					entries, err := d.(fs.ReadDirFile).ReadDir(1)
+					if err == io.EOF {
+						done++
+						continue
+					}
+					if err != nil {
+						t.Fatal(err)
+					}
+					names[i] = append(names[i], entries[0].Name())
+				}
+				if done == 2 {
+					break
+				}
+			}
+			for _, n := range names {
+				sort.Strings(n)
+				if !reflect.DeepEqual(n, []string{"a", "b", "c"}) {
+					t.Errorf("got entries %v, want a, b, c", n)
+				}
+			}
+			d1.Close()
+			d2.Close()
+			if err := fs.WalkDir(fsys, ".", func(string, fs.DirEntry, error) error { return nil }); err != nil {
+				t.Fatal(err)
+			}
+			if n := cfs.opens.Load(); n != 1 {
+				t.Errorf("got %d opens of the directory, want 1", n)
+			}
+
+			if err := os.WriteFile(filepath.Join(dir, "d"), nil, 0o666); err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(filepath.Join(dir, "d"))
+			if err := fsys.Invalidate("d"); err != nil {
+				t.Fatal(err)
+			}
+			entries, err := fsys.ReadDir(".")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 4 {
+				t.Errorf("got %d entries after invalidation, want 4", len(entries))
+			}
+			if n := cfs.opens.Load(); n != 2 {
+				t.Errorf("got %d opens of the directory, want 2", n)
+			}
+		})
+	}
+}
+
+func TestDirHandlesExpire(t *testing.T) {
+	cfs := &dirCountFS{StatFS: os.DirFS(t.TempDir()).(fs.StatFS)}
+	fsys, err := New(cfs, WithDirHandles(10*time.Millisecond, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+	d, err := fsys.Open(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for cfs.closes.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("directory not closed after ttl")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if n := fsys.limiter().count(); n != 0 {
+		t.Errorf("got %d reserved files, want 0", n)
+	}
+}
+
+func TestDirHandlesMaxOpen(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a"), nil, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	cfs := &dirCountFS{StatFS: os.DirFS(dir).(fs.StatFS)}
+	fsys, err := New(cfs, WithDirHandles(time.Minute, false), WithMaxOpen(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+	d, err := fsys.Open(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := fsys.OpenContext(ctx, "a"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v while the directory is open, want %v", err, context.DeadlineExceeded)
+	}
+	d.Close()
+	f, err := fsys.Open("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if n := cfs.closes.Load(); n != 1 {
+		t.Errorf("got %d closes of the directory, want 1", n)
+	}
+}
@@ -0,0 +1,103 @@
+package singleopen
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// WithDup returns an Option that gives every Open of a file
+// backed by an *os.File its own file descriptor, duplicated
+// from the shared descriptor with dup(2) instead of opened by
+// path. The duplicate shares the kernel offset, so it's read
+// at an offset of its own with pread(2). Every duplicate
+// counts for MaxOpen and isn't cached. If the file isn't an
+// *os.File, it can't be duplicated or the limit of MaxOpen is
+// reached and no file can be evicted, the shared file is
+// returned as usual. It's only supported on Unix systems.
+func WithDup() Option {
+	return func(f *FS) error {
+		f.dup = true
+		return nil
+	}
+}
+
+// dupHandle returns a handle of f with its own descriptor.
+func (f *file) dupHandle() (fs.File, bool) {
+	f.swap.RLock()
+	of, ok := osFile(f.File)
+	f.swap.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	if !f.fsys.limiter().tryAcquire(f.fsys.evict) {
+		return nil, false
+	}
+	df, err := dupDescriptor(of)
+	if err != nil {
+		f.fsys.limiter().release()
+		f.fsys.debug("duplicate file descriptor", f.name, "error", err)
+		return nil, false
+	}
+	return &dupFile{File: df, f: f}, true
+}
+
+// dupFile is a duplicated descriptor of a shared file, read
+// at its own offset.
+type dupFile struct {
+	*os.File
+	f      *file
+	offset int64
+}
+
+// Unwrap returns the descriptor of the handle.
+func (d *dupFile) Unwrap() fs.File {
+	return d.File
+}
+
+func (d *dupFile) Read(p []byte) (int, error) {
+	n, err := d.File.ReadAt(p, d.offset)
+	d.offset += int64(n)
+	return n, err
+}
+
+func (d *dupFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		// offset += 0
+	case io.SeekCurrent:
+		offset += d.offset
+	case io.SeekEnd:
+		fi, err := d.File.Stat()
+		if err != nil {
+			return 0, err
+		}
+		offset += fi.Size()
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: d.Name(), Err: fs.ErrInvalid}
+	}
+	d.offset = offset
+	return offset, nil
+}
+
+// WriteTo writes the file from the offset of d to w, the
+// WriteTo of *os.File uses the shared kernel offset.
+func (d *dupFile) WriteTo(w io.Writer) (int64, error) {
+	return io.Copy(w, struct{ io.Reader }{d})
+}
+
+func (d *dupFile) Close() error {
+	if d.f == nil {
+		return fs.ErrClosed
+	}
+	err := d.File.Close()
+	d.f.fsys.limiter().release()
+	if err != nil {
+		err = &fs.PathError{Op: "close", Path: d.f.name, Err: err}
+	}
+	f := d.f
+	d.f = nil
+	return errors.Join(err, f.Close())
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package singleopen
+
+import (
+	"os"
+
+	"github.com/dwlnetnl/singleopen/internal/compat"
+)
+
+func dupDescriptor(f *os.File) (*os.File, error) {
+	return nil, compat.ErrUnsupported
+}
@@ -0,0 +1,36 @@
+//go:build unix
+
+package singleopen
+
+import (
+	"os"
+	"syscall"
+)
+
+// dupDescriptor duplicates the descriptor of f with dup(2),
+// the duplicate shares the offset of f.
+func dupDescriptor(f *os.File) (*os.File, error) {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	var fd int
+	var derr error
+	err = rc.Control(func(p uintptr) {
+		// like os, hold ForkLock so the descriptor doesn't
+		// leak into a child before it's close-on-exec
+		syscall.ForkLock.RLock()
+		fd, derr = syscall.Dup(int(p))
+		if derr == nil {
+			syscall.CloseOnExec(fd)
+		}
+		syscall.ForkLock.RUnlock()
+	})
+	if err != nil {
+		return nil, err
+	}
+	if derr != nil {
+		return nil, &os.PathError{Op: "dup", Path: f.Name(), Err: derr}
+	}
+	return os.NewFile(uintptr(fd), f.Name()), nil
+}
@@ -0,0 +1,130 @@
+//go:build unix
+
+package singleopen
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDup(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte("content"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	fsys, err := New(os.DirFS(dir), WithDup())
+	if err != nil {
+		t.Fatal(err)
+	}
+	f1, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f2, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := fsys.Stats(); s.Shared != 1 || s.RefCount != 2 || s.Misses != 1 {
+		t.Errorf("got %+v, want a single shared file", s)
+	}
+	type unwrapper interface{ Unwrap() fs.File }
+	if f1.(unwrapper).Unwrap() == f2.(unwrapper).Unwrap() {
+		t.Error("got the same descriptor for both handles")
+	}
+	// handles have their own kernel offset
+	for _, f := range []fs.File{f1, f2} {
+		data, err := io.ReadAll(f)
+		if err != nil || string(data) != "content" {
+			t.Errorf("got %q, %v, want content", data, err)
+		}
+	}
+	if err := f1.Close(); err != nil {
+		t.Error(err)
+	}
+	if err := f1.Close(); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("got %v, want ErrClosed", err)
+	}
+	f2.Close()
+	if s := fsys.Stats(); s.Shared != 0 {
+		t.Errorf("got %d shared files, want 0", s.Shared)
+	}
+}
+
+func TestDupReaderAt(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte("content"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	fsys, err := New(os.DirFS(dir), WithDup())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	r, err := fsys.OpenReaderAt("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := make([]byte, 4)
+	if n, err := r.ReadAt(p, 3); err != nil || string(p[:n]) != "tent" {
+		t.Errorf("got %q, %v, want tent", p[:n], err)
+	}
+	r.Close()
+
+	s, err := fsys.OpenSection("file", 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(s)
+	if err != nil || string(data) != "ont" {
+		t.Errorf("got %q, %v, want ont", data, err)
+	}
+	s.Close()
+
+	data, err = fsys.ReadFile("file")
+	if err != nil || string(data) != "content" {
+		t.Errorf("got %q, %v, want content", data, err)
+	}
+	if s := fsys.Stats(); s.Shared != 0 {
+		t.Errorf("got %d shared files, want 0", s.Shared)
+	}
+}
+
+func TestDupMaxOpen(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte("content"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	fsys, err := New(os.DirFS(dir), WithDup(), WithMaxOpen(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+	f1, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f1.(*dupFile); !ok {
+		t.Errorf("got %T, want a duplicated descriptor", f1)
+	}
+	// the limit is reached by the shared file and f1
+	f2, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f2.(*dupFile); ok {
+		t.Error("got a duplicated descriptor beyond the limit")
+	}
+	if n := fsys.limiter().count(); n != 2 {
+		t.Errorf("got %d reserved files, want 2", n)
+	}
+	f1.Close()
+	if n := fsys.limiter().count(); n != 1 {
+		t.Errorf("got %d reserved files after close, want 1", n)
+	}
+	f2.Close()
+}
@@ -0,0 +1,69 @@
+package singleopen
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dwlnetnl/singleopen/cache"
+)
+
+// maxFailures is the maximum number of cached errors of opens.
+const maxFailures = 4096
+
+// failure is a cached error of an open.
+type failure struct {
+	err     error
+	expires time.Time
+}
+
+// WithErrorCache returns an Option that returns the error of
+// a failed open to opens of the same file within window after
+// the failure, instead of opening the file again. If cacheable
+// is not nil, only errors for which it reports true are cached.
+// Context errors are never cached. Invalidate drops the error.
+// At most 4096 errors are cached, the least recently used are
+// dropped first.
+func WithErrorCache(window time.Duration, cacheable func(error) bool) Option {
+	return func(f *FS) error {
+		if window <= 0 {
+			return fmt.Errorf("singleopen: invalid error cache window %v", window)
+		}
+		f.errWindow = window
+		f.errCacheable = cacheable
+		return nil
+	}
+}
+
+// cachedError returns the cached error of opening name.
+// fsys.mu must be held.
+func (fsys *FS) cachedError(name string) error {
+	if fsys.failures == nil {
+		return nil
+	}
+	fl, ok := fsys.failures.Get(name)
+	if !ok {
+		return nil
+	}
+	if !time.Now().Before(fl.expires) {
+		fsys.failures.Remove(name)
+		return nil
+	}
+	return fl.err
+}
+
+// openFailed records that opening name failed with err.
+func (fsys *FS) openFailed(name string, err error) {
+	fsys.storeMissing(name, err)
+	if fsys.errWindow == 0 || isContextErr(err) {
+		return
+	}
+	if fsys.errCacheable != nil && !fsys.errCacheable(err) {
+		return
+	}
+	fsys.mu.Lock()
+	if fsys.failures == nil {
+		fsys.failures = cache.New[string, failure](maxFailures)
+	}
+	fsys.failures.Add(name, failure{err, time.Now().Add(fsys.errWindow)})
+	fsys.mu.Unlock()
+}
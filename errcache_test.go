@@ -0,0 +1,52 @@
+package singleopen
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"syscall"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestErrorCache(t *testing.T) {
+	ffs := &flakyFS{FS: fstest.MapFS{"file": &fstest.MapFile{}}}
+	ffs.n.Store(1)
+	fsys, err := New(ffs, WithErrorCache(time.Hour, func(err error) bool {
+		return !errors.Is(err, fs.ErrNotExist)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := fsys.Open("file"); !errors.Is(err, syscall.EIO) {
+			t.Fatalf("got %v, want EIO", err)
+		}
+		if _, err := fsys.Open("missing"); !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("got %v, want not exist", err)
+		}
+	}
+	if n := ffs.opens.Load(); n != 4 {
+		t.Errorf("got %d opens, want 4", n)
+	}
+	fsys.Invalidate("file")
+	f, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+}
+
+func TestErrorCacheLimit(t *testing.T) {
+	fsys, err := New(fstest.MapFS{}, WithErrorCache(time.Hour, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxFailures+10; i++ {
+		fsys.Open(fmt.Sprint("missing", i))
+	}
+	if n := fsys.failures.Len(); n != maxFailures {
+		t.Errorf("got %d cached errors, want %d", n, maxFailures)
+	}
+}
@@ -0,0 +1,41 @@
+package singleopen
+
+import (
+	"errors"
+	"expvar"
+	"sync"
+)
+
+// WithExpvar returns an Option that publishes the Stats of
+// the FS to expvar under name. The name is published when
+// New succeeds, publishing a name that is already in use is
+// an error.
+func WithExpvar(name string) Option {
+	return func(f *FS) error {
+		if name == "" {
+			return errors.New("singleopen: empty expvar name")
+		}
+		f.expvar = name
+		return nil
+	}
+}
+
+// expvarMu serializes checking and publishing names.
+var expvarMu sync.Mutex
+
+// publishExpvar publishes the Stats of fsys as set by
+// WithExpvar.
+func (fsys *FS) publishExpvar() error {
+	if fsys.expvar == "" {
+		return nil
+	}
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+	if expvar.Get(fsys.expvar) != nil {
+		return errors.New("singleopen: expvar " + fsys.expvar + " already published")
+	}
+	expvar.Publish(fsys.expvar, expvar.Func(func() interface{} {
+		return fsys.Stats()
+	}))
+	return nil
+}
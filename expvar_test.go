@@ -0,0 +1,41 @@
+package singleopen
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestExpvar(t *testing.T) {
+	name := fmt.Sprintf("singleopen_test_%d", time.Now().UnixNano())
+	fsys, err := New(fstest.MapFS{"file": &fstest.MapFile{}}, WithExpvar(name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _ := fsys.Open("file")
+	f.Close()
+
+	var s Stats
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Opens != 1 || s.Misses != 1 {
+		t.Errorf("got %+v", s)
+	}
+
+	if _, err := New(fstest.MapFS{}, WithExpvar(name)); err == nil {
+		t.Error("expected error for published expvar name")
+	}
+
+	// the name is published when New succeeds
+	name2 := name + "_invalid"
+	if _, err := New(fstest.MapFS{}, WithExpvar(name2), WithKeepLast(-1)); err == nil {
+		t.Error("expected error for negative keep last count")
+	}
+	if expvar.Get(name2) != nil {
+		t.Error("expvar is published by failing New")
+	}
+}
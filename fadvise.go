@@ -0,0 +1,75 @@
+package singleopen
+
+import (
+	"fmt"
+	"io/fs"
+
+	"github.com/dwlnetnl/singleopen/internal/compat"
+)
+
+// Advice is an access pattern of a file, passed to the kernel
+// with posix_fadvise(2) to tune readahead.
+type Advice int
+
+const (
+	AdviceNormal     Advice = iota // no specific pattern
+	AdviceSequential               // read from start to end
+	AdviceRandom                   // read at random offsets
+	AdviceWillNeed                 // read soon, start readahead
+)
+
+// WithAdvice returns an Option that advises the access
+// pattern a for files opened on the underlying file system
+// and, if dontNeed is true, that the cached data of a file
+// is not needed anymore when the file is closed. Advice is
+// only given for files that are (or unwrap to) an *os.File
+// on Linux, it's ignored otherwise.
+func WithAdvice(a Advice, dontNeed bool) Option {
+	return func(f *FS) error {
+		if a < AdviceNormal || a > AdviceWillNeed {
+			return fmt.Errorf("singleopen: invalid advice %d", a)
+		}
+		f.advice = a
+		f.dontNeed = dontNeed
+		return nil
+	}
+}
+
+// Advise advises the access pattern a for the file f, like
+// a file returned by Open. Because files are shared, the
+// advice applies to all users of the file. It returns an
+// error wrapping compat.ErrUnsupported if f is not an
+// *os.File or the platform has no support.
+func Advise(f fs.File, a Advice) error {
+	of, ok := osFile(f)
+	if !ok {
+		return fmt.Errorf("singleopen: advise %T: %w", f, compat.ErrUnsupported)
+	}
+	return fadvise(of, a)
+}
+
+// adviseOpen gives the advice of WithAdvice for a file that
+// is opened.
+func (fsys *FS) adviseOpen(name string, f fs.File) {
+	if fsys.advice == AdviceNormal {
+		return
+	}
+	if of, ok := osFile(f); ok {
+		if err := fadvise(of, fsys.advice); err != nil {
+			fsys.debug("advise file", name, "err", err)
+		}
+	}
+}
+
+// adviseClose advises that the data of a file that is about
+// to be closed is not needed anymore, if enabled.
+func (fsys *FS) adviseClose(name string, f fs.File) {
+	if !fsys.dontNeed {
+		return
+	}
+	if of, ok := osFile(f); ok {
+		if err := fadviseDontNeed(of); err != nil {
+			fsys.debug("advise file", name, "err", err)
+		}
+	}
+}
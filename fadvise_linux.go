@@ -0,0 +1,43 @@
+package singleopen
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+var fadvices = [...]int{
+	AdviceNormal:     unix.FADV_NORMAL,
+	AdviceSequential: unix.FADV_SEQUENTIAL,
+	AdviceRandom:     unix.FADV_RANDOM,
+	AdviceWillNeed:   unix.FADV_WILLNEED,
+}
+
+func fadvise(f *os.File, a Advice) error {
+	return fadviseRange(f, 0, 0, fadvices[a])
+}
+
+func fadviseDontNeed(f *os.File) error {
+	return fadviseRange(f, 0, 0, unix.FADV_DONTNEED)
+}
+
+func fadviseWillNeed(f *os.File, off, n int64) error {
+	return fadviseRange(f, off, n, unix.FADV_WILLNEED)
+}
+
+// fadviseRange applies advice to n bytes of f at off, n == 0
+// means up to the end of the file.
+func fadviseRange(f *os.File, off, n int64, advice int) error {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var ferr error
+	err = rc.Control(func(fd uintptr) {
+		ferr = unix.Fadvise(int(fd), off, n, advice)
+	})
+	if err != nil {
+		return err
+	}
+	return os.NewSyscallError("fadvise", ferr)
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package singleopen
+
+import (
+	"os"
+
+	"github.com/dwlnetnl/singleopen/internal/compat"
+)
+
+func fadvise(f *os.File, a Advice) error {
+	return compat.ErrUnsupported
+}
+
+func fadviseDontNeed(f *os.File) error {
+	return compat.ErrUnsupported
+}
+
+func fadviseWillNeed(f *os.File, off, n int64) error {
+	return compat.ErrUnsupported
+}
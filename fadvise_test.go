@@ -0,0 +1,40 @@
+package singleopen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dwlnetnl/singleopen/internal/compat"
+)
+
+func TestAdvice(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fsys, err := New(os.DirFS(dir), WithAdvice(AdviceSequential, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Advise(f, AdviceRandom); err != nil && !errors.Is(err, compat.ErrUnsupported) {
+		t.Error(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Error(err)
+	}
+
+	mf, _ := fstest.MapFS{"file": &fstest.MapFile{}}.Open("file")
+	if err := Advise(mf, AdviceRandom); !errors.Is(err, compat.ErrUnsupported) {
+		t.Errorf("got %v, want unsupported", err)
+	}
+	if _, err := New(os.DirFS(dir), WithAdvice(Advice(-1), false)); err == nil {
+		t.Error("expected error for invalid advice")
+	}
+}
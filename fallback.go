@@ -0,0 +1,98 @@
+package singleopen
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+)
+
+// WithFallback returns an Option that opens and stats files
+// on fallback when it fails on the underlying file system and
+// retry reports true for the error, or for any error if retry
+// is nil. Canceled opens don't fall back. Files opened on
+// fallback are shared and cached like any other file, which
+// serves a local overlay of a remote origin without another
+// caching layer. The underlying file system is replaced by
+// a file system that falls back, the error of fallback is
+// returned if both fail.
+func WithFallback(fallback fs.FS, retry func(error) bool) Option {
+	return func(f *FS) error {
+		if fallback == nil {
+			return errors.New("singleopen: nil fallback file system")
+		}
+		f.FS = &fallbackFS{FS: f.FS, fallback: fallback, retry: retry}
+		return nil
+	}
+}
+
+// fallbackFS is a file system that falls back to another one.
+type fallbackFS struct {
+	fs.FS
+	fallback fs.FS
+	retry    func(error) bool
+}
+
+var (
+	_ OpenContextFS = (*fallbackFS)(nil)
+	_ StatContextFS = (*fallbackFS)(nil)
+	_ fs.StatFS     = (*fallbackFS)(nil)
+)
+
+// falls reports whether err falls back.
+func (f *fallbackFS) falls(err error) bool {
+	if isContextErr(err) {
+		return false
+	}
+	return f.retry == nil || f.retry(err)
+}
+
+func (f *fallbackFS) Open(name string) (fs.File, error) {
+	return f.OpenContext(context.Background(), name)
+}
+
+func (f *fallbackFS) OpenContext(ctx context.Context, name string) (fs.File, error) {
+	ff, err := openContext(ctx, f.FS, name)
+	if err == nil || !f.falls(err) {
+		return ff, err
+	}
+	return openContext(ctx, f.fallback, name)
+}
+
+func (f *fallbackFS) Stat(name string) (fs.FileInfo, error) {
+	return f.StatContext(context.Background(), name)
+}
+
+func (f *fallbackFS) StatContext(ctx context.Context, name string) (fs.FileInfo, error) {
+	fi, err := statContext(ctx, f.FS, name)
+	if err == nil || !f.falls(err) {
+		return fi, err
+	}
+	return statContext(ctx, f.fallback, name)
+}
+
+// openContext opens name on fsys, passing ctx if supported.
+func openContext(ctx context.Context, fsys fs.FS, name string) (fs.File, error) {
+	if cfs, ok := fsys.(OpenContextFS); ok {
+		return cfs.OpenContext(ctx, name)
+	}
+	return fsys.Open(name)
+}
+
+// statContext stats name on fsys like fs.Stat, passing ctx if
+// supported.
+func statContext(ctx context.Context, fsys fs.FS, name string) (fs.FileInfo, error) {
+	if sfs, ok := fsys.(StatContextFS); ok {
+		return sfs.StatContext(ctx, name)
+	}
+	return fs.Stat(fsys, name)
+}
+
+// canStat reports whether fsys stats files without opening
+// them.
+func canStat(fsys fs.FS) bool {
+	switch fsys.(type) {
+	case fs.StatFS, StatContextFS:
+		return true
+	}
+	return false
+}
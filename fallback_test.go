@@ -0,0 +1,46 @@
+package singleopen
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFallback(t *testing.T) {
+	origin := fstest.MapFS{
+		"local":  &fstest.MapFile{Data: []byte("origin")},
+		"remote": &fstest.MapFile{Data: []byte("remote")},
+	}
+	fsys, err := New(fstest.MapFS{
+		"local": &fstest.MapFile{Data: []byte("local")},
+	}, WithFallback(origin, func(err error) bool {
+		return errors.Is(err, fs.ErrNotExist)
+	}), WithKeepLast(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"local": "local", "remote": "remote"} {
+		data, err := fsys.ReadFile(name)
+		if err != nil || string(data) != want {
+			t.Errorf("got %q, %v, want %q", data, err, want)
+		}
+		if _, err := fsys.Stat(name); err != nil {
+			t.Error(err)
+		}
+	}
+	f, err := fsys.Open("remote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if s := fsys.Stats(); s.Misses != 2 || s.CacheHits != 1 {
+		t.Errorf("got %+v, want remote file to be cached", s)
+	}
+	if _, err := fsys.Open("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %v, want ErrNotExist", err)
+	}
+	if _, err := New(origin, WithFallback(nil, nil)); err == nil {
+		t.Error("expected error for nil fallback")
+	}
+}
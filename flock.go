@@ -0,0 +1,86 @@
+package singleopen
+
+import (
+	"context"
+	"io/fs"
+)
+
+// WithFileLocks returns an Option that coordinates with other
+// processes using advisory locks, like flock(2) on Unix. A
+// shared file is locked shared while it has readers, Open
+// waits for a writer to unlock the file and OpenContext until
+// its context is done. Files opened for writing by OpenFile
+// are locked exclusive until they are closed, OpenFileContext
+// waits for the lock until its context is done, appenders lock
+// the file exclusive for every Write. Locks are taken on the
+// open file, so readers and writers of this process wait for
+// each other as well. Only files of the underlying file
+// system that implement syscall.Conn, like *os.File, are
+// locked. WriteFileAtomic doesn't lock, renaming doesn't
+// change open files.
+func WithFileLocks() Option {
+	return func(f *FS) error {
+		f.flocks = true
+		return nil
+	}
+}
+
+// lockMode is the mode of an advisory lock.
+type lockMode int
+
+const (
+	lockNone lockMode = iota
+	lockShared
+	lockExclusive
+)
+
+// lockShared locks the shared file f shared if it isn't
+// locked already, waiting until ctx is done. It's called with
+// a reference to f.
+func (f *file) lockShared(ctx context.Context) error {
+	if !f.fsys.flocks {
+		return nil
+	}
+	f.flmu.Lock()
+	defer f.flmu.Unlock()
+	if f.flocked {
+		return nil
+	}
+	f.swap.RLock()
+	err := flock(ctx, f.File, lockShared)
+	f.swap.RUnlock()
+	if err != nil {
+		return &fs.PathError{Op: "flock", Path: f.name, Err: err}
+	}
+	f.flocked = true
+	return nil
+}
+
+// unlock unlocks f when it becomes idle, so writers of other
+// processes don't wait for cached files.
+func (f *file) unlock() {
+	if !f.fsys.flocks {
+		return
+	}
+	f.flmu.Lock()
+	defer f.flmu.Unlock()
+	if !f.flocked {
+		return
+	}
+	if err := flock(context.Background(), f.File, lockNone); err != nil {
+		f.fsys.closeError(&fs.PathError{Op: "flock", Path: f.name, Err: err})
+	}
+	f.flocked = false
+}
+
+// lockWrite locks the file f opened for writing by name,
+// waiting until ctx is done.
+func (fsys *FS) lockWrite(ctx context.Context, name string, f fs.File, mode lockMode) error {
+	if !fsys.flocks {
+		return nil
+	}
+	if err := flock(ctx, f, mode); err != nil {
+		return &fs.PathError{Op: "flock", Path: name, Err: err}
+	}
+	return nil
+}
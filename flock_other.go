@@ -0,0 +1,13 @@
+//go:build !unix
+
+package singleopen
+
+import (
+	"context"
+	"io/fs"
+)
+
+// flock does nothing, advisory locks are not supported.
+func flock(ctx context.Context, f fs.File, mode lockMode) error {
+	return nil
+}
@@ -0,0 +1,66 @@
+//go:build unix
+
+package singleopen
+
+import (
+	"context"
+	"io/fs"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// maxFlockBackoff is the longest wait between attempts to take
+// a conflicting lock.
+const maxFlockBackoff = 50 * time.Millisecond
+
+// flock sets the advisory lock of f to mode, waiting for
+// conflicting locks until ctx is done. The lock is polled, a
+// blocking flock(2) can't be interrupted. Files without a
+// descriptor are ignored.
+func flock(ctx context.Context, f fs.File, mode lockMode) error {
+	sc, ok := f.(syscall.Conn)
+	if !ok {
+		return nil
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return err
+	}
+	how := unix.LOCK_UN
+	switch mode {
+	case lockShared:
+		how = unix.LOCK_SH | unix.LOCK_NB
+	case lockExclusive:
+		how = unix.LOCK_EX | unix.LOCK_NB
+	}
+	backoff := time.Millisecond
+	for {
+		var ferr error
+		err = rc.Control(func(fd uintptr) {
+			for {
+				ferr = unix.Flock(int(fd), how)
+				if ferr != unix.EINTR {
+					return
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if ferr != unix.EWOULDBLOCK {
+			return ferr
+		}
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+		if backoff *= 2; backoff > maxFlockBackoff {
+			backoff = maxFlockBackoff
+		}
+	}
+}
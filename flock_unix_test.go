@@ -0,0 +1,158 @@
+//go:build unix && go1.25
+
+package singleopen
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileLocks(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "file")
+	if err := os.WriteFile(name, []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+	fsys, err := New(RootFS(root), WithFileLocks(), WithKeepLast(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+	// ext is the file opened by another process
+	ext, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ext.Close()
+	waits := func(what string, fn func() error, unlock func()) {
+		t.Helper()
+		done := make(chan error, 1)
+		go func() { done <- fn() }()
+		select {
+		case err := <-done:
+			t.Fatalf("%s didn't wait: %v", what, err)
+		case <-time.After(50 * time.Millisecond):
+		}
+		unlock()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("%s: %v", what, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s still waits after unlock", what)
+		}
+	}
+
+	r, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// cached file is unlocked
+	waits("external writer", func() error {
+		return flock(context.Background(), ext, lockExclusive)
+	}, func() { r.Close() })
+	waits("open", func() error {
+		_, err := fsys.ReadFile("file")
+		return err
+	}, func() { flock(context.Background(), ext, lockNone) })
+
+	w, err := fsys.OpenFile("file", os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waits("external reader", func() error {
+		return flock(context.Background(), ext, lockShared)
+	}, func() { w.Close() })
+}
+
+func TestOpenFileContextLock(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+	fsys, err := New(RootFS(root), WithFileLocks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+	r, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the reader of this FS holds a shared lock
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := fsys.OpenFileContext(ctx, "file", os.O_WRONLY, 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+	r.Close()
+	w, err := fsys.OpenFileContext(context.Background(), "file", os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+}
+
+func TestFileLocksProcess(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+	fsys, err := New(RootFS(root), WithFileLocks(), WithKeepLast(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+	if _, err := fsys.ReadFile("file"); err != nil {
+		t.Fatal(err)
+	}
+	w, err := fsys.OpenFile("file", os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the cached file is opened while the writer has it locked
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := fsys.OpenContext(ctx, "file"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	done := make(chan error, 1)
+	go func() {
+		r, err := fsys.OpenContext(context.Background(), "file")
+		if err == nil {
+			err = r.Close()
+		}
+		done <- err
+	}()
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reader still waits after the writer is closed")
+	}
+}
@@ -0,0 +1,110 @@
+package singleopen
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"sync"
+)
+
+// WithFreshnessCheck returns an Option that checks whether a
+// file changed before it's reused. The size and modification
+// time from Stat are compared with the values when the file
+// was opened; if they differ the file is invalidated and
+// opened again. This makes reuse safe for files that are
+// modified in place, at the cost of a Stat per open.
+func WithFreshnessCheck() Option {
+	return func(f *FS) error {
+		f.checkFresh = true
+		return nil
+	}
+}
+
+// isFresh reports whether f is unchanged since it was opened.
+func (fsys *FS) isFresh(f *file) bool {
+	changed, err := fsys.changed(f)
+	return err == nil && !changed
+}
+
+// reopen releases the reference to the changed file f and
+// opens it again.
+func (fsys *FS) reopen(ctx context.Context, f *file, info *OpenInfo) (*file, fs.File, error) {
+	fsys.debug("reopen changed file", f.name)
+	f.shard.mu.Lock()
+	fsys.mu.Lock()
+	if g, _ := f.shard.load(f.key); g == f {
+		fsys.invalidate(f.name)
+	}
+	f.stale = true
+	fsys.mu.Unlock()
+	f.shard.mu.Unlock()
+	f.Close()
+	*info = OpenInfo{}
+	return fsys.openShared(ctx, f.name, info)
+}
+
+// Refresh stats all shared and cached files and invalidates
+// the files that changed or no longer exist, see Invalidate.
+// At most concurrency files are checked at once. Other stat
+// errors are joined and returned.
+func (fsys *FS) Refresh(ctx context.Context, concurrency int) error {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	files := fsys.sharedFiles()
+	fsys.mu.Lock()
+	if fsys.cache != nil {
+		for _, f := range fsys.cache.files {
+			files = append(files, f)
+		}
+	}
+	fsys.mu.Unlock()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+		sem  = make(chan struct{}, concurrency)
+	)
+	for _, f := range files {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+		wg.Add(1)
+		go func(f *file) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			changed, err := fsys.changed(f)
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+				return
+			}
+			if changed {
+				fsys.debug("refresh changed file", f.name)
+				fsys.Invalidate(f.name)
+			}
+		}(f)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// changed reports whether f changed since it was opened. A
+// file that does not exist anymore is changed.
+func (fsys *FS) changed(f *file) (bool, error) {
+	fi, err := fs.Stat(fsys.base(), f.name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	return fi.Size() != f.size || !fi.ModTime().Equal(f.mod), nil
+}
@@ -0,0 +1,33 @@
+package singleopen
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestFreshnessCheck(t *testing.T) {
+	mfs := fstest.MapFS{"file": &fstest.MapFile{Data: []byte("old")}}
+	fsys, err := New(mfs, WithKeepLast(4), WithFreshnessCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	fsys.Preopen("file")
+	mfs["file"] = &fstest.MapFile{Data: []byte("changed"), ModTime: time.Now()}
+	data, err := fsys.ReadFile("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "changed" {
+		t.Errorf("got %q, want changed", data)
+	}
+	data, err = fsys.ReadFile("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := fsys.Stats(); string(data) != "changed" || s.Misses != 2 || s.CacheHits != 2 {
+		t.Errorf("got %q and %+v, want unchanged file to be reused", data, s)
+	}
+}
@@ -0,0 +1,83 @@
+package singleopen
+
+import (
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/dwlnetnl/singleopen/cache"
+)
+
+var _ fs.GlobFS = (*FS)(nil)
+
+// maxGlobs is the maximum number of cached results of Glob.
+const maxGlobs = 4096
+
+// globResult is a cached result of Glob.
+type globResult struct {
+	matches []string
+	expires time.Time
+}
+
+// WithGlobCache returns an Option that caches the results
+// of Glob for ttl. At most 4096 results are cached, the least
+// recently used are dropped first.
+func WithGlobCache(ttl time.Duration) Option {
+	return func(f *FS) error {
+		if ttl <= 0 {
+			return fmt.Errorf("singleopen: invalid glob cache ttl %v", ttl)
+		}
+		f.globTTL = ttl
+		return nil
+	}
+}
+
+// Glob returns the names of all files matching pattern.
+// Glob delegates to the underlying file system and caches
+// the result if enabled with WithGlobCache.
+func (fsys *FS) Glob(pattern string) ([]string, error) {
+	fsys.mu.Lock()
+	if fsys.closed.Load() {
+		fsys.mu.Unlock()
+		return nil, &fs.PathError{Op: "glob", Path: pattern, Err: fs.ErrClosed}
+	}
+	if fsys.globs != nil {
+		if gr, ok := fsys.globs.Get(pattern); ok {
+			if time.Now().Before(gr.expires) {
+				fsys.mu.Unlock()
+				return append([]string(nil), gr.matches...), nil
+			}
+			fsys.globs.Remove(pattern)
+		}
+	}
+	fsys.mu.Unlock()
+
+	matches, err := fs.Glob(fsys.base(), pattern)
+	if err != nil || fsys.globTTL == 0 {
+		return matches, err
+	}
+
+	fsys.mu.Lock()
+	if fsys.globs == nil {
+		fsys.globs = cache.New[string, globResult](maxGlobs)
+	}
+	fsys.globs.Add(pattern, globResult{
+		matches: append([]string(nil), matches...),
+		expires: time.Now().Add(fsys.globTTL),
+	})
+	fsys.mu.Unlock()
+	return matches, nil
+}
+
+// clearMetadata drops the cached directory listings, file
+// infos, glob results, missing files, open errors and digests.
+// fsys.mu must be held.
+func (fsys *FS) clearMetadata() {
+	fsys.dirs = nil
+	fsys.statc = nil
+	fsys.globs = nil
+	fsys.missing = nil
+	fsys.failures = nil
+	fsys.digests = nil
+	fsys.inodes = nil
+}
@@ -0,0 +1,45 @@
+package singleopen
+
+import (
+	"fmt"
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestGlob(t *testing.T) {
+	mfs := fstest.MapFS{"tmpl/a.html": &fstest.MapFile{}}
+	fsys, err := New(mfs, WithGlobCache(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches, err := fs.Glob(fsys, "tmpl/*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mfs["tmpl/b.html"] = &fstest.MapFile{}
+	cached, err := fsys.Glob("tmpl/*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(matches, []string{"tmpl/a.html"}) || !reflect.DeepEqual(cached, matches) {
+		t.Errorf("got matches %q and %q, want [tmpl/a.html]", matches, cached)
+	}
+}
+
+func TestGlobCacheLimit(t *testing.T) {
+	fsys, err := New(fstest.MapFS{}, WithGlobCache(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxGlobs+10; i++ {
+		if _, err := fsys.Glob(fmt.Sprint("*.", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := fsys.globs.Len(); n != maxGlobs {
+		t.Errorf("got %d cached globs, want %d", n, maxGlobs)
+	}
+}
@@ -1,5 +1,8 @@
 module github.com/dwlnetnl/singleopen
 
-go 1.16
+go 1.20
 
-require golang.org/x/sync v0.0.0-20210220032951-036812b2e83c
+require (
+	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c
+	golang.org/x/sys v0.30.0
+)
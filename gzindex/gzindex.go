@@ -0,0 +1,231 @@
+// Package gzindex provides random access to the decompressed
+// contents of gzip files opened through a singleopen.FS. An
+// index of access points is built once, reads decompress from
+// the closest point before the offset out of the shared
+// compressed file:
+//
+//	f, err := gzindex.Open(fsys, "access.log.gz")
+//	defer f.Close()
+//	r := io.NewSectionReader(f, off, n)
+//
+// Like zran of zlib, the access points are the starts of the
+// gzip members and of deflate blocks about every span bytes of
+// contents, which store the window of contents before them. A
+// file of a single member seeks as fast as a file compressed
+// in independent members, like by bgzip.
+package gzindex
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+	"sync"
+
+	"github.com/dwlnetnl/singleopen"
+	"github.com/dwlnetnl/singleopen/internal/compat"
+)
+
+// DefaultSpan is the span of access points of Open.
+const DefaultSpan = 1 << 20
+
+// Point is an access point, the start of a gzip member or of a
+// deflate block in a member.
+type Point struct {
+	In     int64  // offset in the compressed file
+	Bit    uint8  // of the first bit in the byte at In
+	Out    int64  // offset in the decompressed contents
+	Window []byte // up to 32 KiB of contents before Out, empty at a member
+}
+
+// Index is an index of the access points of a gzip file. It
+// can be stored to reopen the file without building it again.
+type Index struct {
+	Points []Point
+	Size   int64 // size of the decompressed contents
+}
+
+// BuildIndex builds the index of the gzip file of size bytes
+// in r by decompressing it. Access points in a member are at
+// least span bytes of contents apart, zero means DefaultSpan.
+func BuildIndex(r io.ReaderAt, size, span int64) (*Index, error) {
+	if span <= 0 {
+		span = DefaultSpan
+	}
+	return newInflater(io.NewSectionReader(r, 0, size), span).index()
+}
+
+// countReader counts the bytes read from a buffered reader,
+// it implements io.ByteReader so the decompressor doesn't
+// read ahead of a member.
+type countReader struct {
+	br *bufio.Reader
+	n  int64
+}
+
+func newCountReader(r io.Reader) *countReader {
+	return &countReader{br: bufio.NewReader(r)}
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.br.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countReader) ReadByte() (byte, error) {
+	b, err := c.br.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+// ReaderAt reads the decompressed contents of a gzip file.
+// Reads are serialized, a read continues decompressing where
+// the previous read stopped when it's after it.
+type ReaderAt struct {
+	r    io.ReaderAt
+	size int64
+	idx  *Index
+
+	mu  sync.Mutex
+	zr  io.Reader // nil if not positioned
+	out int64     // decompressed offset of zr
+}
+
+var _ io.ReaderAt = (*ReaderAt)(nil)
+
+// NewReaderAt returns a ReaderAt of the gzip file of size
+// bytes in r with index idx.
+func NewReaderAt(r io.ReaderAt, size int64, idx *Index) *ReaderAt {
+	return &ReaderAt{r: r, size: size, idx: idx}
+}
+
+// Size returns the size of the decompressed contents.
+func (r *ReaderAt) Size() int64 {
+	return r.idx.Size
+}
+
+func (r *ReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("gzindex: negative offset")
+	}
+	if off >= r.idx.Size {
+		return 0, io.EOF
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.point(off)
+	if pt := r.idx.Points[i]; r.zr == nil || r.out > off || r.out < pt.Out {
+		// start at the closest access point
+		zr, err := r.open(i)
+		if err != nil {
+			r.zr = nil
+			return 0, err
+		}
+		r.zr, r.out = zr, pt.Out
+	}
+	if _, err := io.CopyN(io.Discard, r.zr, off-r.out); err != nil {
+		r.zr = nil
+		return 0, err
+	}
+	r.out = off
+	n, err := io.ReadFull(r.zr, p)
+	r.out += int64(n)
+	switch err {
+	case nil:
+		return n, nil
+	case io.EOF, io.ErrUnexpectedEOF:
+		r.zr = nil
+		return n, io.EOF
+	default:
+		r.zr = nil
+		return n, err
+	}
+}
+
+// point returns the index of the last access point at or
+// before off.
+func (r *ReaderAt) point(off int64) int {
+	pts := r.idx.Points
+	return sort.Search(len(pts), func(i int) bool { return pts[i].Out > off }) - 1
+}
+
+// open returns a reader of the contents from access point i.
+// At a block, it decompresses the rest of the member and the
+// members after it.
+func (r *ReaderAt) open(i int) (io.Reader, error) {
+	pt := r.idx.Points[i]
+	in := io.NewSectionReader(r.r, pt.In, r.size-pt.In)
+	if len(pt.Window) == 0 {
+		return gzip.NewReader(newCountReader(in))
+	}
+	fr, err := newBlockInflater(in, pt)
+	if err != nil {
+		return nil, err
+	}
+	for _, next := range r.idx.Points[i+1:] {
+		if len(next.Window) == 0 {
+			// skip the trailer of the member
+			zr, err := gzip.NewReader(newCountReader(io.NewSectionReader(r.r, next.In, r.size-next.In)))
+			if err != nil {
+				return nil, err
+			}
+			return io.MultiReader(fr, zr), nil
+		}
+	}
+	return fr, nil
+}
+
+// File is a gzip file opened for random access.
+type File struct {
+	*ReaderAt
+	f fs.File
+}
+
+// Open opens the gzip file name of fsys and builds its index.
+// The file must implement io.ReaderAt. The shared compressed
+// file is referenced until the File is closed.
+func Open(fsys *singleopen.FS, name string) (*File, error) {
+	return OpenIndex(fsys, name, nil)
+}
+
+// OpenIndex is like Open but uses idx, if not nil, as the
+// index of the file instead of building it.
+func OpenIndex(fsys *singleopen.FS, name string, idx *Index) (*File, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	ra, ok := f.(io.ReaderAt)
+	if !ok {
+		f.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: compat.ErrUnsupported}
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if idx == nil {
+		idx, err = BuildIndex(ra, fi.Size(), DefaultSpan)
+		if err != nil {
+			f.Close()
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+	}
+	return &File{ReaderAt: NewReaderAt(ra, fi.Size(), idx), f: f}, nil
+}
+
+// Index returns the index of the file.
+func (f *File) Index() *Index {
+	return f.idx
+}
+
+// Close releases the reference to the compressed file.
+func (f *File) Close() error {
+	return f.f.Close()
+}
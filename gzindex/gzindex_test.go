@@ -0,0 +1,175 @@
+package gzindex
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dwlnetnl/singleopen"
+)
+
+// compress compresses data in members of at most n bytes.
+func compress(t *testing.T, data []byte, n int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	for len(data) > 0 {
+		m := n
+		if m > len(data) {
+			m = len(data)
+		}
+		w := gzip.NewWriter(&buf)
+		w.Write(data[:m])
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		data = data[m:]
+	}
+	return buf.Bytes()
+}
+
+func TestReaderAt(t *testing.T) {
+	var data []byte
+	for i := 0; i < 10000; i++ {
+		data = fmt.Appendf(data, "line %d\n", i)
+	}
+	fsys, err := singleopen.New(fstest.MapFS{
+		"single.gz":    &fstest.MapFile{Data: compress(t, data, len(data))},
+		"members.gz":   &fstest.MapFile{Data: compress(t, data, 4096)},
+		"truncated.gz": &fstest.MapFile{Data: compress(t, data, len(data))[:100]},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"single.gz", "members.gz"} {
+		f, err := Open(fsys, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Size() != int64(len(data)) {
+			t.Errorf("%s: got size %d, want %d", name, f.Size(), len(data))
+		}
+		for i := 0; i < 100; i++ {
+			off := rand.Intn(len(data))
+			n := rand.Intn(10000)
+			want := data[off:]
+			if len(want) > n {
+				want = want[:n]
+			}
+			got := make([]byte, n)
+			m, err := f.ReadAt(got, int64(off))
+			if !bytes.Equal(got[:m], want) {
+				t.Fatalf("%s: read of %d bytes at %d differs", name, n, off)
+			}
+			if m < n && err != io.EOF {
+				t.Fatalf("%s: got %v on short read, want EOF", name, err)
+			}
+		}
+		idx := f.Index()
+		f.Close()
+
+		f, err = OpenIndex(fsys, name, idx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(io.NewSectionReader(f, 0, f.Size()))
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("%s: read with stored index differs: %v", name, err)
+		}
+		f.Close()
+	}
+	if _, err := Open(fsys, "truncated.gz"); err == nil {
+		t.Error("expected error for truncated file")
+	}
+	if s := fsys.Stats(); s.Shared != 0 {
+		t.Errorf("got %d shared files, want 0", s.Shared)
+	}
+}
+
+func TestBuildIndex(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 10000)
+	gz := compress(t, data, 3000)
+	idx, err := BuildIndex(bytes.NewReader(gz), int64(len(gz)), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Points) != 4 || idx.Size != 10000 {
+		t.Fatalf("got %d points and size %d, want 4 and 10000", len(idx.Points), idx.Size)
+	}
+	for i, pt := range idx.Points {
+		if pt.Out != int64(i*3000) {
+			t.Errorf("point %d: got out %d, want %d", i, pt.Out, i*3000)
+		}
+		if _, err := gzip.NewReader(bytes.NewReader(gz[pt.In:])); err != nil {
+			t.Errorf("point %d: no member at %d: %v", i, pt.In, err)
+		}
+	}
+}
+
+func TestAccessPoints(t *testing.T) {
+	var data []byte
+	for i := 0; i < 50000; i++ {
+		data = fmt.Appendf(data, "line %d %x\n", i, i*i)
+	}
+	// incompressible runs are stored in blocks that start at a
+	// byte after blocks that don't end at one
+	mixed := append([]byte(nil), data...)
+	for i := 0; i+20000 < len(mixed); i += 50000 {
+		rand.Read(mixed[i : i+20000])
+	}
+	for _, tc := range []struct {
+		name string
+		data []byte
+		gz   []byte
+	}{
+		{"single", data, compress(t, data, len(data))},
+		{"members", data, compress(t, data, 100000)},
+		{"stored", mixed, compress(t, mixed, len(mixed))},
+	} {
+		data := tc.data
+		idx, err := BuildIndex(bytes.NewReader(tc.gz), int64(len(tc.gz)), 16<<10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocks := 0
+		for _, pt := range idx.Points {
+			if len(pt.Window) > 0 {
+				blocks++
+				if !bytes.Equal(pt.Window, window(data[:pt.Out])) {
+					t.Fatalf("%s: window of point at %d differs", tc.name, pt.Out)
+				}
+			}
+		}
+		if blocks < 2 {
+			t.Fatalf("%s: got %d block access points", tc.name, blocks)
+		}
+		fsys, err := singleopen.New(fstest.MapFS{"file.gz": &fstest.MapFile{Data: tc.gz}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := OpenIndex(fsys, "file.gz", idx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 100; i++ {
+			off := rand.Intn(len(data))
+			n := rand.Intn(50000)
+			want := data[off:]
+			if len(want) > n {
+				want = want[:n]
+			}
+			got := make([]byte, n)
+			m, err := f.ReadAt(got, int64(off))
+			if !bytes.Equal(got[:m], want) {
+				t.Fatalf("%s: read of %d bytes at %d differs: %v", tc.name, n, off, err)
+			}
+			if m < n && err != io.EOF {
+				t.Fatalf("%s: got %v on short read, want EOF", tc.name, err)
+			}
+		}
+		f.Close()
+	}
+}
@@ -0,0 +1,535 @@
+package gzindex
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+)
+
+// windowSize is the size of the deflate window.
+const windowSize = 32 << 10
+
+// fastBits is the number of bits of the lookup table of codes.
+const fastBits = 9
+
+// huffman is a canonical Huffman code.
+type huffman struct {
+	count  [16]uint16 // number of codes of each length
+	symbol []uint16   // symbols ordered by code
+	fast   [1 << fastBits]uint16
+}
+
+// init builds the code of the code lengths of the symbols. It
+// reports false if the lengths are over-subscribed.
+func (h *huffman) init(lengths []uint8) bool {
+	*h = huffman{symbol: make([]uint16, len(lengths))}
+	for _, l := range lengths {
+		h.count[l]++
+	}
+	left := 1
+	for l := 1; l < 16; l++ {
+		left = left<<1 - int(h.count[l])
+		if left < 0 {
+			return false
+		}
+	}
+	var offs, next [16]int // of the first symbol and code of a length
+	for l := 1; l < 15; l++ {
+		offs[l+1] = offs[l] + int(h.count[l])
+		next[l+1] = (next[l] + int(h.count[l])) << 1
+	}
+	for s, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		h.symbol[offs[l]] = uint16(s)
+		offs[l]++
+		c := next[l]
+		next[l]++
+		if l > fastBits {
+			continue
+		}
+		// codes are read from the least significant bit
+		r := 0
+		for i := 0; i < int(l); i++ {
+			r |= (c >> i & 1) << (int(l) - 1 - i)
+		}
+		for ; r < 1<<fastBits; r += 1 << l {
+			h.fast[r] = uint16(s)<<4 | uint16(l)
+		}
+	}
+	return true
+}
+
+var (
+	lengthBase  = [29]uint16{3, 4, 5, 6, 7, 8, 9, 10, 11, 13, 15, 17, 19, 23, 27, 31, 35, 43, 51, 59, 67, 83, 99, 115, 131, 163, 195, 227, 258}
+	lengthExtra = [29]uint8{0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2, 3, 3, 3, 3, 4, 4, 4, 4, 5, 5, 5, 5, 0}
+	distBase    = [30]uint16{1, 2, 3, 4, 5, 7, 9, 13, 17, 25, 33, 49, 65, 97, 129, 193, 257, 385, 513, 769, 1025, 1537, 2049, 3073, 4097, 6145, 8193, 12289, 16385, 24577}
+	distExtra   = [30]uint8{0, 0, 0, 0, 1, 1, 2, 2, 3, 3, 4, 4, 5, 5, 6, 6, 7, 7, 8, 8, 9, 9, 10, 10, 11, 11, 12, 12, 13, 13}
+
+	// order of the code length code lengths
+	codeOrder = [19]uint8{16, 17, 18, 0, 8, 7, 9, 6, 10, 5, 11, 4, 12, 3, 13, 2, 14, 1, 15}
+)
+
+// fixedLit and fixedDist are the codes of fixed blocks.
+var fixedLit, fixedDist = func() (*huffman, *huffman) {
+	var lengths [288]uint8
+	for i := range lengths {
+		switch {
+		case i < 144:
+			lengths[i] = 8
+		case i < 256:
+			lengths[i] = 9
+		case i < 280:
+			lengths[i] = 7
+		default:
+			lengths[i] = 8
+		}
+	}
+	lit, dist := new(huffman), new(huffman)
+	lit.init(lengths[:])
+	dist.init(bytes.Repeat([]byte{5}, 30))
+	return lit, dist
+}()
+
+// inflater decompresses the members of a gzip file like
+// compress/gzip to build its index. Unlike compress/flate, it
+// knows where the deflate blocks start, decompression can
+// start over there from the window of contents before it.
+type inflater struct {
+	cr   *countReader
+	bits uint32 // bit buffer
+	nb   uint   // bits in the buffer
+	span int64
+
+	buf     []byte // window of the member and contents not read
+	rpos    int    // of the contents in buf not read
+	crc     uint32 // of buf[:crcDone] and what slid out
+	crcDone int
+	out     int64
+	idx     Index
+
+	lit, dist *huffman // codes of the block, nil at its start
+	dyn       [2]huffman
+	final     bool
+}
+
+func newInflater(r io.Reader, span int64) *inflater {
+	return &inflater{
+		cr:   newCountReader(r),
+		span: span,
+		buf:  make([]byte, 0, 2*windowSize+258),
+	}
+}
+
+// newBlockInflater returns an inflater that reads the contents
+// of a member from the block at access point pt, r reads the
+// compressed file from pt.In.
+func newBlockInflater(r io.Reader, pt Point) (*inflater, error) {
+	d := newInflater(r, 0)
+	// the block may start in the byte, alignment of stored
+	// blocks is kept by taking the bits before it
+	if _, err := d.take(uint(pt.Bit)); err != nil {
+		return nil, noEOF(err)
+	}
+	d.buf = append(d.buf, pt.Window...)
+	d.rpos, d.crcDone = len(d.buf), len(d.buf)
+	return d, nil
+}
+
+// Read reads the contents up to the end of the member.
+func (d *inflater) Read(p []byte) (int, error) {
+	for d.rpos == len(d.buf) {
+		if err := d.step(); err == io.EOF {
+			return 0, io.EOF
+		} else if err != nil {
+			return 0, noEOF(err)
+		}
+	}
+	n := copy(p, d.buf[d.rpos:])
+	d.rpos += n
+	return n, nil
+}
+
+// corrupt returns the error of corrupt deflate data.
+func (d *inflater) corrupt() error {
+	return flate.CorruptInputError(d.cr.n)
+}
+
+// fill fills the bit buffer with at least n bits, or as many
+// as are left.
+func (d *inflater) fill(n uint) error {
+	for d.nb < n {
+		b, err := d.cr.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		d.bits |= uint32(b) << d.nb
+		d.nb += 8
+	}
+	return nil
+}
+
+// take returns the next n bits, n <= 16.
+func (d *inflater) take(n uint) (uint32, error) {
+	if err := d.fill(n); err != nil {
+		return 0, err
+	}
+	if d.nb < n {
+		return 0, io.ErrUnexpectedEOF
+	}
+	v := d.bits & (1<<n - 1)
+	d.bits >>= n
+	d.nb -= n
+	return v, nil
+}
+
+// decode returns the next symbol of h.
+func (d *inflater) decode(h *huffman) (int, error) {
+	if err := d.fill(fastBits); err != nil {
+		return 0, err
+	}
+	if e := h.fast[d.bits&(1<<fastBits-1)]; e != 0 && uint(e&15) <= d.nb {
+		d.bits >>= e & 15
+		d.nb -= uint(e & 15)
+		return int(e >> 4), nil
+	}
+	code, first, index := 0, 0, 0
+	for l := 1; l < 16; l++ {
+		b, err := d.take(1)
+		if err != nil {
+			return 0, err
+		}
+		code |= int(b)
+		count := int(h.count[l])
+		if code-first < count {
+			return int(h.symbol[index+code-first]), nil
+		}
+		index += count
+		first = (first + count) << 1
+		code <<= 1
+	}
+	return 0, d.corrupt()
+}
+
+// emit appends b to the contents.
+func (d *inflater) emit(b byte) {
+	d.buf = append(d.buf, b)
+	d.out++
+	d.slide()
+}
+
+// slide drops the contents before the window that are read
+// when the buffer is full.
+func (d *inflater) slide() {
+	if len(d.buf) < 2*windowSize {
+		return
+	}
+	keep := len(d.buf) - windowSize
+	if d.rpos < keep {
+		keep = d.rpos
+	}
+	d.crc = crc32.Update(d.crc, crc32.IEEETable, d.buf[d.crcDone:])
+	d.crcDone = copy(d.buf, d.buf[keep:])
+	d.buf = d.buf[:d.crcDone]
+	d.rpos -= keep
+}
+
+// index decompresses all members and returns their index.
+func (d *inflater) index() (*Index, error) {
+	for {
+		if err := d.member(); err == io.EOF && len(d.idx.Points) > 0 {
+			break
+		} else if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	d.idx.Size = d.out
+	return &d.idx, nil
+}
+
+// member decompresses a member, it returns io.EOF if there is
+// none.
+func (d *inflater) member() error {
+	in := d.cr.n
+	if err := d.header(); err != nil {
+		return err
+	}
+	d.idx.Points = append(d.idx.Points, Point{In: in, Out: d.out})
+	start := d.out
+	d.buf, d.rpos, d.crc, d.crcDone = d.buf[:0], 0, 0, 0
+	d.lit, d.final = nil, false
+	for {
+		last := d.idx.Points[len(d.idx.Points)-1]
+		if d.lit == nil && !d.final && d.out > start && d.out-last.Out >= d.span {
+			pos := d.cr.n*8 - int64(d.nb)
+			d.idx.Points = append(d.idx.Points, Point{
+				In:     pos / 8,
+				Bit:    uint8(pos % 8),
+				Out:    d.out,
+				Window: window(d.buf),
+			})
+		}
+		err := d.step()
+		d.rpos = len(d.buf)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return noEOF(err)
+		}
+	}
+	d.crc = crc32.Update(d.crc, crc32.IEEETable, d.buf[d.crcDone:])
+
+	// the trailer starts at a byte
+	d.bits >>= d.nb % 8
+	d.nb -= d.nb % 8
+	var trailer [8]byte
+	for i := range trailer {
+		b, err := d.take(8)
+		if err != nil {
+			return noEOF(err)
+		}
+		trailer[i] = byte(b)
+	}
+	if binary.LittleEndian.Uint32(trailer[:4]) != d.crc ||
+		binary.LittleEndian.Uint32(trailer[4:]) != uint32(d.out-start) {
+		return gzip.ErrChecksum
+	}
+	return nil
+}
+
+// header reads the header of a member, it returns io.EOF if
+// there is none.
+func (d *inflater) header() error {
+	var h [10]byte
+	if _, err := io.ReadFull(d.cr, h[:]); err != nil {
+		return err
+	}
+	if h[0] != 0x1f || h[1] != 0x8b || h[2] != 8 {
+		return gzip.ErrHeader
+	}
+	flg := h[3]
+	if flg&0x04 != 0 { // FEXTRA
+		var n [2]byte
+		if _, err := io.ReadFull(d.cr, n[:]); err != nil {
+			return noEOF(err)
+		}
+		if _, err := io.CopyN(io.Discard, d.cr, int64(binary.LittleEndian.Uint16(n[:]))); err != nil {
+			return noEOF(err)
+		}
+	}
+	for _, f := range []byte{0x08, 0x10} { // FNAME, FCOMMENT
+		if flg&f == 0 {
+			continue
+		}
+		for {
+			b, err := d.cr.ReadByte()
+			if err != nil {
+				return noEOF(err)
+			}
+			if b == 0 {
+				break
+			}
+		}
+	}
+	if flg&0x02 != 0 { // FHCRC
+		if _, err := io.CopyN(io.Discard, d.cr, 2); err != nil {
+			return noEOF(err)
+		}
+	}
+	return nil
+}
+
+// step decompresses up to the end of a deflate block or until
+// a window of contents isn't read, it returns io.EOF after the
+// final block.
+func (d *inflater) step() error {
+	if d.lit == nil {
+		if d.final {
+			return io.EOF
+		}
+		hdr, err := d.take(3)
+		if err != nil {
+			return err
+		}
+		d.final = hdr&1 != 0
+		switch hdr >> 1 {
+		case 0:
+			return d.stored()
+		case 1:
+			d.lit, d.dist = fixedLit, fixedDist
+		case 2:
+			if err := d.dynamic(&d.dyn[0], &d.dyn[1]); err != nil {
+				return err
+			}
+			d.lit, d.dist = &d.dyn[0], &d.dyn[1]
+		default:
+			return d.corrupt()
+		}
+	}
+	return d.codes()
+}
+
+// stored copies a stored block.
+func (d *inflater) stored() error {
+	d.bits >>= d.nb % 8
+	d.nb -= d.nb % 8
+	n, err := d.take(16)
+	if err != nil {
+		return err
+	}
+	nn, err := d.take(16)
+	if err != nil {
+		return err
+	}
+	if n != ^nn&0xffff {
+		return d.corrupt()
+	}
+	for ; n > 0; n-- {
+		b, err := d.take(8)
+		if err != nil {
+			return err
+		}
+		d.emit(byte(b))
+	}
+	return nil
+}
+
+// dynamic reads the codes of a dynamic block.
+func (d *inflater) dynamic(lit, dist *huffman) error {
+	v, err := d.take(14)
+	if err != nil {
+		return err
+	}
+	nlen, ndist, ncode := int(v&31)+257, int(v>>5&31)+1, int(v>>10)+4
+	if nlen > 286 || ndist > 30 {
+		return d.corrupt()
+	}
+	var lengths [286 + 30]uint8
+	for i := 0; i < ncode; i++ {
+		l, err := d.take(3)
+		if err != nil {
+			return err
+		}
+		lengths[codeOrder[i]] = uint8(l)
+	}
+	var codes huffman
+	if !codes.init(lengths[:19]) {
+		return d.corrupt()
+	}
+	lengths = [len(lengths)]uint8{}
+	for i := 0; i < nlen+ndist; {
+		sym, err := d.decode(&codes)
+		if err != nil {
+			return err
+		}
+		if sym < 16 {
+			lengths[i] = uint8(sym)
+			i++
+			continue
+		}
+		var l uint8
+		var rep uint32
+		switch sym {
+		case 16:
+			if i == 0 {
+				return d.corrupt()
+			}
+			l = lengths[i-1]
+			rep, err = d.take(2)
+			rep += 3
+		case 17:
+			rep, err = d.take(3)
+			rep += 3
+		default:
+			rep, err = d.take(7)
+			rep += 11
+		}
+		if err != nil {
+			return err
+		}
+		if i+int(rep) > nlen+ndist {
+			return d.corrupt()
+		}
+		for ; rep > 0; rep-- {
+			lengths[i] = l
+			i++
+		}
+	}
+	if lengths[256] == 0 || !lit.init(lengths[:nlen]) || !dist.init(lengths[nlen:nlen+ndist]) {
+		return d.corrupt()
+	}
+	return nil
+}
+
+// codes decompresses the codes of the block until its end or
+// until a window of contents isn't read.
+func (d *inflater) codes() error {
+	for len(d.buf)-d.rpos < windowSize {
+		sym, err := d.decode(d.lit)
+		if err != nil {
+			return err
+		}
+		switch {
+		case sym < 256:
+			d.emit(byte(sym))
+			continue
+		case sym == 256:
+			d.lit, d.dist = nil, nil
+			return nil
+		case sym > 285:
+			return d.corrupt()
+		}
+		sym -= 257
+		extra, err := d.take(uint(lengthExtra[sym]))
+		if err != nil {
+			return err
+		}
+		n := int(lengthBase[sym]) + int(extra)
+		sym, err = d.decode(d.dist)
+		if err != nil {
+			return err
+		}
+		if sym > 29 {
+			return d.corrupt()
+		}
+		extra, err = d.take(uint(distExtra[sym]))
+		if err != nil {
+			return err
+		}
+		back := int(distBase[sym]) + int(extra)
+		if back > len(d.buf) {
+			return d.corrupt()
+		}
+		for j := 0; j < n; j++ {
+			d.buf = append(d.buf, d.buf[len(d.buf)-back])
+		}
+		d.out += int64(n)
+		d.slide()
+	}
+	return nil
+}
+
+// window returns a copy of the window at the end of buf.
+func window(buf []byte) []byte {
+	if len(buf) > windowSize {
+		buf = buf[len(buf)-windowSize:]
+	}
+	return append([]byte(nil), buf...)
+}
+
+// noEOF returns io.ErrUnexpectedEOF for io.EOF.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,101 @@
+package singleopen
+
+import "io/fs"
+
+// Hooks are callbacks for lifecycle events of files opened
+// by a FS. Every hook is optional. Hooks are called without
+// holding internal locks, possibly concurrently, and may call
+// methods of the FS.
+type Hooks struct {
+	// OnOpen is called after a file is opened on the
+	// underlying file system. The file info is nil when
+	// it's not available.
+	OnOpen func(name string, fi fs.FileInfo, err error)
+
+	// OnHit is called when an open reuses a shared file or,
+	// if cached is true, a file from the close cache.
+	OnHit func(name string, cached bool)
+
+	// OnEvict is called when a file is evicted from the
+	// close cache, before it is closed.
+	OnEvict func(name string)
+
+	// OnClose is called after a file is closed on the
+	// underlying file system.
+	OnClose func(name string, err error)
+
+	// OnCloseError is called when closing a file fails in
+	// the background, like a file evicted from the close
+	// cache, where the error can't be returned to a caller.
+	OnCloseError func(name string, err error)
+
+	// OnLeak is called when a file returned by Open is
+	// garbage collected without being closed, see
+	// WithLeakDetection.
+	OnLeak func(name string)
+
+	// OnDoubleClose is called when a file returned by Open is
+	// closed again, with the stacks of the first and second
+	// Close, see WithStrictClose.
+	OnDoubleClose func(name, first, second string)
+
+	// OnRefCountError is called when the reference count of
+	// a file drops below zero, see WithRefCountPolicy.
+	OnRefCountError func(name string, err error)
+}
+
+// WithHooks returns an Option that sets the lifecycle hooks.
+func WithHooks(h Hooks) Option {
+	return func(f *FS) error {
+		f.hooks = h
+		return nil
+	}
+}
+
+func (h *Hooks) open(name string, fi fs.FileInfo, err error) {
+	if h.OnOpen != nil {
+		h.OnOpen(name, fi, err)
+	}
+}
+
+func (h *Hooks) hit(name string, cached bool) {
+	if h.OnHit != nil {
+		h.OnHit(name, cached)
+	}
+}
+
+func (h *Hooks) evict(name string) {
+	if h.OnEvict != nil {
+		h.OnEvict(name)
+	}
+}
+
+func (h *Hooks) close(name string, err error) {
+	if h.OnClose != nil {
+		h.OnClose(name, err)
+	}
+}
+
+func (h *Hooks) closeError(name string, err error) {
+	if h.OnCloseError != nil {
+		h.OnCloseError(name, err)
+	}
+}
+
+func (h *Hooks) leak(name string) {
+	if h.OnLeak != nil {
+		h.OnLeak(name)
+	}
+}
+
+func (h *Hooks) doubleClose(name, first, second string) {
+	if h.OnDoubleClose != nil {
+		h.OnDoubleClose(name, first, second)
+	}
+}
+
+func (h *Hooks) refCountError(name string, err error) {
+	if h.OnRefCountError != nil {
+		h.OnRefCountError(name, err)
+	}
+}
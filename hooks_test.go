@@ -0,0 +1,150 @@
+package singleopen
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+)
+
+func TestHooks(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+	record := func(event string) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}
+	fsys, err := New(fstest.MapFS{
+		"file1": &fstest.MapFile{},
+		"file2": &fstest.MapFile{},
+	}, WithKeepLast(1), WithHooks(Hooks{
+		OnOpen:  func(name string, fi fs.FileInfo, err error) { record("open " + name) },
+		OnHit:   func(name string, cached bool) { record(fmt.Sprint("hit ", name, " ", cached)) },
+		OnEvict: func(name string) { record("evict " + name) },
+		OnClose: func(name string, err error) { record("close " + name) },
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f1, _ := fsys.Open("file1")
+	f2, _ := fsys.Open("file1")
+	f1.Close()
+	f2.Close()
+	f1, _ = fsys.Open("file1")
+	f1.Close()
+	f2, _ = fsys.Open("file2")
+	f2.Close()
+	fsys.Close()
+
+	want := []string{
+		"open file1",
+		"hit file1 false",
+		"hit file1 true",
+		"open file2",
+		"evict file1",
+		"close file1",
+		"close file2",
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("got events %q, want %q", events, want)
+	}
+}
+
+func TestHooksReentrant(t *testing.T) {
+	mfs := fstest.MapFS{}
+	for i := 0; i < 20; i++ {
+		mfs[fmt.Sprint("file", i)] = &fstest.MapFile{}
+	}
+	var fsys *FS
+	var evictions atomic.Int32
+	fsys, err := New(mfs, WithKeepLast(1), WithHooks(Hooks{
+		OnEvict: func(name string) {
+			fsys.Stats()
+			evictions.Add(1)
+		},
+		OnClose: func(name string, err error) { fsys.RefCount(name) },
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name := range mfs {
+		f, err := fsys.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+	if err := fsys.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := evictions.Load(); n != 19 {
+		t.Errorf("got %d evictions, want 19", n)
+	}
+}
+
+// closeErrFS is a file system of which files fail to close.
+type closeErrFS struct {
+	fs.FS
+}
+
+func (fsys closeErrFS) Open(name string) (fs.File, error) {
+	f, err := fsys.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return closeErrFile{f}, nil
+}
+
+type closeErrFile struct {
+	fs.File
+}
+
+var errClose = errors.New("close failed")
+
+func (f closeErrFile) Close() error {
+	f.File.Close()
+	return errClose
+}
+
+func TestCloseError(t *testing.T) {
+	names := make(chan string, 2)
+	fsys, err := New(closeErrFS{fstest.MapFS{
+		"a": &fstest.MapFile{},
+		"b": &fstest.MapFile{},
+	}}, WithKeepLast(1), WithHooks(Hooks{
+		OnCloseError: func(name string, err error) {
+			if !errors.Is(err, errClose) {
+				t.Errorf("got %v, want close error", err)
+			}
+			names <- name
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b"} {
+		f, err := fsys.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if name := <-names; name != "a" {
+		t.Errorf("got close error of %q on eviction, want a", name)
+	}
+	fsys.KeepLast(0)
+	if name := <-names; name != "b" {
+		t.Errorf("got close error of %q on disable, want b", name)
+	}
+	if n := fsys.Stats().CloseErrs; n != 2 {
+		t.Errorf("got %d close errors, want 2", n)
+	}
+}
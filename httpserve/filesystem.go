@@ -0,0 +1,94 @@
+package httpserve
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/dwlnetnl/singleopen"
+	"github.com/dwlnetnl/singleopen/internal/compat"
+)
+
+// FileSystem returns fsys as an http.FileSystem that behaves
+// like http.Dir, so an http.FileServer of http.Dir serves the
+// same with shared files. Files are opened as shared files of
+// fsys, seeking a file moves the offset of the opened file
+// only if the file implements io.ReaderAt. Directories are
+// read in the order of fs.ReadDir.
+func FileSystem(fsys *singleopen.FS) http.FileSystem {
+	return fileSystem{fsys}
+}
+
+type fileSystem struct {
+	fsys *singleopen.FS
+}
+
+func (hfs fileSystem) Open(name string) (http.File, error) {
+	if strings.Contains(name, "\x00") {
+		return nil, errors.New("http: invalid character in file path")
+	}
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		name = "."
+	}
+	f, err := hfs.fsys.Open(name)
+	if err != nil {
+		return nil, hfs.mapOpenError(err, name)
+	}
+	return &file{File: f, name: name}, nil
+}
+
+// mapOpenError returns fs.ErrNotExist if a parent of name is
+// a file, like http.Dir does for the error "not a directory".
+func (hfs fileSystem) mapOpenError(err error, name string) error {
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+		return err
+	}
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		fi, serr := hfs.fsys.Stat(dir)
+		if serr != nil {
+			continue
+		}
+		if !fi.IsDir() {
+			return fs.ErrNotExist
+		}
+		break // closest existing parent is a directory
+	}
+	return err
+}
+
+// file is an http.File of a file of a singleopen.FS.
+type file struct {
+	fs.File
+	name string
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	s, ok := f.File.(io.Seeker)
+	if !ok {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: compat.ErrUnsupported}
+	}
+	return s.Seek(offset, whence)
+}
+
+// Readdir reads the directory like (*os.File).Readdir.
+// Entries whose file info can't be read, like files removed
+// since the directory is read, are skipped.
+func (f *file) Readdir(count int) ([]os.FileInfo, error) {
+	d, ok := f.File.(fs.ReadDirFile)
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: errors.New("not a directory")}
+	}
+	entries, err := d.ReadDir(count)
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		if fi, err := e.Info(); err == nil {
+			infos = append(infos, fi)
+		}
+	}
+	return infos, err
+}
@@ -0,0 +1,60 @@
+package httpserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dwlnetnl/singleopen"
+)
+
+func TestFileSystem(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "sub", "site"), 0o755)
+	os.WriteFile(filepath.Join(dir, "file.txt"), []byte("0123456789"), 0o644)
+	os.WriteFile(filepath.Join(dir, "sub", "a.txt"), []byte("a"), 0o644)
+	os.WriteFile(filepath.Join(dir, "sub", "site", "index.html"), []byte("<p>index</p>"), 0o644)
+	fsys, err := singleopen.New(os.DirFS(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := http.FileServer(http.Dir(dir))
+	got := http.FileServer(FileSystem(fsys))
+
+	for _, tt := range []struct {
+		path, rng string
+	}{
+		{"/file.txt", ""},
+		{"/file.txt", "bytes=2-4"},
+		{"/file.txt", "bytes=-3"},
+		{"/", ""},
+		{"/sub/", ""},
+		{"/sub", ""},
+		{"/sub/site/", ""},
+		{"/sub/site/index.html", ""},
+		{"/missing", ""},
+		{"/../file.txt", ""},
+		{"/file.txt/x", ""},
+		{"/file.txt/x/y", ""},
+	} {
+		do := func(h http.Handler) *httptest.ResponseRecorder {
+			r := httptest.NewRequest("GET", "http://example.com"+tt.path, nil)
+			if tt.rng != "" {
+				r.Header.Set("Range", tt.rng)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			return w
+		}
+		w1, w2 := do(want), do(got)
+		if w1.Code != w2.Code || w1.Body.String() != w2.Body.String() ||
+			w1.Header().Get("Location") != w2.Header().Get("Location") {
+			t.Errorf("%s %s: got %d %q, want %d %q", tt.path, tt.rng, w2.Code, w2.Body, w1.Code, w1.Body)
+		}
+	}
+	if s := fsys.Stats(); s.Shared != 0 {
+		t.Errorf("got %d shared files, want 0", s.Shared)
+	}
+}
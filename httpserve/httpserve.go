@@ -0,0 +1,64 @@
+// Package httpserve serves files of a singleopen.FS over HTTP.
+package httpserve
+
+import (
+	"crypto"
+	_ "crypto/sha256" // hash of ETag
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+
+	"github.com/dwlnetnl/singleopen"
+)
+
+// ServeFile replies to the request with the contents of the
+// named file of fsys, like http.ServeContent. Range requests
+// are read from the shared file. The Last-Modified header is
+// set from the file info of fsys.Stat, which is cached if
+// enabled, and the ETag header, if not set already, from the
+// cached SHA-256 digest of the file, see (*singleopen.FS).Digest.
+// Conditional requests are handled by http.ServeContent.
+// Directories are not served.
+func ServeFile(w http.ResponseWriter, r *http.Request, fsys *singleopen.FS, name string) {
+	fi, err := fsys.Stat(name)
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+	if fi.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	f, err := fsys.Open(name)
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+	defer f.Close()
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if w.Header().Get("Etag") == "" {
+		if sum, err := fsys.Digest(name, crypto.SHA256); err == nil {
+			w.Header().Set("Etag", `"`+base64.RawURLEncoding.EncodeToString(sum)+`"`)
+		}
+	}
+	http.ServeContent(w, r, fi.Name(), fi.ModTime(), rs)
+}
+
+// serveError replies with the status code of err, without
+// revealing the error.
+func serveError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		http.Error(w, "404 page not found", http.StatusNotFound)
+	case errors.Is(err, fs.ErrPermission):
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+	default:
+		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,68 @@
+package httpserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/dwlnetnl/singleopen"
+)
+
+func TestServeFile(t *testing.T) {
+	mod := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	fsys, err := singleopen.New(fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte("<p>hello</p>"), ModTime: mod},
+		"dir/file":   &fstest.MapFile{},
+	}, singleopen.WithStatCache(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve := func(name string, header http.Header) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest("GET", "/"+name, nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		ServeFile(w, r, fsys, name)
+		return w
+	}
+
+	w := serve("index.html", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "<p>hello</p>" {
+		t.Fatalf("got %d %q", w.Code, w.Body)
+	}
+	etag := w.Header().Get("Etag")
+	if etag == "" {
+		t.Error("no ETag")
+	}
+	if lm := w.Header().Get("Last-Modified"); lm != mod.Format(http.TimeFormat) {
+		t.Errorf("got Last-Modified %q", lm)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("got Content-Type %q", ct)
+	}
+
+	w = serve("index.html", http.Header{"Range": {"bytes=3-7"}})
+	if w.Code != http.StatusPartialContent || w.Body.String() != "hello" {
+		t.Errorf("got %d %q, want partial hello", w.Code, w.Body)
+	}
+	w = serve("index.html", http.Header{"If-None-Match": {etag}})
+	if w.Code != http.StatusNotModified {
+		t.Errorf("got %d, want not modified by ETag", w.Code)
+	}
+	w = serve("index.html", http.Header{"If-Modified-Since": {mod.Format(http.TimeFormat)}})
+	if w.Code != http.StatusNotModified {
+		t.Errorf("got %d, want not modified by date", w.Code)
+	}
+	for name, code := range map[string]int{"missing": http.StatusNotFound, "dir": http.StatusNotFound} {
+		if w := serve(name, nil); w.Code != code {
+			t.Errorf("%s: got %d, want %d", name, w.Code, code)
+		}
+	}
+	if s := fsys.Stats(); s.Shared != 0 {
+		t.Errorf("got %d shared files, want 0", s.Shared)
+	}
+}
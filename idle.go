@@ -0,0 +1,104 @@
+package singleopen
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MaxIdle closes files that are in the close cache for
+// longer than d, even if the cache is not full. If d <= 0,
+// cached files are kept until evicted. MaxIdle has no
+// effect after Close.
+func (fsys *FS) MaxIdle(d time.Duration) {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if fsys.closed.Load() {
+		return
+	}
+	fsys.stopIdleCloser()
+	if d > 0 {
+		stop := make(chan struct{})
+		fsys.idleStop = stop
+		go fsys.idleCloser(d, stop)
+	}
+}
+
+// WithMaxIdle returns an Option that closes files that are
+// idle in the close cache for longer than d, see (*FS).MaxIdle.
+func WithMaxIdle(d time.Duration) Option {
+	return func(f *FS) error {
+		if d < 0 {
+			return fmt.Errorf("singleopen: invalid max idle duration %v", d)
+		}
+		f.maxIdle = d
+		return nil
+	}
+}
+
+// stopIdleCloser stops the goroutine started by MaxIdle.
+// fsys.mu must be held.
+func (fsys *FS) stopIdleCloser() {
+	if fsys.idleStop != nil {
+		close(fsys.idleStop)
+		fsys.idleStop = nil
+	}
+}
+
+func (fsys *FS) idleCloser(d time.Duration, stop <-chan struct{}) {
+	if d < 2*time.Millisecond {
+		d = 2 * time.Millisecond
+	}
+	t := time.NewTicker(d / 2)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-t.C:
+			fsys.evictIdle(now.Add(-d))
+		}
+	}
+}
+
+// evictIdle evicts the files that are added to the close
+// cache before t and returns the number of evicted files.
+func (fsys *FS) evictIdle(t time.Time) int {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if fsys.cache == nil {
+		return 0
+	}
+	return fsys.cache.evictIdle(t)
+}
+
+// Prune closes all files in the close cache. Unlike evicted
+// files, the files are closed before Prune returns. Errors
+// from closing files are joined.
+func (fsys *FS) Prune() error {
+	return fsys.CloseIdle(0)
+}
+
+// CloseIdle closes the files in the close cache that have not
+// been used within d. The files are closed before CloseIdle
+// returns. Errors from closing files are joined.
+func (fsys *FS) CloseIdle(d time.Duration) error {
+	fsys.mu.Lock()
+	if fsys.cache == nil {
+		fsys.mu.Unlock()
+		return nil
+	}
+	files := fsys.cache.removeIdle(time.Now().Add(-d), true)
+	fsys.stats.evictions.Add(int64(len(files)))
+	fsys.mu.Unlock()
+
+	var errs []error
+	for _, f := range files {
+		fsys.debug("close idle file", f.name)
+		fsys.hooks.evict(f.name)
+		if err := f.close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,64 @@
+package singleopen
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestMaxIdle(t *testing.T) {
+	evicted := make(chan string, 1)
+	fsys, err := New(fstest.MapFS{"file": &fstest.MapFile{}},
+		WithKeepLast(4), WithMaxIdle(time.Hour),
+		WithHooks(Hooks{OnEvict: func(name string) { evicted <- name }}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	f, _ := fsys.Open("file")
+	f.Close()
+	if n := fsys.evictIdle(time.Now().Add(-time.Minute)); n != 0 {
+		t.Errorf("evicted %d recently closed files", n)
+	}
+	if n := fsys.evictIdle(time.Now().Add(time.Minute)); n != 1 {
+		t.Errorf("evicted %d idle files, want 1", n)
+	}
+	<-evicted
+
+	fsys.MaxIdle(time.Millisecond)
+	f, _ = fsys.Open("file")
+	f.Close()
+	select {
+	case <-evicted:
+	case <-time.After(5 * time.Second):
+		t.Error("idle file is not closed")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	fsys, err := New(fstest.MapFS{
+		"file1": &fstest.MapFile{},
+		"file2": &fstest.MapFile{},
+	}, WithKeepLast(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	fsys.Preopen("file1")
+	if err := fsys.CloseIdle(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if s := fsys.Stats(); s.Cached != 1 {
+		t.Errorf("got %d cached files, want recently used file to stay", s.Cached)
+	}
+
+	fsys.Preopen("file2")
+	if err := fsys.Prune(); err != nil {
+		t.Fatal(err)
+	}
+	if s := fsys.Stats(); s.OpenFiles != 0 || s.Evictions != 2 {
+		t.Errorf("got %+v, want all files closed", s)
+	}
+}
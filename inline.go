@@ -0,0 +1,139 @@
+package singleopen
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// WithInline returns an Option that reads regular files of at
+// most threshold bytes into memory when they are opened. The
+// underlying file is closed right away, the shared file is
+// served from memory and doesn't count as an open file for
+// MaxOpen. Only files that implement io.ReaderAt are inlined.
+// Inlined files use at most DefaultInlineBudget bytes of
+// memory unless set by WithInlineBudget.
+func WithInline(threshold int64) Option {
+	return func(f *FS) error {
+		if threshold <= 0 {
+			return fmt.Errorf("singleopen: invalid inline threshold %d", threshold)
+		}
+		f.inline = threshold
+		return nil
+	}
+}
+
+// DefaultInlineBudget is the default memory budget of
+// inlined files.
+const DefaultInlineBudget = 64 << 20
+
+// WithInlineBudget returns an Option that limits the memory
+// used by inlined files to n bytes. When the budget is
+// exhausted the least recently closed inlined files in the
+// close cache are evicted. If that isn't enough, because the
+// inlined files are referenced, a file isn't inlined.
+func WithInlineBudget(n int64) Option {
+	return func(f *FS) error {
+		if n <= 0 {
+			return fmt.Errorf("singleopen: invalid inline budget %d", n)
+		}
+		f.inlineBudget = n
+		return nil
+	}
+}
+
+// canInline reports whether a file with info fi is inlined.
+func (fsys *FS) canInline(fi fs.FileInfo) bool {
+	return fsys.inline > 0 && fi != nil &&
+		fi.Mode().IsRegular() && fi.Size() <= fsys.inline
+}
+
+// inlineFile reads f into memory. It reports false if f can't
+// be inlined, f is left open and unchanged.
+func (fsys *FS) inlineFile(f fs.File, fi fs.FileInfo) (*memFile, bool) {
+	ra, ok := f.(io.ReaderAt)
+	if !ok {
+		return nil, false
+	}
+	data, err := io.ReadAll(io.NewSectionReader(ra, 0, fsys.inline+1))
+	if err != nil || int64(len(data)) != fi.Size() {
+		return nil, false // failed or changed
+	}
+	return &memFile{Reader: bytes.NewReader(data), fi: fi}, true
+}
+
+// reserveInline reserves n bytes of the inline budget, it
+// evicts inlined files from the close cache to make room.
+// It reports false if the budget is exhausted.
+func (fsys *FS) reserveInline(n int64) bool {
+	budget := fsys.inlineBudget
+	if budget == 0 {
+		budget = DefaultInlineBudget
+	}
+	for {
+		used := fsys.stats.inlineSize.Load()
+		if used+n > budget {
+			if !fsys.evictInline() {
+				return false
+			}
+			continue
+		}
+		if fsys.stats.inlineSize.CompareAndSwap(used, used+n) {
+			fsys.stats.inlined.Add(1)
+			return true
+		}
+	}
+}
+
+// releaseInline releases the budget reserved for f.
+func (fsys *FS) releaseInline(f *file) {
+	fsys.stats.inlineSize.Add(-f.size)
+	fsys.stats.inlined.Add(-1)
+}
+
+// evictInline evicts the least recently closed inlined file
+// in the close cache and waits until a file is released by
+// the file closer. It reports false if there is none.
+func (fsys *FS) evictInline() bool {
+	fsys.waiters.Add(1)
+	defer fsys.waiters.Add(-1)
+	released := fsys.releasedChan()
+	fsys.mu.Lock()
+	var oldest *file
+	if fsys.cache != nil {
+		for _, f := range fsys.cache.files {
+			if f.inline && (oldest == nil || f.idle.Before(oldest.idle)) {
+				oldest = f
+			}
+		}
+	}
+	if oldest == nil {
+		fsys.mu.Unlock()
+		return false
+	}
+	fsys.cache.evictFile(oldest)
+	fsys.mu.Unlock()
+	// wait for the file closer to release the budget
+	<-released
+	return true
+}
+
+// memFile is a file read into memory.
+type memFile struct {
+	*bytes.Reader
+	fi fs.FileInfo
+}
+
+var (
+	_ fs.File     = (*memFile)(nil)
+	_ io.ReaderAt = (*memFile)(nil)
+)
+
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	return f.fi, nil
+}
+
+func (f *memFile) Close() error {
+	return nil
+}
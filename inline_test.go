@@ -0,0 +1,123 @@
+package singleopen
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/fstest"
+)
+
+func TestInline(t *testing.T) {
+	cfs := &countFS{FS: fstest.MapFS{
+		"small": &fstest.MapFile{Data: []byte("small")},
+		"large": &fstest.MapFile{Data: bytes.Repeat([]byte("large"), 100)},
+	}, max: 10}
+	fsys, err := New(cfs, WithInline(64), WithMaxOpen(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	small, err := fsys.Open("small")
+	if err != nil {
+		t.Fatal(err)
+	}
+	large, err := fsys.Open("large")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfs.n != 1 {
+		t.Errorf("got %d open files, want only large file to be open", cfs.n)
+	}
+	data, err := io.ReadAll(small)
+	if err != nil || string(data) != "small" {
+		t.Errorf("got %q, %v, want small", data, err)
+	}
+	small.Close()
+	large.Close()
+	if cfs.n != 0 {
+		t.Errorf("got %d open files, want 0", cfs.n)
+	}
+	if _, err := New(cfs, WithInline(0)); err == nil {
+		t.Error("expected error for invalid threshold")
+	}
+}
+
+func TestInlineBudget(t *testing.T) {
+	cfs := &countFS{FS: fstest.MapFS{
+		"a": &fstest.MapFile{Data: []byte("0123456789")},
+		"b": &fstest.MapFile{Data: []byte("0123456789")},
+		"c": &fstest.MapFile{Data: []byte("0123456789")},
+	}, max: 10}
+	fsys, err := New(cfs, WithInline(64), WithInlineBudget(20), WithKeepLast(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := fsys.Open("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := fsys.Open("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	a.Close()
+	if s := fsys.Stats(); s.Inlined != 2 || s.InlineSize != 20 {
+		t.Errorf("got %d inlined files of %d bytes, want 2 of 20", s.Inlined, s.InlineSize)
+	}
+
+	// evicts a from the close cache
+	c, err := fsys.Open("c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	s := fsys.Stats()
+	if s.Inlined != 2 || s.InlineSize != 20 || s.Cached != 0 || s.Evictions != 1 {
+		t.Errorf("got %+v, want a evicted", s)
+	}
+
+	// budget is used by referenced files
+	a, err = fsys.Open("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfs.n != 1 {
+		t.Errorf("got %d open files, want a to be open", cfs.n)
+	}
+	a.Close()
+	if s := fsys.Stats(); s.Inlined != 2 || s.InlineSize != 20 {
+		t.Errorf("got %d inlined files of %d bytes, want 2 of 20", s.Inlined, s.InlineSize)
+	}
+	if _, err := New(cfs, WithInlineBudget(0)); err == nil {
+		t.Error("expected error for invalid budget")
+	}
+}
+
+func TestInlineEvictHooks(t *testing.T) {
+	var evicted []string
+	fsys, err := New(fstest.MapFS{
+		"a": &fstest.MapFile{Data: []byte("0123456789")},
+		"b": &fstest.MapFile{Data: []byte("0123456789")},
+	}, WithInline(64), WithInlineBudget(10), WithKeepLast(10), WithHooks(Hooks{
+		OnEvict: func(name string) { evicted = append(evicted, name) },
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := fsys.Open("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Close()
+	b, err := fsys.Open("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if len(evicted) != 1 || evicted[0] != "a" {
+		t.Errorf("got evicted files %v, want a", evicted)
+	}
+	if s := fsys.Stats(); s.Inlined != 1 || s.Evictions != 1 {
+		t.Errorf("got %+v, want b inlined and a evicted", s)
+	}
+}
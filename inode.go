@@ -0,0 +1,105 @@
+package singleopen
+
+import (
+	"context"
+	"io/fs"
+	"strconv"
+)
+
+// WithInodeSharing returns an Option that shares regular
+// files by their device and inode number instead of their
+// name, so the hard links of a file share a single open file.
+// It needs the file info of the underlying file system to
+// carry the inode, like that of os.DirFS on Unix; other files
+// are shared by name. Every open stats the file, enable
+// WithStatCache to avoid that.
+func WithInodeSharing() Option {
+	return func(f *FS) error {
+		f.inodeSharing = true
+		return nil
+	}
+}
+
+// inodeKey returns the key of name by its inode, or key if
+// the inode isn't known. The file info is returned if name
+// could be stat'ed. The name is linked to the shared file of
+// the key by link once it's opened.
+func (fsys *FS) inodeKey(ctx context.Context, name, key string) (fs.FileInfo, string) {
+	fi, ok := fsys.cachedStat(name)
+	if !ok {
+		var err error
+		fi, err = fsys.statBase(ctx, name)
+		if err != nil {
+			return nil, key // reported by open
+		}
+		fsys.storeStat(name, fi)
+	}
+	if !fi.Mode().IsRegular() {
+		return fi, key
+	}
+	dev, ino, ok := fileID(fi)
+	if !ok {
+		return fi, key
+	}
+	// not a valid path, doesn't collide with names
+	return fi, "/" + strconv.FormatUint(dev, 10) + ":" + strconv.FormatUint(ino, 10)
+}
+
+// link records name as a name of the shared file f if f is
+// shared by inode, so sharedKey finds f by name.
+func (fsys *FS) link(f *file, name string) {
+	if !fsys.inodeSharing {
+		return
+	}
+	key := fsys.key(name)
+	if key == f.key {
+		return
+	}
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if fsys.inodes == nil {
+		fsys.inodes = make(map[string]string)
+	}
+	fsys.inodes[key] = f.key
+	for _, l := range f.links {
+		if l == key {
+			return
+		}
+	}
+	f.links = append(f.links, key)
+}
+
+// unlink removes the names of f recorded by link when f is
+// closed, unless they refer to another shared file of the
+// inode opened since.
+func (fsys *FS) unlink(f *file) {
+	if !fsys.inodeSharing {
+		return
+	}
+	if g, ok := fsys.shard(f.key).load(f.key); ok && g != f {
+		return
+	}
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if fsys.cache != nil {
+		if g, ok := fsys.cache.get(f.key); ok && g != f {
+			return
+		}
+	}
+	for _, key := range f.links {
+		if fsys.inodes[key] == f.key {
+			delete(fsys.inodes, key)
+		}
+	}
+	f.links = nil
+}
+
+// sharedKey returns the key the shared file of name is
+// stored by. fsys.mu must be held.
+func (fsys *FS) sharedKey(name string) string {
+	key := fsys.key(name)
+	if ikey, ok := fsys.inodes[key]; ok {
+		return ikey
+	}
+	return key
+}
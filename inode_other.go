@@ -0,0 +1,9 @@
+//go:build !unix
+
+package singleopen
+
+import "io/fs"
+
+func fileID(fi fs.FileInfo) (dev, ino uint64, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,75 @@
+//go:build unix
+
+package singleopen
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInodeSharing(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte("data"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(filepath.Join(dir, "file"), filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	fsys, err := New(os.DirFS(dir), WithInodeSharing())
+	if err != nil {
+		t.Fatal(err)
+	}
+	f1, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f1.Close()
+	f2, err := fsys.Open("link")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f2.Close()
+	if st := fsys.Stats(); st.Misses != 1 || st.Hits != 1 {
+		t.Errorf("got %+v, want one open shared by both links", st)
+	}
+	if n := fsys.RefCount("link"); n != 2 {
+		t.Errorf("got %d references, want 2", n)
+	}
+	if b, err := io.ReadAll(f2); err != nil || string(b) != "data" {
+		t.Errorf("got %q, %v", b, err)
+	}
+}
+
+func TestInodeLinks(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte("data"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(filepath.Join(dir, "file"), filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	fsys, err := New(os.DirFS(dir), WithInodeSharing(), WithKeepLast(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+	for _, name := range []string{"file", "link"} {
+		f, err := fsys.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+	if n := len(fsys.inodes); n != 2 {
+		t.Errorf("got %d names of inodes, want 2", n)
+	}
+	// the names are removed when the shared file is evicted
+	if err := fsys.Prune(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(fsys.inodes); n != 0 {
+		t.Errorf("got %d names of inodes after eviction, want 0", n)
+	}
+}
@@ -0,0 +1,3 @@
+// Package compat provides what the packages of the module use
+// of versions of Go newer than the one of go.mod.
+package compat
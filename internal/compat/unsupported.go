@@ -0,0 +1,8 @@
+//go:build go1.21
+
+package compat
+
+import "errors"
+
+// ErrUnsupported is errors.ErrUnsupported.
+var ErrUnsupported = errors.ErrUnsupported
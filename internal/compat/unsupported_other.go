@@ -0,0 +1,9 @@
+//go:build !go1.21
+
+package compat
+
+import "errors"
+
+// ErrUnsupported stands in for errors.ErrUnsupported of Go
+// 1.21 and later.
+var ErrUnsupported = errors.New("unsupported operation")
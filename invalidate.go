@@ -0,0 +1,116 @@
+package singleopen
+
+import (
+	"errors"
+	"path"
+	"strconv"
+)
+
+// Invalidate makes sure the next open of the named file opens
+// it on the underlying file system. A cached file is closed and
+// a shared file is closed when its last reference is closed
+// instead of being added to the close cache; current readers
+// keep reading the file they opened. Cached metadata of the
+// file is dropped. A pinned file stays pinned until Unpin.
+func (fsys *FS) Invalidate(name string) error {
+	fsys.mu.Lock()
+	key := fsys.sharedKey(name)
+	fsys.mu.Unlock()
+	sh := fsys.shard(key)
+	sh.mu.Lock()
+	fsys.mu.Lock()
+	fsys.invalidate(name)
+	var cached *file
+	if fsys.cache != nil {
+		if f, ok := fsys.cache.get(key); ok {
+			fsys.cache.remove(key)
+			cached = f
+		}
+	}
+	fsys.mu.Unlock()
+	sh.mu.Unlock()
+
+	fsys.debug("invalidate file", name)
+	err := fsys.invalidateDir(name)
+	if cached != nil {
+		err = errors.Join(cached.close(), err)
+	}
+	return err
+}
+
+// invalidate marks the shared file of name as stale and drops
+// cached metadata. The shard of name and fsys.mu must be held.
+func (fsys *FS) invalidate(name string) {
+	// don't join opens that started before invalidation
+	key := fsys.sharedKey(name)
+	fsys.opener.Forget(flightKey(key, fsys.gen))
+	sh := fsys.shard(key)
+	if f, ok := sh.load(key); ok {
+		f.stale = true
+		sh.files.Delete(key)
+	}
+	if fsys.statc != nil {
+		fsys.statc.Remove(name)
+	}
+	if fsys.missing != nil {
+		fsys.missing.Remove(name)
+	}
+	if fsys.failures != nil {
+		fsys.failures.Remove(name)
+	}
+	if fsys.digests != nil {
+		fsys.digests.Remove(name)
+	}
+	delete(fsys.inodes, fsys.key(name))
+	delete(fsys.dirs, path.Dir(name))
+	fsys.globs = nil
+}
+
+// InvalidateAll makes sure the next open of every file opens
+// it on the underlying file system, see Invalidate. All files
+// in the close cache are closed, errors are joined.
+func (fsys *FS) InvalidateAll() error {
+	fsys.lockShards()
+	fsys.mu.Lock()
+	// opens started before this are not shared anymore
+	fsys.gen++
+	for i := range fsys.shards {
+		sh := &fsys.shards[i]
+		sh.each(func(f *file) {
+			f.stale = true
+		})
+		sh.files.Range(func(k, _ any) bool {
+			sh.files.Delete(k)
+			return true
+		})
+	}
+	fsys.clearMetadata()
+	var cached []*file
+	if fsys.cache != nil {
+		fsys.cache.clear(func(f *file) {
+			cached = append(cached, f)
+		})
+	}
+	fsys.mu.Unlock()
+	fsys.unlockShards()
+
+	fsys.debug("invalidate all files", "")
+	var errs []error
+	if err := fsys.invalidateDirs(); err != nil {
+		errs = append(errs, err)
+	}
+	for _, f := range cached {
+		if err := f.close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// flightKey returns the key of an open of name in generation gen.
+func flightKey(name string, gen uint64) string {
+	if gen == 0 {
+		return name
+	}
+	return strconv.FormatUint(gen, 10) + "\x00" + name
+}
@@ -0,0 +1,90 @@
+package singleopen
+
+import (
+	"io"
+	"testing"
+	"testing/fstest"
+)
+
+func TestInvalidate(t *testing.T) {
+	mfs := fstest.MapFS{"file": &fstest.MapFile{Data: []byte("old")}}
+	fsys, err := New(mfs, WithKeepLast(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	old, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mfs["file"] = &fstest.MapFile{Data: []byte("new")}
+	if err := fsys.Invalidate("file"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := fsys.ReadFile("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "new" {
+		t.Errorf("got %q after invalidation, want new", data)
+	}
+	data, err = io.ReadAll(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "old" {
+		t.Errorf("got %q from current reader, want old", data)
+	}
+	old.Close()
+
+	// stale file is not cached, the new one is
+	if s := fsys.Stats(); s.Cached != 1 || s.Shared != 0 {
+		t.Errorf("got %+v, want only the new file cached", s)
+	}
+	if err := fsys.Invalidate("file"); err != nil {
+		t.Fatal(err)
+	}
+	if s := fsys.Stats(); s.OpenFiles != 0 {
+		t.Errorf("got %d open files, want cached file closed", s.OpenFiles)
+	}
+}
+
+func TestInvalidateAll(t *testing.T) {
+	mfs := fstest.MapFS{
+		"file1": &fstest.MapFile{Data: []byte("old")},
+		"file2": &fstest.MapFile{Data: []byte("old")},
+	}
+	fsys, err := New(mfs, WithKeepLast(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	fsys.Preopen("file1")
+	old, err := fsys.Open("file2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mfs["file1"] = &fstest.MapFile{Data: []byte("new")}
+	mfs["file2"] = &fstest.MapFile{Data: []byte("new")}
+	if err := fsys.InvalidateAll(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"file1", "file2"} {
+		data, err := fsys.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "new" {
+			t.Errorf("got %q from %s, want new", data, name)
+		}
+	}
+	if data, _ := io.ReadAll(old); string(data) != "old" {
+		t.Errorf("got %q from current reader, want old", data)
+	}
+	old.Close()
+	if s := fsys.Stats(); s.Cached != 2 {
+		t.Errorf("got %d cached files, want 2", s.Cached)
+	}
+}
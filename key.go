@@ -0,0 +1,33 @@
+package singleopen
+
+import "errors"
+
+// KeyFunc maps the name of a file to the key it's shared by.
+// Names with the same key share a single open file, like
+// names that only differ in case on a case-insensitive file
+// system. A KeyFunc must be deterministic.
+type KeyFunc func(name string) string
+
+// WithKeyFunc returns an Option that shares files by the key
+// returned by k instead of their name. The file is opened
+// by the name that is opened first, later opens by another
+// name with the same key reuse it. Metadata caches, pins and
+// the names passed to hooks and returned by OpenFiles are
+// not affected.
+func WithKeyFunc(k KeyFunc) Option {
+	return func(f *FS) error {
+		if k == nil {
+			return errors.New("singleopen: nil key func")
+		}
+		f.keyFn = k
+		return nil
+	}
+}
+
+// key returns the key name is shared by.
+func (fsys *FS) key(name string) string {
+	if fsys.keyFn == nil {
+		return name
+	}
+	return fsys.keyFn(name)
+}
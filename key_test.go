@@ -0,0 +1,43 @@
+package singleopen
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestKeyFunc(t *testing.T) {
+	fsys, err := New(fstest.MapFS{
+		"File": &fstest.MapFile{Data: []byte("data")},
+		"file": &fstest.MapFile{Data: []byte("data")},
+	}, WithKeyFunc(strings.ToLower), WithKeepLast(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f1, err := fsys.Open("File")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f2, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := fsys.RefCount("FILE"); n != 2 {
+		t.Errorf("got %d references, want 2", n)
+	}
+	f1.Close()
+	f2.Close()
+	// reused from the close cache
+	if b, err := fsys.ReadFile("fILE"); err != nil || string(b) != "data" {
+		t.Errorf("got %q, %v", b, err)
+	}
+	if n := fsys.Stats().Misses; n != 1 {
+		t.Errorf("got %d opens on the file system, want 1", n)
+	}
+	if err := fsys.Invalidate("FILE"); err != nil {
+		t.Fatal(err)
+	}
+	if n := fsys.CacheLen(); n != 0 {
+		t.Errorf("got %d cached files after invalidation, want 0", n)
+	}
+}
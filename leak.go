@@ -0,0 +1,84 @@
+package singleopen
+
+import (
+	"io/fs"
+	"sync/atomic"
+)
+
+// WithLeakDetection returns an Option that detects files
+// returned by Open that are garbage collected without being
+// closed. A leaked file is reported to Hooks.OnLeak and logged
+// at warn level, and its reference is released so the shared
+// file isn't kept open forever.
+func WithLeakDetection() Option {
+	return func(f *FS) error {
+		f.leaks = true
+		return nil
+	}
+}
+
+// trackedHandle returns a handle of f that records its open
+// site and reports a leak when it's garbage collected without
+// being closed, as enabled.
+func (f *file) trackedHandle() fs.File {
+	ref := handleRef{f, f.track()}
+	if f.isReaderAt() {
+		fr := &fileReaderAt{file: f, tracked: true, site: ref.site}
+		if f.fsys.leaks {
+			fr.cleanup = addCleanup(fr, ref)
+		}
+		return fr
+	}
+	h := &fileHandle{file: f, site: ref.site}
+	if f.fsys.leaks {
+		h.cleanup = addCleanup(h, ref)
+	}
+	return h
+}
+
+// handleRef is the reference to a file held by a handle.
+type handleRef struct {
+	f    *file
+	site *openSite // nil if not recorded
+}
+
+// leaked reports the leak of a reference to a file and
+// releases it.
+func leaked(ref handleRef) {
+	f := ref.f
+	// closing may block, don't hold up other cleanups
+	go func() {
+		f.fsys.hooks.leak(f.name)
+		if f.fsys.logger != nil {
+			args := []any{"name", f.name}
+			if ref.site != nil {
+				args = append(args, "stack", ref.site.String())
+			}
+			f.fsys.logger.Warn("singleopen: file is not closed", args...)
+		}
+		f.untrack(ref.site)
+		f.Close()
+	}()
+}
+
+// fileHandle is a tracked handle of a file that doesn't
+// implement io.ReaderAt.
+type fileHandle struct {
+	*file
+	site    *openSite
+	cleanup cleanup
+	closed  atomic.Pointer[closeSite]
+}
+
+func (h *fileHandle) Close() error {
+	cs := h.closing()
+	if cs == nil {
+		cs = &closeSite{} // not recorded
+	}
+	if !h.closed.CompareAndSwap(nil, cs) {
+		return h.closed.Load().again()
+	}
+	stopCleanup(h, h.cleanup)
+	h.untrack(h.site)
+	return h.file.Close()
+}
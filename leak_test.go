@@ -0,0 +1,64 @@
+package singleopen
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"runtime"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestLeakDetection(t *testing.T) {
+	leaks := make(chan string, 2)
+	fsys, err := New(streamFS{fstest.MapFS{
+		"file": &fstest.MapFile{},
+	}}, WithLeakDetection(), WithHooks(Hooks{
+		OnLeak: func(name string) { leaks <- name },
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// leaks a handle of a file without io.ReaderAt
+	func() {
+		if _, err := fsys.Open("file"); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	f, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Error(err)
+	}
+	if err := f.Close(); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("got %v on second close, want ErrClosed", err)
+	}
+
+	deadline := time.After(5 * time.Second)
+	for {
+		runtime.GC()
+		select {
+		case name := <-leaks:
+			if name != "file" {
+				t.Errorf("got leak of %q, want file", name)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := fsys.WaitIdle(ctx); err != nil {
+				t.Fatalf("leaked file is not released: %v", err)
+			}
+			select {
+			case name := <-leaks:
+				t.Errorf("closed file %q reported as leaked", name)
+			default:
+			}
+			return
+		case <-deadline:
+			t.Fatal("leak is not detected")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package singleopen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"syscall"
+)
+
+// limiter bounds the number of open files on the underlying
+// file system. It has its own lock because files are closed
+// in the background while FS.mu may be held.
+type limiter struct {
+	mu    sync.Mutex
+	max   int           // zero means no limit
+	n     int           // open or being opened files
+	freed chan struct{} // closed when n decrements
+
+	// files are reserved from parent as well, which calls
+	// evictParent to free a file; immutable, see Pool
+	parent      *limiter
+	evictParent func() bool
+}
+
+// acquire reserves a file. If the limit is reached, evict is
+// called to free a file. If evict reports false, acquire waits
+// until a file is released or ctx is done.
+func (l *limiter) acquire(ctx context.Context, evict func() bool) error {
+	l.mu.Lock()
+	for l.max > 0 && l.n >= l.max {
+		l.mu.Unlock()
+		freed := l.freedChan()
+		evict()
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		l.mu.Lock()
+	}
+	l.n++
+	l.mu.Unlock()
+	if l.parent != nil {
+		if err := l.parent.acquire(ctx, l.evictParent); err != nil {
+			l.put()
+			return err
+		}
+	}
+	return nil
+}
+
+// tryAcquire reserves a file like acquire but reports false
+// instead of waiting if the limit is reached and evict reports
+// false.
+func (l *limiter) tryAcquire(evict func() bool) bool {
+	l.mu.Lock()
+	for l.max > 0 && l.n >= l.max {
+		l.mu.Unlock()
+		freed := l.freedChan()
+		if !evict() {
+			return false
+		}
+		<-freed
+		l.mu.Lock()
+	}
+	l.n++
+	l.mu.Unlock()
+	if l.parent != nil && !l.parent.tryAcquire(l.evictParent) {
+		l.put()
+		return false
+	}
+	return true
+}
+
+// count returns the number of reserved files.
+func (l *limiter) count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.n
+}
+
+// freedChan returns a channel that is closed when a file is
+// released.
+func (l *limiter) freedChan() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.freed == nil {
+		l.freed = make(chan struct{})
+	}
+	return l.freed
+}
+
+// release releases a file reserved by acquire.
+func (l *limiter) release() {
+	l.put()
+	if l.parent != nil {
+		l.parent.release()
+	}
+}
+
+// put releases a file reserved from l itself.
+func (l *limiter) put() {
+	l.mu.Lock()
+	l.n--
+	if l.freed != nil {
+		close(l.freed)
+		l.freed = nil
+	}
+	l.mu.Unlock()
+}
+
+func (l *limiter) setMax(n int) {
+	l.mu.Lock()
+	l.max = n
+	if l.freed != nil {
+		// wake waiters to check the new limit
+		close(l.freed)
+		l.freed = nil
+	}
+	l.mu.Unlock()
+}
+
+// MaxOpen limits the number of simultaneously open files on
+// the underlying file system to n. If n <= 0, the number is
+// not limited. When the limit is reached, opening a file
+// evicts the least recently used file from the close cache
+// or, if the close cache is empty, blocks until a file is
+// closed or the context passed to OpenContext is done.
+// Directories are counted only if they are shared, see
+// WithDirHandles. If fsys is in a pool, see
+// WithPool, the quota of its tenant is set.
+func (fsys *FS) MaxOpen(n int) {
+	if n < 0 {
+		n = 0
+	}
+	fsys.limiter().setMax(n)
+}
+
+// WithMaxOpen returns an Option that limits the number of
+// open files, see (*FS).MaxOpen.
+func WithMaxOpen(n int) Option {
+	return func(f *FS) error {
+		if n < 0 {
+			return fmt.Errorf("singleopen: invalid max open count %d", n)
+		}
+		f.MaxOpen(n)
+		return nil
+	}
+}
+
+// evictOldest evicts the least recently used file from the
+// close cache. It reports whether a file is evicted.
+func (fsys *FS) evictOldest() bool {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if fsys.cache == nil {
+		return false
+	}
+	return fsys.cache.evict()
+}
+
+// openRecover opens name on the underlying file system. If
+// the process runs out of file descriptors, files from the
+// close cache are closed one by one and the open is retried.
+func (fsys *FS) openRecover(ctx context.Context, name string) (fs.File, error) {
+	f, err := fsys.openFile(ctx, name)
+	for err != nil && isTooManyOpen(err) {
+		freed := fsys.limiter().freedChan()
+		if !fsys.evictAny() {
+			break
+		}
+		fsys.debug("evicted file to recover from too many open files", name)
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return nil, err
+		}
+		f, err = fsys.openFile(ctx, name)
+	}
+	return f, err
+}
+
+func isTooManyOpen(err error) bool {
+	return errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE)
+}
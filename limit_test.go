@@ -0,0 +1,100 @@
+package singleopen
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"syscall"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestMaxOpen(t *testing.T) {
+	fsys, err := New(fstest.MapFS{
+		"file1": &fstest.MapFile{},
+		"file2": &fstest.MapFile{},
+	}, WithMaxOpen(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f1, err := fsys.Open("file1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// closing a file unblocks a waiting open
+	opened := make(chan error)
+	go func() {
+		f2, err := fsys.Open("file2")
+		if err == nil {
+			f2.Close()
+		}
+		opened <- err
+	}()
+	for {
+		// the waiting open creates the channel it waits for
+		fsys.limit.mu.Lock()
+		waiting := fsys.limit.freed != nil
+		fsys.limit.mu.Unlock()
+		if waiting {
+			break
+		}
+		runtime.Gosched()
+	}
+	f1.Close()
+	if err := <-opened; err != nil {
+		t.Fatal(err)
+	}
+
+	f1, _ = fsys.Open("file1")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := fsys.OpenContext(ctx, "file2"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, want context.DeadlineExceeded", err)
+	}
+	f1.Close()
+
+	// cached files are evicted to make room
+	fsys.KeepLast(4)
+	f1, _ = fsys.Open("file1")
+	f1.Close()
+	f2, err := fsys.Open("file2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f2.Close()
+	if s := fsys.Stats(); s.Evictions != 1 {
+		t.Errorf("got %d evictions, want 1", s.Evictions)
+	}
+	fsys.Close()
+}
+
+func TestTooManyOpenRecovery(t *testing.T) {
+	cfs := &countFS{FS: fstest.MapFS{
+		"file1": &fstest.MapFile{},
+		"file2": &fstest.MapFile{},
+	}, max: 1}
+	fsys, err := New(cfs, WithKeepLast(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	f1, err := fsys.Open("file1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f1.Close()
+	f2, err := fsys.Open("file2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f2.Close()
+
+	f1, _ = fsys.Open("file1")
+	defer f1.Close()
+	if _, err := fsys.Open("file2"); !errors.Is(err, syscall.EMFILE) {
+		t.Errorf("got error %v, want EMFILE without cached files", err)
+	}
+}
@@ -0,0 +1,36 @@
+package singleopen
+
+import "io/fs"
+
+// logger is the part of *slog.Logger used by a FS, see
+// WithLogger.
+type logger interface {
+	Debug(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+func (fsys *FS) debug(msg, name string, args ...any) {
+	if fsys.logger == nil {
+		return
+	}
+	if name != "" {
+		args = append(args, "name", name)
+	}
+	fsys.logger.Debug("singleopen: "+msg, args...)
+}
+
+// closeError reports an error of closing a file in the
+// background to Hooks.OnCloseError, Stats and the logger.
+func (fsys *FS) closeError(err error) {
+	fsys.stats.closeErrs.Add(1)
+	var name string
+	if pe, ok := err.(*fs.PathError); ok {
+		name = pe.Path
+	}
+	fsys.hooks.closeError(name, err)
+	if fsys.logger == nil {
+		return
+	}
+	fsys.logger.Warn("singleopen: close file", "err", err)
+}
@@ -0,0 +1,35 @@
+//go:build go1.21
+
+package singleopen
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	fsys, err := New(fstest.MapFS{"file": &fstest.MapFile{}}, WithLogger(l), WithKeepLast(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _ := fsys.Open("file")
+	f.Close()
+	f, _ = fsys.Open("file")
+	f.Close()
+	fsys.Close()
+
+	for _, msg := range []string{
+		"enable close cache",
+		"open file",
+		"reuse cached file",
+	} {
+		if !strings.Contains(buf.String(), msg) {
+			t.Errorf("log does not contain %q:\n%s", msg, buf.String())
+		}
+	}
+}
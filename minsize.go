@@ -0,0 +1,47 @@
+package singleopen
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// WithMinSize returns an Option that doesn't share regular
+// files smaller than n bytes, because reopening them is cheaper
+// than keeping them open. They are opened on the underlying
+// file system on every open like files rejected by a reuse
+// filter, or, if inline is true, read into memory and closed
+// right away, see WithReuseFilter.
+func WithMinSize(n int64, inline bool) Option {
+	return func(f *FS) error {
+		if n <= 0 {
+			return fmt.Errorf("singleopen: invalid min size %d", n)
+		}
+		f.minSize = n
+		f.minInline = inline
+		return nil
+	}
+}
+
+// tooSmall reports whether a file with info fi is too small
+// to be shared.
+func (fsys *FS) tooSmall(fi fs.FileInfo) bool {
+	return fi.Mode().IsRegular() && fi.Size() < fsys.minSize
+}
+
+// unshared returns the file f of name that is not shared, or
+// its contents if it's too small and inlined.
+func (fsys *FS) unshared(name string, f fs.File, fi fs.FileInfo) (fs.File, error) {
+	if !fsys.minInline || !fsys.tooSmall(fi) {
+		return f, nil
+	}
+	data, err := io.ReadAll(io.LimitReader(f, fi.Size()+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &memFile{Reader: bytes.NewReader(data), fi: fi}, nil
+}
@@ -0,0 +1,47 @@
+package singleopen
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMinSize(t *testing.T) {
+	mfs := fstest.MapFS{
+		"small": &fstest.MapFile{Data: []byte("small")},
+		"large": &fstest.MapFile{Data: bytes.Repeat([]byte("large"), 100)},
+	}
+	for _, inline := range []bool{false, true} {
+		t.Run(fmt.Sprint("inline=", inline), func(t *testing.T) {
+			fsys, err := New(mfs, WithKeepLast(2), WithMinSize(100, inline))
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := fsys.Open("small")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := f.(*memFile); ok != inline {
+				t.Errorf("got %T, want inlined %v", f, inline)
+			}
+			if b, err := io.ReadAll(f); err != nil || string(b) != "small" {
+				t.Errorf("got %q, %v", b, err)
+			}
+			if n := fsys.RefCount("small"); n != 0 {
+				t.Errorf("got %d references of small file, want 0", n)
+			}
+			f.Close()
+			if err := fsys.Pin("large"); err != nil {
+				t.Fatal(err)
+			}
+			if n := fsys.RefCount("large"); n != 1 {
+				t.Errorf("got %d references of large file, want 1", n)
+			}
+			if n := fsys.CacheLen(); n != 0 {
+				t.Errorf("got %d cached files, want 0", n)
+			}
+		})
+	}
+}
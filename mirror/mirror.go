@@ -0,0 +1,131 @@
+// Package mirror provides a file system that hedges opens
+// across equivalent replicas, like two mounts of the same
+// NFS export. Use it as the underlying file system of a
+// singleopen.FS to share the file of the replica that opened
+// it first:
+//
+//	m, err := mirror.New(50*time.Millisecond, os.DirFS(primary), os.DirFS(replica))
+//	fsys, err := singleopen.New(m, singleopen.WithKeepLast(128))
+package mirror
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"time"
+
+	"github.com/dwlnetnl/singleopen"
+)
+
+// FS is a file system that opens files on the first replica
+// that responds.
+type FS struct {
+	replicas []fs.FS
+	delay    time.Duration
+}
+
+var _ singleopen.OpenContextFS = (*FS)(nil)
+
+// New returns a FS that opens files on the first of replicas.
+// If the open doesn't complete within delay or it fails, the
+// file is opened on the next replica as well, and so on. The
+// file of the first successful open is returned, files opened
+// by others are closed. If all opens fail, the error of the
+// first replica is returned.
+func New(delay time.Duration, replicas ...fs.FS) (*FS, error) {
+	if len(replicas) == 0 {
+		return nil, errors.New("mirror: no replicas")
+	}
+	for _, r := range replicas {
+		if r == nil {
+			return nil, errors.New("mirror: nil file system")
+		}
+	}
+	if delay < 0 {
+		return nil, errors.New("mirror: invalid delay")
+	}
+	return &FS{replicas: replicas, delay: delay}, nil
+}
+
+func (m *FS) Open(name string) (fs.File, error) {
+	return m.OpenContext(context.Background(), name)
+}
+
+// result is the result of an open on the replica i.
+type result struct {
+	i   int
+	f   fs.File
+	err error
+}
+
+// OpenContext opens name on the replicas, see New. Opens on
+// replicas that implement singleopen.OpenContextFS are
+// canceled once a file is opened.
+func (m *FS) OpenContext(ctx context.Context, name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	ch := make(chan result, len(m.replicas))
+	next, pending := 0, 0
+	hedge := func() {
+		i := next
+		go func() {
+			f, err := open(ctx, m.replicas[i], name)
+			ch <- result{i, f, err}
+		}()
+		next++
+		pending++
+	}
+	// the losing opens are closed in the background
+	abandon := func() {
+		cancel()
+		go func(pending int) {
+			for ; pending > 0; pending-- {
+				if r := <-ch; r.err == nil {
+					r.f.Close()
+				}
+			}
+		}(pending)
+	}
+
+	hedge()
+	t := time.NewTimer(m.delay)
+	defer t.Stop()
+	errs := make([]error, len(m.replicas))
+	for pending > 0 {
+		var timeout <-chan time.Time
+		if next < len(m.replicas) {
+			timeout = t.C
+		}
+		select {
+		case r := <-ch:
+			pending--
+			if r.err == nil {
+				abandon()
+				return r.f, nil
+			}
+			errs[r.i] = r.err
+			if next < len(m.replicas) {
+				hedge()
+				t.Reset(m.delay)
+			}
+		case <-timeout:
+			hedge()
+			t.Reset(m.delay)
+		case <-ctx.Done():
+			err := ctx.Err()
+			abandon()
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+	}
+	cancel()
+	return nil, errs[0]
+}
+
+func open(ctx context.Context, fsys fs.FS, name string) (fs.File, error) {
+	if cfs, ok := fsys.(singleopen.OpenContextFS); ok {
+		return cfs.OpenContext(ctx, name)
+	}
+	return fsys.Open(name)
+}
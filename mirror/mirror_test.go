@@ -0,0 +1,121 @@
+package mirror
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/dwlnetnl/singleopen"
+)
+
+// slowFS delays Open until release is closed, if not nil, and
+// counts the open files.
+type slowFS struct {
+	fs.FS
+	release chan struct{}
+	closed  chan struct{} // receives when a file is closed
+	open    atomic.Int32
+}
+
+func (s *slowFS) Open(name string) (fs.File, error) {
+	if s.release != nil {
+		<-s.release
+	}
+	f, err := s.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	s.open.Add(1)
+	return &slowFile{f, s}, nil
+}
+
+type slowFile struct {
+	fs.File
+	s *slowFS
+}
+
+func (f *slowFile) Close() error {
+	f.s.open.Add(-1)
+	if f.s.closed != nil {
+		f.s.closed <- struct{}{}
+	}
+	return f.File.Close()
+}
+
+func TestMirror(t *testing.T) {
+	primary := &slowFS{FS: fstest.MapFS{
+		"file": &fstest.MapFile{Data: []byte("primary")},
+	}, release: make(chan struct{}), closed: make(chan struct{}, 1)}
+	replica := &slowFS{FS: fstest.MapFS{
+		"file":  &fstest.MapFile{Data: []byte("replica")},
+		"other": &fstest.MapFile{Data: []byte("other")},
+	}}
+	m, err := New(10*time.Millisecond, primary, replica)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys, err := singleopen.New(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// hedged to the replica
+	data, err := fsys.ReadFile("file")
+	if err != nil || string(data) != "replica" {
+		t.Errorf("got %q, %v, want replica", data, err)
+	}
+
+	// missing on the primary
+	data, err = fsys.ReadFile("other")
+	if err != nil || string(data) != "other" {
+		t.Errorf("got %q, %v, want other", data, err)
+	}
+
+	// missing everywhere
+	close(primary.release)
+	if _, err := fsys.Open("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %v, want ErrNotExist", err)
+	}
+
+	// the losing open is closed
+	<-primary.closed
+	if n := primary.open.Load(); n != 0 {
+		t.Errorf("got %d open files on primary, want 0", n)
+	}
+}
+
+func TestMirrorPrimary(t *testing.T) {
+	primary := fstest.MapFS{"file": &fstest.MapFile{Data: []byte("primary")}}
+	replica := &slowFS{FS: fstest.MapFS{
+		"file": &fstest.MapFile{Data: []byte("replica")},
+	}}
+	m, err := New(time.Second, primary, replica)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := m.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil || string(data) != "primary" {
+		t.Errorf("got %q, %v, want primary", data, err)
+	}
+	if n := replica.open.Load(); n != 0 {
+		t.Errorf("got %d opens on replica, want 0", n)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(0); err == nil {
+		t.Error("expected error without replicas")
+	}
+	if _, err := New(-1, fstest.MapFS{}); err == nil {
+		t.Error("expected error for negative delay")
+	}
+}
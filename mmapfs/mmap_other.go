@@ -0,0 +1,14 @@
+//go:build !unix
+
+package mmapfs
+
+import "os"
+
+// mmap maps nothing, memory mapping is not supported.
+func mmap(f *os.File, size int64) ([]byte, error) {
+	return nil, nil
+}
+
+func munmap(data []byte) error {
+	return nil
+}
@@ -0,0 +1,40 @@
+//go:build unix
+
+package mmapfs
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// mmap maps size bytes of f read-only. An empty file maps
+// to nil.
+func mmap(f *os.File, size int64) ([]byte, error) {
+	if size == 0 {
+		return nil, nil
+	}
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	var merr error
+	err = rc.Control(func(fd uintptr) {
+		data, merr = unix.Mmap(int(fd), 0, int(size), unix.PROT_READ, unix.MAP_SHARED)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if merr != nil {
+		return nil, os.NewSyscallError("mmap", merr)
+	}
+	return data, nil
+}
+
+func munmap(data []byte) error {
+	if data == nil {
+		return nil
+	}
+	return os.NewSyscallError("munmap", unix.Munmap(data))
+}
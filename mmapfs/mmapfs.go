@@ -0,0 +1,110 @@
+// Package mmapfs provides a file system whose files are
+// memory-mapped. Use it as the underlying file system of a
+// singleopen.FS to map each file once and let all readers
+// share the mapping; the mapping is removed when the shared
+// file is closed or evicted:
+//
+//	m, err := mmapfs.New(os.DirFS(dir), 64<<20)
+//	fsys, err := singleopen.New(m, singleopen.WithKeepLast(128))
+//
+// Files must not be truncated while they are mapped, reading
+// beyond the end of a truncated file crashes the program.
+package mmapfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// FS is a file system that memory-maps files opened from an
+// underlying file system.
+type FS struct {
+	fsys    fs.FS
+	maxSize int64
+}
+
+var _ fs.FS = (*FS)(nil)
+
+// New returns a FS that memory-maps regular files of fsys
+// that are an *os.File and at most maxSize bytes. Other files
+// are returned as opened. On platforms without memory mapping
+// all files are returned as opened.
+func New(fsys fs.FS, maxSize int64) (*FS, error) {
+	if fsys == nil {
+		return nil, errors.New("mmapfs: nil file system")
+	}
+	if maxSize <= 0 {
+		return nil, errors.New("mmapfs: invalid max size")
+	}
+	return &FS{fsys: fsys, maxSize: maxSize}, nil
+}
+
+// Open opens the named file and maps it if it's eligible.
+func (m *FS) Open(name string) (fs.File, error) {
+	f, err := m.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	of, ok := f.(*os.File)
+	if !ok {
+		return f, nil
+	}
+	fi, err := of.Stat()
+	if err != nil {
+		of.Close()
+		return nil, err
+	}
+	if !fi.Mode().IsRegular() || fi.Size() > m.maxSize {
+		return f, nil
+	}
+	data, err := mmap(of, fi.Size())
+	if err != nil {
+		of.Close()
+		return nil, &fs.PathError{Op: "mmap", Path: name, Err: err}
+	}
+	if data == nil && fi.Size() > 0 {
+		return f, nil // not supported
+	}
+	return &file{File: of, data: data}, nil
+}
+
+// file is an *os.File that reads at offsets from its mapping.
+type file struct {
+	*os.File
+	data []byte
+}
+
+// ReadAt copies len(p) bytes at offset off from the mapping.
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, &fs.PathError{Op: "read", Path: f.Name(), Err: fs.ErrInvalid}
+	}
+	if off >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Bytes returns the mapped contents of the file. The slice
+// is read-only and must not be used after Close.
+func (f *file) Bytes() []byte {
+	return f.data
+}
+
+// Unwrap returns the underlying *os.File.
+func (f *file) Unwrap() fs.File {
+	return f.File
+}
+
+// Close removes the mapping and closes the file.
+func (f *file) Close() error {
+	err := munmap(f.data)
+	f.data = nil
+	return errors.Join(err, f.File.Close())
+}
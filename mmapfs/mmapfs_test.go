@@ -0,0 +1,68 @@
+package mmapfs
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dwlnetnl/singleopen"
+)
+
+func TestMmap(t *testing.T) {
+	dir := t.TempDir()
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	os.WriteFile(filepath.Join(dir, "small"), data, 0o644)
+	os.WriteFile(filepath.Join(dir, "large"), append(data, data...), 0o644)
+	os.WriteFile(filepath.Join(dir, "empty"), nil, 0o644)
+	m, err := New(os.DirFS(dir), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys, err := singleopen.New(m, singleopen.WithKeepLast(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	for _, name := range []string{"small", "large", "empty"} {
+		want, _ := os.ReadFile(filepath.Join(dir, name))
+		got, err := fsys.ReadFile(name)
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("%s: got %d bytes, %v, want %d", name, len(got), err, len(want))
+		}
+	}
+
+	f, err := m.Open("small")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.(*file); !ok {
+		t.Errorf("got %T, want mapped file", f)
+	}
+	p := make([]byte, 4)
+	n, err := f.(io.ReaderAt).ReadAt(p, int64(len(data)-2))
+	if n != 2 || err != io.EOF || string(p[:n]) != "89" {
+		t.Errorf("got %q, %v, want 89 and EOF", p[:n], err)
+	}
+	if err := f.Close(); err != nil {
+		t.Error(err)
+	}
+	f, err = m.Open("large")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.(*os.File); !ok {
+		t.Errorf("got %T, want unmapped file over max size", f)
+	}
+	f.Close()
+
+	if err := fstest.TestFS(m, "small", "large", "empty"); err != nil {
+		t.Error(err)
+	}
+	if _, err := New(m, 0); err == nil {
+		t.Error("expected error for invalid max size")
+	}
+}
@@ -0,0 +1,61 @@
+package singleopen
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/dwlnetnl/singleopen/cache"
+)
+
+// maxMissing is the maximum number of files remembered to not
+// exist.
+const maxMissing = 4096
+
+// WithNotExistCache returns an Option that remembers for ttl
+// that a file does not exist, so repeated opens and stats of
+// missing files don't reach the underlying file system. Use
+// Invalidate or InvalidateAll when a missing file is created.
+// At most 4096 files are remembered, the least recently used
+// are forgotten first.
+func WithNotExistCache(ttl time.Duration) Option {
+	return func(f *FS) error {
+		if ttl <= 0 {
+			return fmt.Errorf("singleopen: invalid not exist cache ttl %v", ttl)
+		}
+		f.missingTTL = ttl
+		return nil
+	}
+}
+
+// isMissing reports whether name is known to not exist.
+// fsys.mu must be held.
+func (fsys *FS) isMissing(name string) bool {
+	if fsys.missing == nil {
+		return false
+	}
+	expires, ok := fsys.missing.Get(name)
+	if !ok {
+		return false
+	}
+	if !time.Now().Before(expires) {
+		fsys.missing.Remove(name)
+		return false
+	}
+	return true
+}
+
+// storeMissing remembers that name does not exist if err
+// says so.
+func (fsys *FS) storeMissing(name string, err error) {
+	if fsys.missingTTL == 0 || !errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	fsys.mu.Lock()
+	if fsys.missing == nil {
+		fsys.missing = cache.New[string, time.Time](maxMissing)
+	}
+	fsys.missing.Add(name, time.Now().Add(fsys.missingTTL))
+	fsys.mu.Unlock()
+}
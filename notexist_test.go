@@ -0,0 +1,56 @@
+package singleopen
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestNotExistCache(t *testing.T) {
+	mfs := fstest.MapFS{}
+	ffs := &flakyFS{FS: mfs}
+	fsys, err := New(ffs, WithNotExistCache(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := fsys.Open("missing"); !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("got %v, want not exist", err)
+		}
+		if _, err := fsys.Stat("missing"); !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("got %v, want not exist", err)
+		}
+	}
+	if n := ffs.opens.Load(); n != 1 {
+		t.Errorf("got %d opens, want 1", n)
+	}
+	mfs["missing"] = &fstest.MapFile{}
+	if _, err := fsys.Open("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %v, want cached not exist", err)
+	}
+	fsys.Invalidate("missing")
+	f, err := fsys.Open("missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if _, err := New(mfs, WithNotExistCache(0)); err == nil {
+		t.Error("expected error for invalid ttl")
+	}
+}
+
+func TestNotExistCacheLimit(t *testing.T) {
+	fsys, err := New(fstest.MapFS{}, WithNotExistCache(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxMissing+10; i++ {
+		fsys.Stat(fmt.Sprint("missing", i))
+	}
+	if n := fsys.missing.Len(); n != maxMissing {
+		t.Errorf("got %d missing files, want %d", n, maxMissing)
+	}
+}
@@ -0,0 +1,15 @@
+package singleopen
+
+import "io/fs"
+
+// OpenerFunc is a file system of a function that opens files,
+// for resources that are not a fs.FS, like objects of a blob
+// store: New(OpenerFunc(open)). The function is called with
+// the name passed to Open. The returned files should implement
+// io.ReaderAt, see FS.
+type OpenerFunc func(name string) (fs.File, error)
+
+// Open calls fn(name).
+func (fn OpenerFunc) Open(name string) (fs.File, error) {
+	return fn(name)
+}
@@ -0,0 +1,39 @@
+package singleopen
+
+import (
+	"io"
+	"io/fs"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+)
+
+func TestOpenerFunc(t *testing.T) {
+	m := fstest.MapFS{
+		"blob": &fstest.MapFile{Data: []byte("data")},
+	}
+	var opens atomic.Int64
+	fsys, err := New(OpenerFunc(func(name string) (fs.File, error) {
+		opens.Add(1)
+		return m.Open(name)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f1, err := fsys.Open("blob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f1.Close()
+	f2, err := fsys.Open("blob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f2.Close()
+	if n := opens.Load(); n != 1 {
+		t.Errorf("got %d opens, want 1", n)
+	}
+	if b, err := io.ReadAll(f2); err != nil || string(b) != "data" {
+		t.Errorf("got %q, %v, want data", b, err)
+	}
+}
@@ -0,0 +1,57 @@
+package singleopen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrOpenBusy is returned (wrapped) by opens that exceed the
+// limit of WithOpenConcurrency when they don't queue.
+var ErrOpenBusy = errors.New("singleopen: too many concurrent opens")
+
+// WithOpenConcurrency returns an Option that limits the number
+// of concurrent opens on the underlying file system to n.
+// Concurrent opens of the same file are shared and count once.
+// If queue is true, opens past the limit wait for an open to
+// finish, otherwise they fail with ErrOpenBusy. Remote file
+// systems may fall over when a cold cache triggers hundreds
+// of parallel opens.
+func WithOpenConcurrency(n int, queue bool) Option {
+	return func(f *FS) error {
+		if n <= 0 {
+			return fmt.Errorf("singleopen: invalid open concurrency %d", n)
+		}
+		f.opening = make(chan struct{}, n)
+		f.openQueue = queue
+		return nil
+	}
+}
+
+// acquireOpen reserves an open on the underlying file system.
+func (fsys *FS) acquireOpen(ctx context.Context) error {
+	if fsys.opening == nil {
+		return nil
+	}
+	if !fsys.openQueue {
+		select {
+		case fsys.opening <- struct{}{}:
+			return nil
+		default:
+			return ErrOpenBusy
+		}
+	}
+	select {
+	case fsys.opening <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releaseOpen releases an open reserved by acquireOpen.
+func (fsys *FS) releaseOpen() {
+	if fsys.opening != nil {
+		<-fsys.opening
+	}
+}
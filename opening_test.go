@@ -0,0 +1,76 @@
+package singleopen
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// gateFS blocks Open of name after sending on started until
+// release is closed.
+type gateFS struct {
+	fs.FS
+	name    string
+	started chan struct{}
+	release chan struct{}
+}
+
+func (g gateFS) Open(name string) (fs.File, error) {
+	if name == g.name {
+		g.started <- struct{}{}
+		<-g.release
+	}
+	return g.FS.Open(name)
+}
+
+func TestOpenConcurrency(t *testing.T) {
+	for _, queue := range []bool{false, true} {
+		gfs := gateFS{
+			FS: fstest.MapFS{
+				"a": &fstest.MapFile{},
+				"b": &fstest.MapFile{},
+			},
+			name:    "a",
+			started: make(chan struct{}),
+			release: make(chan struct{}),
+		}
+		fsys, err := New(gfs, WithOpenConcurrency(1, queue))
+		if err != nil {
+			t.Fatal(err)
+		}
+		done := make(chan error)
+		go func() {
+			f, err := fsys.Open("a")
+			if err == nil {
+				f.Close()
+			}
+			done <- err
+		}()
+		<-gfs.started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		_, err = fsys.OpenContext(ctx, "b")
+		cancel()
+		if !queue && !errors.Is(err, ErrOpenBusy) {
+			t.Errorf("got %v, want ErrOpenBusy", err)
+		}
+		if queue && !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got %v, want open to wait", err)
+		}
+		close(gfs.release)
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+		f, err := fsys.Open("b")
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+	if _, err := New(fstest.MapFS{}, WithOpenConcurrency(0, true)); err == nil {
+		t.Error("expected error for invalid concurrency")
+	}
+}
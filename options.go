@@ -0,0 +1,67 @@
+package singleopen
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// An Option configures an FS created by New.
+type Option func(*FS) error
+
+// New returns a FS that reuses file handles of files opened
+// from fsys. Options are applied in order and the first
+// invalid option is returned as error. The close cache is
+// enabled after all options are applied, so the order of
+// options doesn't matter for its settings.
+//
+// The zero value of FS is still valid; New only exists to
+// configure a FS in one step.
+func New(fsys fs.FS, opts ...Option) (*FS, error) {
+	if fsys == nil {
+		return nil, errors.New("singleopen: nil file system")
+	}
+	f := &FS{FS: fsys}
+	for _, opt := range opts {
+		if err := opt(f); err != nil {
+			f.Close() // leave a pool
+			return nil, err
+		}
+	}
+	if err := f.checkPolicy(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	f.KeepLast(f.keepLast)
+	f.KeepBytes(f.keepBytes)
+	f.MaxIdle(f.maxIdle)
+	if err := f.publishExpvar(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// WithKeepLast returns an Option that keeps the last n
+// recently closed files open, see (*FS).KeepLast.
+func WithKeepLast(n int) Option {
+	return func(f *FS) error {
+		if n < 0 {
+			return fmt.Errorf("singleopen: invalid keep last count %d", n)
+		}
+		f.keepLast = n
+		return nil
+	}
+}
+
+// WithKeepBytes returns an Option that keeps recently closed
+// files open up to a total size of n bytes, see (*FS).KeepBytes.
+func WithKeepBytes(n int64) Option {
+	return func(f *FS) error {
+		if n < 0 {
+			return fmt.Errorf("singleopen: invalid keep bytes size %d", n)
+		}
+		f.keepBytes = n
+		return nil
+	}
+}
@@ -0,0 +1,79 @@
+package singleopen
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	if _, err := New(nil); err == nil {
+		t.Error("expected error for nil file system")
+	}
+	if _, err := New(fstest.MapFS{}, WithKeepLast(-1)); err == nil {
+		t.Error("expected error for negative keep last count")
+	}
+
+	fsys, err := New(fstest.MapFS{"file": &fstest.MapFile{}}, WithKeepLast(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fsys.cache == nil || fsys.cache.maxEntries != 4 {
+		t.Error("close cache not configured")
+	}
+	if err := fstest.TestFS(fsys, "file"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the cache is enabled after all options
+	fsys, err = New(fstest.MapFS{}, WithKeepLast(4), WithMaxIdle(time.Hour),
+		WithCachePolicy(NewLFU()), WithCachePartition("dir", 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fsys.cache.policy.(*lfuPolicy); !ok || len(fsys.cache.parts) != 1 {
+		t.Error("cache settings after WithKeepLast are ignored")
+	}
+	if fsys.idleStop == nil {
+		t.Error("idle files are not closed")
+	}
+	fsys.Close()
+
+	// a failing option tears down the FS
+	pool, _ := NewPool(10)
+	_, err = New(fstest.MapFS{}, WithPool(pool), WithKeepLast(4),
+		WithMaxIdle(time.Hour), WithKeepBytes(-1))
+	if err == nil {
+		t.Fatal("expected error for negative keep bytes size")
+	}
+	if len(pool.tenants) != 0 {
+		t.Error("FS is not removed from the pool")
+	}
+}
+
+func TestKeepBytes(t *testing.T) {
+	fsys, err := New(fstest.MapFS{
+		"small1": &fstest.MapFile{Data: make([]byte, 10)},
+		"small2": &fstest.MapFile{Data: make([]byte, 10)},
+		"large":  &fstest.MapFile{Data: make([]byte, 100)},
+	}, WithKeepBytes(50))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	for _, name := range []string{"small1", "small2", "large"} {
+		f, err := fsys.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+	s := fsys.Stats()
+	if s.Cached != 2 || s.Evictions != 1 {
+		t.Errorf("got %d cached files and %d evictions, want 2 and 1", s.Cached, s.Evictions)
+	}
+	if _, ok := fsys.cache.get("large"); ok {
+		t.Error("large file is cached")
+	}
+}
@@ -0,0 +1,59 @@
+// Package otel traces a singleopen.FS with OpenTelemetry.
+package otel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/dwlnetnl/singleopen"
+)
+
+const scope = "github.com/dwlnetnl/singleopen"
+
+// WithTracerProvider returns a singleopen.Option that creates
+// a span for every open and adds an event for every read to
+// the span of the context passed to ReadAtContext, see
+// singleopen.ReaderAtContext.
+func WithTracerProvider(tp trace.TracerProvider) singleopen.Option {
+	return singleopen.WithTracer(&tracer{tp.Tracer(scope)})
+}
+
+type tracer struct {
+	tracer trace.Tracer
+}
+
+func (t *tracer) StartOpen(ctx context.Context, name string) (context.Context, func(singleopen.OpenInfo, error)) {
+	ctx, span := t.tracer.Start(ctx, "singleopen.Open",
+		trace.WithAttributes(attribute.String("file.path", name)))
+	return ctx, func(info singleopen.OpenInfo, err error) {
+		span.SetAttributes(
+			attribute.Bool("singleopen.hit", info.Hit),
+			attribute.Bool("singleopen.cache_hit", info.CacheHit),
+			attribute.Bool("singleopen.shared", info.Shared),
+		)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+func (t *tracer) Read(ctx context.Context, name string, off int64, n int, err error) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("file.path", name),
+		attribute.Int64("singleopen.offset", off),
+		attribute.Int("singleopen.bytes", n),
+	}
+	if err != nil {
+		attrs = append(attrs, attribute.String("error", err.Error()))
+	}
+	span.AddEvent("singleopen.read", trace.WithAttributes(attrs...))
+}
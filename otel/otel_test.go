@@ -0,0 +1,46 @@
+package otel
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/dwlnetnl/singleopen"
+)
+
+func TestWithTracerProvider(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	fsys, err := singleopen.New(fstest.MapFS{
+		"file": &fstest.MapFile{Data: []byte("data")},
+	}, WithTracerProvider(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	f, err := fsys.OpenContext(ctx, "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4)
+	if _, err := f.(singleopen.ReaderAtContext).ReadAtContext(ctx, buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	parent.End()
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	if name := spans[0].Name(); name != "singleopen.Open" {
+		t.Errorf("got span %q, want singleopen.Open", name)
+	}
+	if len(spans[1].Events()) == 0 {
+		t.Error("no read events on parent span")
+	}
+}
@@ -0,0 +1,231 @@
+// Package overlay provides a file system that merges layers
+// of file systems. Use it as the underlying file system of a
+// singleopen.FS to share a single pool of files and a single
+// limit of open files among all layers:
+//
+//	o, err := overlay.New(os.DirFS(patches), os.DirFS(assets))
+//	fsys, err := singleopen.New(o, singleopen.WithMaxOpen(256))
+package overlay
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+
+	"github.com/dwlnetnl/singleopen"
+)
+
+// FS is a file system that merges layers, upper layers come
+// first. A file is opened from the upmost layer that has it.
+// Directories are merged: their entries are those of the
+// directory in every layer, the upmost layer wins if entries
+// have the same name. A file in an upper layer hides a
+// directory of the same name, and the files in it, in lower
+// layers.
+type FS struct {
+	layers []fs.FS
+}
+
+var (
+	_ singleopen.OpenContextFS = (*FS)(nil)
+	_ fs.StatFS                = (*FS)(nil)
+	_ fs.ReadDirFS             = (*FS)(nil)
+)
+
+// New returns a FS that merges layers.
+func New(layers ...fs.FS) (*FS, error) {
+	if len(layers) == 0 {
+		return nil, errors.New("overlay: no layers")
+	}
+	for _, l := range layers {
+		if l == nil {
+			return nil, errors.New("overlay: nil file system")
+		}
+	}
+	return &FS{layers: layers}, nil
+}
+
+func (o *FS) Open(name string) (fs.File, error) {
+	return o.OpenContext(context.Background(), name)
+}
+
+// OpenContext opens name from the upmost layer that has it,
+// see FS. The context is passed to layers that implement
+// singleopen.OpenContextFS.
+func (o *FS) OpenContext(ctx context.Context, name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	for i, l := range o.layers {
+		f, err := open(ctx, l, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			if hidden(l, name) {
+				break
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if !fi.IsDir() {
+			return f, nil
+		}
+		f.Close()
+		return &dir{fs: o, name: name, fi: fi, layer: i}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (o *FS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	for _, l := range o.layers {
+		fi, err := fs.Stat(l, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			if hidden(l, name) {
+				break
+			}
+			continue
+		}
+		return fi, err
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir reads the merged directory name, see FS.
+func (o *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	for i, l := range o.layers {
+		fi, err := fs.Stat(l, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			if hidden(l, name) {
+				break
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+		}
+		return o.readDir(name, i)
+	}
+	return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+}
+
+// readDir merges directory name of layer and the layers below.
+func (o *FS) readDir(name string, layer int) ([]fs.DirEntry, error) {
+	seen := make(map[string]bool)
+	var entries []fs.DirEntry
+	for _, l := range o.layers[layer:] {
+		list, err := fs.ReadDir(l, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			if hidden(l, name) {
+				break
+			}
+			continue
+		}
+		if err != nil {
+			if isNotDir(l, name) {
+				break // hides the directories below
+			}
+			return nil, err
+		}
+		for _, e := range list {
+			if !seen[e.Name()] {
+				seen[e.Name()] = true
+				entries = append(entries, e)
+			}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// hidden reports whether name, which doesn't exist in l, is
+// hidden in lower layers because its closest existing parent
+// directory in l is a file.
+func hidden(l fs.FS, name string) bool {
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if fi, err := fs.Stat(l, dir); err == nil {
+			return !fi.IsDir()
+		}
+	}
+	return false
+}
+
+// isNotDir reports whether name is a file in l.
+func isNotDir(l fs.FS, name string) bool {
+	fi, err := fs.Stat(l, name)
+	return err == nil && !fi.IsDir()
+}
+
+func open(ctx context.Context, fsys fs.FS, name string) (fs.File, error) {
+	if cfs, ok := fsys.(singleopen.OpenContextFS); ok {
+		return cfs.OpenContext(ctx, name)
+	}
+	return fsys.Open(name)
+}
+
+// dir is a merged directory.
+type dir struct {
+	fs      *FS
+	name    string
+	fi      fs.FileInfo
+	layer   int // upmost layer of the directory
+	entries []fs.DirEntry
+	read    bool // entries are read
+	off     int
+}
+
+var _ fs.ReadDirFile = (*dir)(nil)
+
+func (d *dir) Stat() (fs.FileInfo, error) {
+	return d.fi, nil
+}
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *dir) Close() error {
+	return nil
+}
+
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		entries, err := d.fs.readDir(d.name, d.layer)
+		if err != nil {
+			return nil, err
+		}
+		d.entries = entries
+		d.read = true
+	}
+	rest := d.entries[d.off:]
+	if n <= 0 {
+		d.off = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.off += n
+	return rest[:n], nil
+}
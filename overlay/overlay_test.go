@@ -0,0 +1,62 @@
+package overlay
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dwlnetnl/singleopen"
+)
+
+func TestOverlay(t *testing.T) {
+	upper := fstest.MapFS{
+		"a":       &fstest.MapFile{Data: []byte("upper a")},
+		"dir/b":   &fstest.MapFile{Data: []byte("upper b")},
+		"hidden":  &fstest.MapFile{Data: []byte("upper hidden")},
+		"only/up": &fstest.MapFile{Data: []byte("up")},
+		"dir/sub": &fstest.MapFile{Mode: fs.ModeDir},
+	}
+	lower := fstest.MapFS{
+		"a":          &fstest.MapFile{Data: []byte("lower a")},
+		"dir/b":      &fstest.MapFile{Data: []byte("lower b")},
+		"dir/c":      &fstest.MapFile{Data: []byte("lower c")},
+		"hidden/d":   &fstest.MapFile{Data: []byte("lower d")},
+		"dir/sub/e":  &fstest.MapFile{Data: []byte("lower e")},
+		"only/lower": &fstest.MapFile{Data: []byte("lower")},
+	}
+	o, err := New(upper, lower)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys, err := singleopen.New(o, singleopen.WithMaxOpen(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = fstest.TestFS(fsys, "a", "dir/b", "dir/c", "dir/sub/e", "hidden", "only/up", "only/lower")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"a":      "upper a",
+		"dir/b":  "upper b",
+		"dir/c":  "lower c",
+		"hidden": "upper hidden",
+	} {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil || string(data) != want {
+			t.Errorf("%s: got %q, %v, want %q", name, data, err, want)
+		}
+	}
+	if _, err := fsys.Open("hidden/d"); err == nil {
+		t.Error("file below a file of an upper layer is visible")
+	}
+	if s := fsys.Stats(); s.OpenFiles != 0 {
+		t.Errorf("got %d open files, want 0", s.OpenFiles)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(); err == nil {
+		t.Error("expected error without layers")
+	}
+}
@@ -0,0 +1,71 @@
+package singleopen
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// WithCachePartition returns an Option that gives the files
+// in the directory prefix their own partition of the close
+// cache that keeps the last n closed files. Files evict only
+// files of their own partition, so many small files can't
+// evict the few files that are expensive to reopen. When
+// prefixes overlap, the longest one applies. Every partition
+// has its own instance of the cache policy, see
+// WithCachePolicyFunc, files outside any partition use the
+// limit of KeepLast. The limit of KeepBytes
+// applies to the whole cache. Partitions only apply while the
+// close cache is enabled.
+func WithCachePartition(prefix string, n int) Option {
+	return func(f *FS) error {
+		if prefix == "." {
+			prefix = ""
+		}
+		if prefix != "" && !fs.ValidPath(prefix) {
+			return fmt.Errorf("singleopen: invalid cache partition prefix %q", prefix)
+		}
+		if n <= 0 {
+			return fmt.Errorf("singleopen: invalid cache partition size %d", n)
+		}
+		f.partitions = append(f.partitions, partition{prefix: prefix, max: n})
+		return nil
+	}
+}
+
+// partition is a partition of the close cache.
+type partition struct {
+	prefix string
+	max    int
+}
+
+// cachePart is the state of a partition in a close cache.
+type cachePart struct {
+	partition
+	policy CachePolicy
+	n      int // files in the partition
+}
+
+func newCachePart(prefix string, n int, p CachePolicy) *cachePart {
+	return &cachePart{partition: partition{prefix, n}, policy: p}
+}
+
+// part returns the partition of f, or nil if f isn't in one.
+func (c *closeCache) part(f *file) *cachePart {
+	var match *cachePart
+	for _, p := range c.parts {
+		if !inDir(f.name, p.prefix) {
+			continue
+		}
+		if match == nil || len(p.prefix) > len(match.prefix) {
+			match = p
+		}
+	}
+	return match
+}
+
+// inDir reports whether name is in the directory prefix, an
+// empty prefix is the root.
+func inDir(name, prefix string) bool {
+	return prefix == "" || name == prefix || strings.HasPrefix(name, prefix+"/")
+}
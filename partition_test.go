@@ -0,0 +1,79 @@
+package singleopen
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCachePartition(t *testing.T) {
+	mfs := fstest.MapFS{}
+	for i := 0; i < 4; i++ {
+		mfs[fmt.Sprintf("thumbs/%d", i)] = &fstest.MapFile{}
+		mfs[fmt.Sprintf("videos/%d", i)] = &fstest.MapFile{}
+	}
+	fsys, err := New(mfs, WithKeepLast(2), WithCachePartition("videos", 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := func(name string) {
+		f, err := fsys.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+	for i := 0; i < 3; i++ {
+		open(fmt.Sprintf("videos/%d", i))
+	}
+	for i := 0; i < 4; i++ {
+		open(fmt.Sprintf("thumbs/%d", i))
+	}
+	var cached []string
+	fsys.Range(func(name string, _ int, inCache bool) bool {
+		if inCache {
+			cached = append(cached, name)
+		}
+		return true
+	})
+	sort.Strings(cached)
+	want := []string{"thumbs/2", "thumbs/3", "videos/0", "videos/1", "videos/2"}
+	if !reflect.DeepEqual(cached, want) {
+		t.Errorf("got cached %v, want %v", cached, want)
+	}
+
+	// evicts the least recently used video
+	open("videos/3")
+	if n := fsys.CacheLen(); n != 5 {
+		t.Errorf("got %d cached files, want 5", n)
+	}
+	if f, ok := fsys.cache.get("videos/0"); ok {
+		t.Errorf("videos/0 (%p) is not evicted", f)
+	}
+}
+
+func TestCachePartitionPolicy(t *testing.T) {
+	mfs := fstest.MapFS{"videos/0": &fstest.MapFile{}}
+	fsys, err := New(mfs, WithKeepLast(2), WithCachePartition("videos", 3),
+		WithCachePolicyFunc(func() CachePolicy { return customPolicy{NewLRU()} }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+	f, err := fsys.Open("videos/0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	fsys.mu.Lock()
+	_, ok := fsys.cache.parts[0].policy.(customPolicy)
+	fsys.mu.Unlock()
+	if !ok {
+		t.Error("partition doesn't use the cache policy")
+	}
+	if _, err := New(mfs, WithCachePolicy(customPolicy{NewLRU()}), WithCachePartition("videos", 3)); err == nil {
+		t.Error("expected error for a partition with a cache policy that can't be created")
+	}
+}
@@ -0,0 +1,48 @@
+package singleopen
+
+import "io/fs"
+
+// Pin opens the named file and keeps it open until Unpin or
+// Close is called, regardless of references and the close
+// cache. Pinning a pinned file has no effect.
+func (fsys *FS) Pin(name string) error {
+	fsys.mu.Lock()
+	_, ok := fsys.pins[name]
+	fsys.mu.Unlock()
+	if ok {
+		return nil
+	}
+
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	fsys.mu.Lock()
+	if _, ok := fsys.pins[name]; ok || fsys.closed.Load() {
+		// pinned concurrently or closed
+		fsys.mu.Unlock()
+		return f.Close()
+	}
+	if fsys.pins == nil {
+		fsys.pins = make(map[string]fs.File)
+	}
+	fsys.pins[name] = f
+	fsys.mu.Unlock()
+	fsys.debug("pin file", name)
+	return nil
+}
+
+// Unpin releases a file pinned by Pin. The file is closed,
+// or added to the close cache, when it's not referenced
+// anymore. Unpinning a file that is not pinned has no effect.
+func (fsys *FS) Unpin(name string) error {
+	fsys.mu.Lock()
+	f, ok := fsys.pins[name]
+	delete(fsys.pins, name)
+	fsys.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	fsys.debug("unpin file", name)
+	return f.Close()
+}
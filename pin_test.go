@@ -0,0 +1,48 @@
+package singleopen
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPin(t *testing.T) {
+	fsys, err := New(fstest.MapFS{
+		"index": &fstest.MapFile{},
+		"file":  &fstest.MapFile{},
+	}, WithKeepLast(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.Pin("index"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.Pin("index"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.Pin("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got error %v, want fs.ErrNotExist", err)
+	}
+
+	f, _ := fsys.Open("index")
+	f.Close()
+	f, _ = fsys.Open("file")
+	f.Close()
+	s := fsys.Stats()
+	if s.Pinned != 1 || s.Shared != 1 || s.RefCount != 1 || s.Evictions != 0 {
+		t.Errorf("got %+v, want index to be pinned", s)
+	}
+
+	if err := fsys.Unpin("index"); err != nil {
+		t.Fatal(err)
+	}
+	if s := fsys.Stats(); s.Pinned != 0 || s.Shared != 0 {
+		t.Errorf("got %+v, want index to be unpinned", s)
+	}
+
+	fsys.Pin("file")
+	if err := fsys.Close(); err != nil {
+		t.Errorf("got error %v, want pinned files to be closed", err)
+	}
+}
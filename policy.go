@@ -0,0 +1,257 @@
+package singleopen
+
+import (
+	"container/heap"
+	"container/list"
+	"errors"
+
+	"github.com/dwlnetnl/singleopen/cache"
+)
+
+// CacheEntry describes a file that is added to the close cache.
+type CacheEntry struct {
+	Name  string
+	Size  int64 // size from Stat when the file was opened
+	Opens int   // number of opens served by the open file
+}
+
+// A CachePolicy decides which file is evicted from the close
+// cache. Files are reused by removing them from the close
+// cache, so the policy only tracks files that are not in use.
+// A policy is used by a single FS and its methods are called
+// with internal locks held.
+type CachePolicy interface {
+	// Add is called when a file is added to the close cache.
+	Add(e CacheEntry)
+
+	// Remove is called when a file is removed from the close
+	// cache because it's reused or closed by the FS.
+	Remove(name string)
+
+	// Evict removes the file that should be evicted next and
+	// returns its name. It reports false if the policy is
+	// empty.
+	Evict() (name string, ok bool)
+}
+
+// WithCachePolicy returns an Option that sets the policy used
+// to evict files from the close cache. The default policy is
+// NewLRU. Policies other than those of NewLRU, NewLFU and
+// NewSIEVE can't be combined with WithPriority or
+// WithCachePartition, use WithCachePolicyFunc instead.
+func WithCachePolicy(p CachePolicy) Option {
+	return func(f *FS) error {
+		if p == nil {
+			return errors.New("singleopen: nil cache policy")
+		}
+		f.policy = p
+		f.newPolicy = policyFunc(p)
+		return nil
+	}
+}
+
+// WithCachePolicyFunc returns an Option that sets the policy
+// used to evict files from the close cache to one returned
+// by fn, like WithCachePolicy. The files of every priority,
+// see WithPriority, and of every partition, see
+// WithCachePartition, are evicted by their own policy returned
+// by fn.
+func WithCachePolicyFunc(fn func() CachePolicy) Option {
+	return func(f *FS) error {
+		if fn == nil {
+			return errors.New("singleopen: nil cache policy func")
+		}
+		p := fn()
+		if p == nil {
+			return errors.New("singleopen: nil cache policy")
+		}
+		f.policy = p
+		f.newPolicy = fn
+		return nil
+	}
+}
+
+// policyFunc returns the constructor of p if it's returned by
+// NewLRU, NewLFU or NewSIEVE, otherwise nil.
+func policyFunc(p CachePolicy) func() CachePolicy {
+	switch p.(type) {
+	case *lruPolicy:
+		return NewLRU
+	case *lfuPolicy:
+		return NewLFU
+	case *sievePolicy:
+		return NewSIEVE
+	}
+	return nil
+}
+
+// checkPolicy returns an error if the cache policy can't be
+// created for every priority and partition.
+func (fsys *FS) checkPolicy() error {
+	if fsys.policy == nil || fsys.newPolicy != nil {
+		return nil
+	}
+	if fsys.priority != nil {
+		return errors.New("singleopen: WithPriority needs the cache policy of WithCachePolicyFunc")
+	}
+	if len(fsys.partitions) > 0 {
+		return errors.New("singleopen: WithCachePartition needs the cache policy of WithCachePolicyFunc")
+	}
+	return nil
+}
+
+type lruPolicy struct {
+	c *cache.Cache[string, struct{}]
+}
+
+// NewLRU returns a policy that evicts the least recently
+// closed file.
+func NewLRU() CachePolicy {
+	return &lruPolicy{cache.New[string, struct{}](0)}
+}
+
+func (p *lruPolicy) Add(e CacheEntry) { p.c.Add(e.Name, struct{}{}) }
+
+func (p *lruPolicy) Remove(name string) { p.c.Remove(name) }
+
+func (p *lruPolicy) Evict() (string, bool) {
+	key, _, ok := p.c.Oldest()
+	if !ok {
+		return "", false
+	}
+	p.c.RemoveOldest()
+	return key, true
+}
+
+type lfuEntry struct {
+	name  string
+	opens int
+	seq   uint64 // insertion order to break ties
+	index int
+}
+
+type lfuHeap []*lfuEntry
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].opens != h[j].opens {
+		return h[i].opens < h[j].opens
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x interface{}) {
+	e := x.(*lfuEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
+
+type lfuPolicy struct {
+	heap    lfuHeap
+	entries map[string]*lfuEntry
+	seq     uint64
+}
+
+// NewLFU returns a policy that evicts the file that served
+// the least opens, the least recently closed file first.
+func NewLFU() CachePolicy {
+	return &lfuPolicy{entries: make(map[string]*lfuEntry)}
+}
+
+func (p *lfuPolicy) Add(e CacheEntry) {
+	p.Remove(e.Name)
+	p.seq++
+	le := &lfuEntry{name: e.Name, opens: e.Opens, seq: p.seq}
+	heap.Push(&p.heap, le)
+	p.entries[e.Name] = le
+}
+
+func (p *lfuPolicy) Remove(name string) {
+	if le, ok := p.entries[name]; ok {
+		heap.Remove(&p.heap, le.index)
+		delete(p.entries, name)
+	}
+}
+
+func (p *lfuPolicy) Evict() (string, bool) {
+	if len(p.heap) == 0 {
+		return "", false
+	}
+	le := heap.Pop(&p.heap).(*lfuEntry)
+	delete(p.entries, le.name)
+	return le.name, true
+}
+
+type sieveEntry struct {
+	name    string
+	visited bool
+}
+
+type sievePolicy struct {
+	ll      *list.List // front is newest
+	hand    *list.Element
+	entries map[string]*list.Element
+}
+
+// NewSIEVE returns a policy that implements the SIEVE eviction
+// algorithm. Files that served more than one open are treated
+// as visited, which makes SIEVE resistant to scans of files
+// that are opened only once.
+func NewSIEVE() CachePolicy {
+	return &sievePolicy{
+		ll:      list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (p *sievePolicy) Add(e CacheEntry) {
+	p.Remove(e.Name)
+	p.entries[e.Name] = p.ll.PushFront(&sieveEntry{e.Name, e.Opens > 1})
+}
+
+func (p *sievePolicy) Remove(name string) {
+	if ele, ok := p.entries[name]; ok {
+		if p.hand == ele {
+			p.hand = ele.Prev()
+		}
+		p.ll.Remove(ele)
+		delete(p.entries, name)
+	}
+}
+
+func (p *sievePolicy) Evict() (string, bool) {
+	if p.ll.Len() == 0 {
+		return "", false
+	}
+	ele := p.hand
+	if ele == nil {
+		ele = p.ll.Back()
+	}
+	for ele.Value.(*sieveEntry).visited {
+		ele.Value.(*sieveEntry).visited = false
+		ele = ele.Prev()
+		if ele == nil {
+			ele = p.ll.Back()
+		}
+	}
+	p.hand = ele.Prev()
+	name := ele.Value.(*sieveEntry).name
+	p.ll.Remove(ele)
+	delete(p.entries, name)
+	return name, true
+}
@@ -0,0 +1,74 @@
+package singleopen
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCachePolicies(t *testing.T) {
+	evictAll := func(p CachePolicy) []string {
+		var names []string
+		for {
+			name, ok := p.Evict()
+			if !ok {
+				return names
+			}
+			names = append(names, name)
+		}
+	}
+	entries := []CacheEntry{
+		{Name: "a", Opens: 3},
+		{Name: "b", Opens: 1},
+		{Name: "c", Opens: 2},
+		{Name: "d", Opens: 1},
+	}
+	tests := []struct {
+		name   string
+		policy CachePolicy
+		want   []string
+	}{
+		{"LRU", NewLRU(), []string{"a", "b", "d"}},
+		{"LFU", NewLFU(), []string{"b", "d", "a"}},
+		{"SIEVE", NewSIEVE(), []string{"b", "d", "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, e := range entries {
+				tt.policy.Add(e)
+			}
+			tt.policy.Remove("c")
+			if got := evictAll(tt.policy); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got eviction order %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCachePolicyScan(t *testing.T) {
+	mfs := fstest.MapFS{"hot": &fstest.MapFile{}}
+	for i := 0; i < 10; i++ {
+		mfs[fmt.Sprint("scan", i)] = &fstest.MapFile{}
+	}
+	fsys, err := New(mfs, WithCachePolicy(NewSIEVE()), WithKeepLast(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	for i := 0; i < 2; i++ {
+		f, _ := fsys.Open("hot")
+		f.Close()
+	}
+	for i := 0; i < 10; i++ {
+		f, _ := fsys.Open(fmt.Sprint("scan", i))
+		f.Close()
+	}
+	fsys.mu.Lock()
+	_, ok := fsys.cache.get("hot")
+	fsys.mu.Unlock()
+	if !ok {
+		t.Error("hot file is evicted by scan")
+	}
+}
@@ -0,0 +1,198 @@
+package singleopen
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// A Pool is a limit of open files shared by FS values, like
+// the mounts of a process that has a single budget of file
+// descriptors. The FS values of a pool are grouped in tenants
+// that each have a quota of open files. When the limit of the
+// pool is reached, opening a file evicts a file from the close
+// cache of the tenant with the most open files, so a busy
+// tenant can't take over the budget. Use NewPool to create a
+// Pool.
+type Pool struct {
+	limit limiter
+
+	mu      sync.Mutex // protects below
+	tenants []*tenant
+}
+
+// tenant is a group of FS values of a pool with a quota.
+type tenant struct {
+	name    string // empty for the tenant of a single FS
+	limit   limiter
+	members []*FS // protected by Pool.mu
+	next    int   // member to evict from next, protected by Pool.mu
+}
+
+// NewPool returns a Pool that limits the number of open files
+// to maxOpen, see (*FS).MaxOpen.
+func NewPool(maxOpen int) (*Pool, error) {
+	if maxOpen < 0 {
+		return nil, fmt.Errorf("singleopen: invalid max open count %d", maxOpen)
+	}
+	p := new(Pool)
+	p.limit.setMax(maxOpen)
+	return p, nil
+}
+
+// MaxOpen sets the limit of open files of the pool, see
+// (*FS).MaxOpen.
+func (p *Pool) MaxOpen(n int) {
+	if n < 0 {
+		n = 0
+	}
+	p.limit.setMax(n)
+}
+
+// SetQuota limits the number of open files of the named tenant
+// to n, see WithPoolTenant. If n <= 0, the tenant is limited
+// by the pool only. The quota can be set before the tenant
+// has any FS.
+func (p *Pool) SetQuota(tenant string, n int) {
+	if n < 0 {
+		n = 0
+	}
+	p.mu.Lock()
+	t := p.tenant(tenant)
+	p.mu.Unlock()
+	t.limit.setMax(n)
+}
+
+// tenant returns the named tenant, creating it if needed.
+// p.mu must be held.
+func (p *Pool) tenant(name string) *tenant {
+	if name != "" {
+		for _, t := range p.tenants {
+			if t.name == name {
+				return t
+			}
+		}
+	}
+	t := &tenant{name: name}
+	t.limit.parent = &p.limit
+	t.limit.evictParent = p.evict
+	p.tenants = append(p.tenants, t)
+	return t
+}
+
+// WithPool returns an Option that counts the open files of the
+// FS against the limit of p instead of its own, as a tenant
+// of its own. MaxOpen and WithMaxOpen set the quota of the
+// tenant then. The FS leaves the pool when it's closed.
+func WithPool(p *Pool) Option {
+	return WithPoolTenant(p, "")
+}
+
+// WithPoolTenant returns an Option like WithPool that adds the
+// FS to the named tenant of p, whose FS values share the quota
+// set by SetQuota. A multi-tenant server creates a FS per
+// tenant or per mount of a tenant.
+func WithPoolTenant(p *Pool, tenant string) Option {
+	return func(f *FS) error {
+		if p == nil {
+			return errors.New("singleopen: nil pool")
+		}
+		if f.pool != nil {
+			return errors.New("singleopen: file system is in a pool already")
+		}
+		p.mu.Lock()
+		t := p.tenant(tenant)
+		t.members = append(t.members, f)
+		p.mu.Unlock()
+		f.pool = p
+		f.tenant = t
+		return nil
+	}
+}
+
+// limiter returns the limiter of open files of fsys.
+func (fsys *FS) limiter() *limiter {
+	if fsys.tenant != nil {
+		return &fsys.tenant.limit
+	}
+	return &fsys.limit
+}
+
+// evict evicts a file from the close cache of fsys, or an
+// unreferenced shared directory, or if it has none, a file
+// of the other members of its tenant in turn. It reports
+// whether a file is evicted.
+func (fsys *FS) evict() bool {
+	if fsys.evictOldest() || fsys.evictDir() {
+		return true
+	}
+	if fsys.tenant == nil {
+		return false
+	}
+	return fsys.pool.evictFrom(fsys.tenant, fsys)
+}
+
+// evictAny evicts a file like evict or, if none is evicted,
+// from the other tenants of the pool of fsys.
+func (fsys *FS) evictAny() bool {
+	return fsys.evict() || fsys.pool != nil && fsys.pool.evict()
+}
+
+// evictFrom evicts a file from a member of t other than skip.
+func (p *Pool) evictFrom(t *tenant, skip *FS) bool {
+	p.mu.Lock()
+	members := append([]*FS(nil), t.members...)
+	start := t.next
+	t.next++
+	p.mu.Unlock()
+	for i := range members {
+		m := members[(start+i)%len(members)]
+		if m != skip && m.evictOldest() {
+			return true
+		}
+	}
+	return false
+}
+
+// evict evicts a file from the tenant with the most open files
+// that has a file to evict.
+func (p *Pool) evict() bool {
+	p.mu.Lock()
+	tenants := append([]*tenant(nil), p.tenants...)
+	p.mu.Unlock()
+	sort.SliceStable(tenants, func(i, j int) bool {
+		return tenants[i].limit.count() > tenants[j].limit.count()
+	})
+	for _, t := range tenants {
+		if p.evictFrom(t, nil) {
+			return true
+		}
+	}
+	return false
+}
+
+// leavePool removes fsys from its pool.
+func (fsys *FS) leavePool() {
+	t := fsys.tenant
+	if t == nil {
+		return
+	}
+	p := fsys.pool
+	p.mu.Lock()
+	for i, m := range t.members {
+		if m == fsys {
+			t.members = append(t.members[:i], t.members[i+1:]...)
+			break
+		}
+	}
+	if t.name == "" {
+		for i, t1 := range p.tenants {
+			if t1 == t {
+				p.tenants = append(p.tenants[:i], p.tenants[i+1:]...)
+				break
+			}
+		}
+	}
+	p.mu.Unlock()
+}
@@ -0,0 +1,108 @@
+package singleopen
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	pool, err := NewPool(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs1, err := New(fstest.MapFS{
+		"a": &fstest.MapFile{Data: []byte("a")},
+		"b": &fstest.MapFile{Data: []byte("b")},
+	}, WithPool(pool), WithKeepLast(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs2, err := New(fstest.MapFS{
+		"c": &fstest.MapFile{Data: []byte("c")},
+	}, WithPool(pool), WithKeepLast(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b"} {
+		if _, err := fs1.ReadFile(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	f, err := fs2.OpenContext(ctx, "c")
+	if err != nil {
+		t.Fatalf("open with files cached by other FS: %v", err)
+	}
+	defer f.Close()
+	if files := fs1.OpenFiles(); len(files) != 1 || files[0].Name != "b" {
+		t.Errorf("got %v cached by first FS, want b", files)
+	}
+
+	if err := fs1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(pool.tenants); n != 1 {
+		t.Errorf("got %d tenants after Close, want 1", n)
+	}
+	if _, err := New(fstest.MapFS{}, WithPool(nil)); err == nil {
+		t.Error("nil pool: got nil error")
+	}
+}
+
+func TestPoolQuota(t *testing.T) {
+	files := fstest.MapFS{
+		"a": &fstest.MapFile{Data: []byte("a")},
+		"b": &fstest.MapFile{Data: []byte("b")},
+		"c": &fstest.MapFile{Data: []byte("c")},
+	}
+	pool, err := NewPool(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.SetQuota("noisy", 2)
+	noisy, err := New(files, WithPoolTenant(pool, "noisy"), WithKeepLast(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	quiet, err := New(files, WithPoolTenant(pool, "quiet"), WithKeepLast(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached := func(fsys *FS) []string {
+		var names []string
+		for _, f := range fsys.OpenFiles() {
+			names = append(names, f.Name)
+		}
+		return names
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	read := func(fsys *FS, name string) {
+		t.Helper()
+		f, err := fsys.OpenContext(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		read(noisy, name)
+	}
+	if got := cached(noisy); !reflect.DeepEqual(got, []string{"b", "c"}) {
+		t.Errorf("got %v cached by tenant over quota, want [b c]", got)
+	}
+	read(quiet, "a")
+	read(quiet, "b")
+	// the pool is full, the tenant with the most files is evicted
+	if got := cached(quiet); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("got %v cached by quiet tenant, want [a b]", got)
+	}
+	if got := cached(noisy); !reflect.DeepEqual(got, []string{"c"}) {
+		t.Errorf("got %v cached by noisy tenant, want [c]", got)
+	}
+}
@@ -0,0 +1,90 @@
+package singleopen
+
+import (
+	"context"
+	"io"
+)
+
+// Prefetch opens the named file and reads n bytes at offset
+// off in the background, so a later read of the range is
+// served from memory. For an *os.File the kernel is asked to
+// read ahead, other files are read and the data is dropped.
+// The file is closed again afterwards, enable the close cache
+// to keep it open.
+//
+// At most the warm concurrency (see WithWarmConcurrency)
+// prefetches run at the same time, Prefetch drops the request
+// and reports false if that many are running.
+func (fsys *FS) Prefetch(name string, off, n int64) bool {
+	if off < 0 || n <= 0 || fsys.isClosed() {
+		return false
+	}
+	sem := fsys.prefetchSem()
+	select {
+	case sem <- struct{}{}:
+	default:
+		fsys.debug("drop prefetch", name)
+		return false
+	}
+	go func() {
+		defer func() { <-sem }()
+		if err := fsys.prefetch(name, off, n); err != nil {
+			fsys.debug("prefetch file", name, "err", err)
+		}
+	}()
+	return true
+}
+
+// prefetchSem returns the semaphore of Prefetch.
+func (fsys *FS) prefetchSem() chan struct{} {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if fsys.prefetching == nil {
+		n := fsys.warmN
+		if n == 0 {
+			n = defaultWarmConcurrency
+		}
+		fsys.prefetching = make(chan struct{}, n)
+	}
+	return fsys.prefetching
+}
+
+func (fsys *FS) prefetch(name string, off, n int64) error {
+	var info OpenInfo
+	f, dir, err := fsys.openShared(context.Background(), name, &info)
+	if err != nil {
+		return err
+	}
+	if dir != nil {
+		return dir.Close()
+	}
+	defer f.Close()
+
+	f.swap.RLock()
+	of, ok := osFile(f.File)
+	f.swap.RUnlock()
+	if ok && fadviseWillNeed(of, off, n) == nil {
+		return nil
+	}
+	if !f.isReaderAt() {
+		return nil
+	}
+	bp := copyBufPool.Get().(*[]byte)
+	defer copyBufPool.Put(bp)
+	buf := *bp
+	for end := off + n; off < end; {
+		p := buf
+		if int64(len(p)) > end-off {
+			p = p[:end-off]
+		}
+		m, err := f.readAt(context.Background(), p, off)
+		off += int64(m)
+		if err == io.EOF || m == 0 && err == nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
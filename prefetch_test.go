@@ -0,0 +1,42 @@
+package singleopen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPrefetch(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), make([]byte, 100000), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, fsys := range []*FS{
+		{FS: os.DirFS(dir)},
+		{FS: fstest.MapFS{"file": &fstest.MapFile{Data: make([]byte, 100000)}}},
+	} {
+		fsys.KeepLast(1)
+		if !fsys.Prefetch("file", 1000, 50000) {
+			t.Fatal("prefetch is dropped")
+		}
+		// wait for the prefetch by taking all its slots
+		sem := fsys.prefetchSem()
+		for i := 0; i < cap(sem); i++ {
+			sem <- struct{}{}
+		}
+		for i := 0; i < cap(sem); i++ {
+			<-sem
+		}
+		if s := fsys.Stats(); s.Cached != 1 {
+			t.Fatal("prefetched file is not cached")
+		}
+		if fsys.Prefetch("file", -1, 10) {
+			t.Error("invalid prefetch is started")
+		}
+		fsys.Close()
+		if fsys.Prefetch("file", 0, 10) {
+			t.Error("prefetch is started after close")
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package singleopen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+)
+
+// Preopen opens the named files and closes them again, so
+// they are kept in the close cache. It can be used to warm
+// the close cache, without it Preopen has no lasting effect.
+// All names are opened, errors are joined.
+func (fsys *FS) Preopen(names ...string) error {
+	var errs []error
+	for _, name := range names {
+		if err := fsys.preopen(context.Background(), name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (fsys *FS) preopen(ctx context.Context, name string) error {
+	f, err := fsys.OpenContext(ctx, name)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// defaultWarmConcurrency is the number of files opened
+// concurrently by WarmDir.
+const defaultWarmConcurrency = 8
+
+// WithWarmConcurrency returns an Option that sets the number
+// of files opened concurrently by WarmDir. The default is 8.
+func WithWarmConcurrency(n int) Option {
+	return func(f *FS) error {
+		if n <= 0 {
+			return fmt.Errorf("singleopen: invalid warm concurrency %d", n)
+		}
+		f.warmN = n
+		return nil
+	}
+}
+
+// WarmDir walks the tree rooted at dir and preopens the
+// regular files for which filter returns true, see Preopen.
+// A nil filter matches all regular files. Files are opened
+// concurrently. WarmDir stops when ctx is done.
+func (fsys *FS) WarmDir(ctx context.Context, dir string, filter func(path string, d fs.DirEntry) bool) error {
+	n := fsys.warmN
+	if n == 0 {
+		n = defaultWarmConcurrency
+	}
+	sem := make(chan struct{}, n)
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	walkErr := fs.WalkDir(fsys, dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || (filter != nil && !filter(name, d)) {
+			return nil
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := fsys.preopen(ctx, name); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+		return nil
+	})
+	wg.Wait()
+	if walkErr != nil {
+		errs = append(errs, walkErr)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,63 @@
+package singleopen
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPreopen(t *testing.T) {
+	fsys, err := New(fstest.MapFS{
+		"file1": &fstest.MapFile{},
+		"file2": &fstest.MapFile{},
+	}, WithKeepLast(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	err = fsys.Preopen("file1", "missing", "file2")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got error %v, want fs.ErrNotExist", err)
+	}
+	if s := fsys.Stats(); s.Cached != 2 || s.RefCount != 0 {
+		t.Errorf("got %+v, want 2 cached files", s)
+	}
+}
+
+func TestWarmDir(t *testing.T) {
+	fsys, err := New(fstest.MapFS{
+		"assets/app.js":      &fstest.MapFile{},
+		"assets/img/app.png": &fstest.MapFile{},
+		"assets/app.js.map":  &fstest.MapFile{},
+		"other/file":         &fstest.MapFile{},
+	}, WithKeepLast(8), WithWarmConcurrency(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	err = fsys.WarmDir(context.Background(), "assets", func(name string, d fs.DirEntry) bool {
+		return !strings.HasSuffix(name, ".map")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"assets/app.js", "assets/img/app.png"} {
+		if _, ok := fsys.cache.get(name); !ok {
+			t.Errorf("%s is not cached", name)
+		}
+	}
+	if s := fsys.Stats(); s.Cached != 2 {
+		t.Errorf("got %d cached files, want 2", s.Cached)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := fsys.WarmDir(ctx, ".", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,57 @@
+package singleopen
+
+import "errors"
+
+// Priority is the priority of a file in the close cache, see
+// WithPriority.
+type Priority int
+
+const (
+	PriorityLow    Priority = -1 // evicted first, like one-shot reads
+	PriorityNormal Priority = 0  // the default
+	PriorityHigh   Priority = 1  // evicted last, like index files
+)
+
+// PriorityFunc returns the priority of the named file.
+type PriorityFunc func(name string) Priority
+
+// WithPriority returns an Option that sets the priority of
+// files in the close cache by fn, called when a file is opened
+// on the underlying file system. Low priority files are
+// evicted before others, high priority files are only evicted
+// when no other file can be: when the limits of the cache or
+// of WithMaxOpen are exceeded by high priority files alone.
+// High priority files are not closed by WithMaxIdle, but
+// by Prune and CloseIdle. Files of a cache partition, see
+// WithCachePartition, are evicted by their partition. Files of
+// the same priority are evicted by their own cache policy, see
+// WithCachePolicy and WithCachePolicyFunc.
+func WithPriority(fn PriorityFunc) Option {
+	return func(f *FS) error {
+		if fn == nil {
+			return errors.New("singleopen: nil priority func")
+		}
+		f.priority = fn
+		return nil
+	}
+}
+
+// priorityOf returns the priority of the named file.
+func (fsys *FS) priorityOf(name string) Priority {
+	if fsys.priority == nil {
+		return PriorityNormal
+	}
+	return fsys.priority(name)
+}
+
+// tier returns the policy of the priority of f outside any
+// partition.
+func (c *closeCache) tier(f *file) CachePolicy {
+	switch {
+	case f.prio < PriorityNormal:
+		return c.low
+	case f.prio > PriorityNormal:
+		return c.high
+	}
+	return c.policy
+}
@@ -0,0 +1,127 @@
+package singleopen
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestPriority(t *testing.T) {
+	m := fstest.MapFS{}
+	for _, name := range []string{"index", "index2", "index3", "batch1", "batch2", "batch3", "n", "m"} {
+		m[name] = &fstest.MapFile{Data: []byte(name)}
+	}
+	fsys, err := New(m, WithKeepLast(2), WithPriority(func(name string) Priority {
+		switch {
+		case strings.HasPrefix(name, "index"):
+			return PriorityHigh
+		case strings.HasPrefix(name, "batch"):
+			return PriorityLow
+		}
+		return PriorityNormal
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached := func(names ...string) {
+		t.Helper()
+		var got []string
+		for _, f := range fsys.OpenFiles() {
+			got = append(got, f.Name)
+		}
+		if !reflect.DeepEqual(got, names) {
+			t.Errorf("got %v cached, want %v", got, names)
+		}
+	}
+	read := func(names ...string) {
+		t.Helper()
+		for _, name := range names {
+			if _, err := fsys.ReadFile(name); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	read("index", "batch1", "batch2", "batch3")
+	cached("batch3", "index")
+	read("n")
+	cached("index", "n")
+	read("m")
+	cached("index", "m")
+	// only high priority files are left to evict
+	read("index2", "index3")
+	cached("index2", "index3")
+
+	// a normal file is evicted before them
+	read("n")
+	cached("index2", "index3")
+	// high priority files are not idle
+	if n := fsys.evictIdle(time.Now().Add(time.Hour)); n != 0 {
+		t.Errorf("evicted %d idle files, want 0", n)
+	}
+	if err := fsys.Prune(); err != nil {
+		t.Fatal(err)
+	}
+	cached()
+}
+
+func TestPriorityPolicy(t *testing.T) {
+	m := fstest.MapFS{}
+	for _, name := range []string{"batch1", "batch2", "batch3"} {
+		m[name] = &fstest.MapFile{Data: []byte(name)}
+	}
+	fsys, err := New(m, WithKeepLast(2), WithCachePolicy(NewLFU()),
+		WithPriority(func(name string) Priority { return PriorityLow }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+	for _, name := range []string{"batch1", "batch1", "batch1", "batch2", "batch3"} {
+		if _, err := fsys.ReadFile(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the least frequently used file is evicted, not batch1
+	var got []string
+	for _, f := range fsys.OpenFiles() {
+		got = append(got, f.Name)
+	}
+	if want := []string{"batch1", "batch3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v cached, want %v", got, want)
+	}
+}
+
+// customPolicy is a cache policy not created by this package.
+type customPolicy struct {
+	CachePolicy
+}
+
+func TestPriorityPolicyFunc(t *testing.T) {
+	m := fstest.MapFS{"file": &fstest.MapFile{Data: []byte("file")}}
+	low := func(name string) Priority { return PriorityLow }
+	var n int
+	fsys, err := New(m, WithKeepLast(2), WithCachePolicyFunc(func() CachePolicy {
+		n++
+		return customPolicy{NewLRU()}
+	}), WithPriority(low))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+	if _, err := fsys.ReadFile("file"); err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("got %d policies, want 3", n)
+	}
+	fsys.mu.Lock()
+	_, ok := fsys.cache.low.(customPolicy)
+	fsys.mu.Unlock()
+	if !ok {
+		t.Error("low priority files are not evicted by the cache policy")
+	}
+	if _, err := New(m, WithCachePolicy(customPolicy{NewLRU()}), WithPriority(low)); err == nil {
+		t.Error("expected error for priorities with a cache policy that can't be created")
+	}
+}
@@ -0,0 +1,73 @@
+package singleopen
+
+import (
+	"fmt"
+	"sync"
+)
+
+// WithReadahead returns an Option that sets the window of
+// readahead of sequential reads. When reads of a file returned
+// by Open follow each other, the kernel is asked to read the
+// next window ahead. Readahead is only done for files that are
+// (or unwrap to) an *os.File on Linux. Without the option or
+// with a window of 0, readahead is disabled.
+func WithReadahead(window int64) Option {
+	return func(f *FS) error {
+		if window < 0 {
+			return fmt.Errorf("singleopen: invalid readahead window %d", window)
+		}
+		f.readahead = window
+		return nil
+	}
+}
+
+// sequential detects sequential reads of a file returned by
+// Open.
+type sequential struct {
+	mu    sync.Mutex
+	next  int64 // offset following the last read
+	runs  int   // number of consecutive sequential reads
+	ahead int64 // offset up to which readahead is done
+}
+
+// read records a read of n bytes at off and returns the range
+// to read ahead, n is 0 if there is none.
+func (s *sequential) read(off int64, n int, window int64) (int64, int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if off == s.next {
+		s.runs++
+	} else {
+		s.runs = 0
+		s.ahead = 0
+	}
+	s.next = off + int64(n)
+	if s.runs < 2 || s.next+window/2 < s.ahead {
+		return 0, 0
+	}
+	start := s.ahead
+	if start < s.next {
+		start = s.next
+	}
+	s.ahead = start + window
+	return start, window
+}
+
+// readahead asks the kernel to read ahead of a sequential read
+// of n bytes at off.
+func (f *fileReaderAt) readahead(off int64, n int) {
+	window := f.fsys.readahead
+	if window == 0 || n == 0 {
+		return
+	}
+	start, size := f.seq.read(off, n, window)
+	if size == 0 {
+		return
+	}
+	f.swap.RLock()
+	of, ok := osFile(f.File)
+	f.swap.RUnlock()
+	if ok {
+		fadviseWillNeed(of, start, size)
+	}
+}
@@ -0,0 +1,58 @@
+package singleopen
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadahead(t *testing.T) {
+	var s sequential
+	type ahead struct{ start, n int64 }
+	var got []ahead
+	for _, off := range []int64{0, 100, 200, 300, 400, 500, 600, 1000, 1100, 1200} {
+		if start, n := s.read(off, 100, 400); n > 0 {
+			got = append(got, ahead{start, n})
+		}
+	}
+	want := []ahead{{200, 400}, {600, 400}, {1300, 400}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got readahead %v, want %v", got, want)
+	}
+
+	dir := t.TempDir()
+	data := make([]byte, 100000)
+	if err := os.WriteFile(filepath.Join(dir, "file"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fsys, err := New(os.DirFS(dir), WithReadahead(4096))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got2, err := fsys.ReadFile("file")
+	if err != nil || len(got2) != len(data) {
+		t.Errorf("got %d bytes, %v", len(got2), err)
+	}
+	f, _ := fsys.Open("file")
+	if n, err := io.Copy(io.Discard, io.LimitReader(f, 50000)); n != 50000 || err != nil {
+		t.Errorf("got %d bytes, %v", n, err)
+	}
+	if f.(*fileReaderAt).seq.runs == 0 {
+		t.Error("sequential reads are not detected")
+	}
+	f.Close()
+
+	// readahead is disabled by default
+	fsys = &FS{FS: os.DirFS(dir)}
+	f, _ = fsys.Open("file")
+	io.Copy(io.Discard, io.LimitReader(f, 50000))
+	if f.(*fileReaderAt).seq.runs != 0 {
+		t.Error("sequential reads are read ahead by default")
+	}
+	f.Close()
+	if _, err := New(os.DirFS(dir), WithReadahead(-1)); err == nil {
+		t.Error("expected error for invalid window")
+	}
+}
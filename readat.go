@@ -0,0 +1,114 @@
+package singleopen
+
+import (
+	"context"
+	"io"
+	"io/fs"
+
+	"github.com/dwlnetnl/singleopen/internal/compat"
+)
+
+// ReadAt reads len(p) bytes of the named file starting at
+// offset off, like io.ReaderAt. The shared file is referenced
+// during the call only, without allocating a file to return.
+// It's an error if the file does not implement io.ReaderAt,
+// see WithSpool.
+func (fsys *FS) ReadAt(name string, p []byte, off int64) (int, error) {
+	ctx := context.Background()
+	var info OpenInfo
+	var end func(OpenInfo, error)
+	octx := ctx
+	if fsys.tracer != nil {
+		octx, end = fsys.tracer.StartOpen(ctx, name)
+	}
+	f, dir, err := fsys.openShared(octx, name, &info)
+	if end != nil {
+		end(info, err)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if dir != nil {
+		dir.Close()
+		return 0, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+	defer f.Close()
+	if !f.isReaderAt() {
+		return 0, &fs.PathError{Op: "read", Path: name, Err: compat.ErrUnsupported}
+	}
+	n, err := f.readAt(ctx, p, off)
+	if fsys.tracer != nil {
+		fsys.tracer.Read(ctx, name, off, n, err)
+	}
+	return n, err
+}
+
+// isReaderAt reports whether the shared file implements
+// io.ReaderAt.
+func (f *file) isReaderAt() bool {
+	f.swap.RLock()
+	defer f.swap.RUnlock()
+	_, ok := f.File.(io.ReaderAt)
+	return ok
+}
+
+// ReaderAtCloser is a file opened for positional reads.
+type ReaderAtCloser interface {
+	io.ReaderAt
+	io.Closer
+}
+
+// ReaderAtContext is implemented by the files of a FS that
+// implement io.ReaderAt. ReadAtContext is like ReadAt but
+// stops waiting for the rate limiter of WithThrottle when ctx
+// is done, and passes ctx to the Tracer.
+type ReaderAtContext interface {
+	ReadAtContext(ctx context.Context, p []byte, off int64) (int, error)
+}
+
+// OpenReaderAt opens the named file for positional reads,
+// see Open. It's an error if the file does not implement
+// io.ReaderAt, see WithSpool, or is a directory.
+func (fsys *FS) OpenReaderAt(name string) (ReaderAtCloser, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	// a handle of its own descriptor (see WithDup) reads it
+	fr, ok := f.(ReaderAtCloser)
+	if !ok {
+		f.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: compat.ErrUnsupported}
+	}
+	return fr, nil
+}
+
+// A Section is a view of n bytes of a shared file starting at
+// an offset. It holds a reference to the file until Close.
+type Section struct {
+	*io.SectionReader
+	f ReaderAtCloser
+}
+
+// OpenSection opens a view of n bytes of the named file
+// starting at offset off, see OpenReaderAt.
+func (fsys *FS) OpenSection(name string, off, n int64) (*Section, error) {
+	if off < 0 || n < 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	f, err := fsys.OpenReaderAt(name)
+	if err != nil {
+		return nil, err
+	}
+	return &Section{io.NewSectionReader(f, off, n), f}, nil
+}
+
+// Close releases the reference to the file.
+func (s *Section) Close() error {
+	if s.f == nil {
+		return fs.ErrClosed
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
@@ -0,0 +1,108 @@
+package singleopen
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dwlnetnl/singleopen/internal/compat"
+)
+
+func TestReadAt(t *testing.T) {
+	fsys := &FS{FS: fstest.MapFS{
+		"dir/file": &fstest.MapFile{Data: []byte("data")},
+	}}
+	fsys.KeepLast(1)
+	p := make([]byte, 3)
+	n, err := fsys.ReadAt("dir/file", p, 1)
+	if err != nil || string(p[:n]) != "ata" {
+		t.Errorf("got %q, %v, want ata", p[:n], err)
+	}
+	n, err = fsys.ReadAt("dir/file", p, 2)
+	if err != io.EOF || string(p[:n]) != "ta" {
+		t.Errorf("got %q, %v, want ta and EOF", p[:n], err)
+	}
+	if s := fsys.Stats(); s.Misses != 1 || s.CacheHits != 1 || s.Shared != 0 {
+		t.Errorf("got %+v, want file to be reused and released", s)
+	}
+	if _, err := fsys.ReadAt("dir", p, 0); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("got %v, want invalid for directory", err)
+	}
+
+	sub, err := fsys.Sub("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err = sub.(*SubFS).ReadAt("file", p, 0)
+	if err != nil || string(p[:n]) != "dat" {
+		t.Errorf("got %q, %v, want dat", p[:n], err)
+	}
+	f, err := fsys.Open("dir/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if testing.AllocsPerRun(100, func() { fsys.ReadAt("dir/file", p, 0) }) != 0 {
+		t.Error("ReadAt of shared file allocates")
+	}
+}
+
+func TestOpenReaderAt(t *testing.T) {
+	fsys := &FS{FS: fstest.MapFS{
+		"dir/file": &fstest.MapFile{Data: []byte("data")},
+	}}
+	f, err := fsys.OpenReaderAt("dir/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := make([]byte, 2)
+	n, err := f.ReadAt(p, 2)
+	if err != nil || string(p[:n]) != "ta" {
+		t.Errorf("got %q, %v, want ta", p[:n], err)
+	}
+	if err := f.Close(); err != nil {
+		t.Error(err)
+	}
+	if _, err := fsys.OpenReaderAt("dir"); !errors.Is(err, compat.ErrUnsupported) {
+		t.Errorf("got %v, want unsupported for directory", err)
+	}
+	sub, _ := fsys.Sub("dir")
+	if _, err := sub.(*SubFS).OpenReaderAt("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %v, want not exist", err)
+	}
+}
+
+func TestOpenSection(t *testing.T) {
+	fsys := &FS{FS: fstest.MapFS{
+		"file": &fstest.MapFile{Data: []byte("header,payload,trailer")},
+	}}
+	s, err := fsys.OpenSection("file", 7, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(s)
+	if err != nil || string(data) != "payload" {
+		t.Errorf("got %q, %v, want payload", data, err)
+	}
+	if _, err := s.Seek(-4, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = io.ReadAll(s)
+	if string(data) != "load" {
+		t.Errorf("got %q, want load", data)
+	}
+	if n := fsys.Stats().RefCount; n != 1 {
+		t.Errorf("got %d references, want 1", n)
+	}
+	if err := s.Close(); err != nil {
+		t.Error(err)
+	}
+	if err := s.Close(); err != fs.ErrClosed {
+		t.Errorf("got %v, want closed", err)
+	}
+	if _, err := fsys.OpenSection("file", -1, 1); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("got %v, want invalid", err)
+	}
+}
@@ -0,0 +1,70 @@
+package singleopen
+
+import (
+	"fmt"
+	"io/fs"
+	"time"
+)
+
+var _ fs.ReadDirFS = (*FS)(nil)
+
+// dirList is a cached directory listing.
+type dirList struct {
+	entries []fs.DirEntry
+	expires time.Time
+}
+
+// WithReadDirCache returns an Option that caches directory
+// listings returned by ReadDir for ttl.
+func WithReadDirCache(ttl time.Duration) Option {
+	return func(f *FS) error {
+		if ttl <= 0 {
+			return fmt.Errorf("singleopen: invalid read dir cache ttl %v", ttl)
+		}
+		f.dirTTL = ttl
+		return nil
+	}
+}
+
+// ReadDir reads the named directory and returns a list of
+// directory entries sorted by filename. Directories are not
+// reused unless enabled with WithDirHandles, otherwise ReadDir
+// delegates to the underlying file system. Listings are cached
+// if enabled with WithReadDirCache.
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	fsys.mu.Lock()
+	if fsys.closed.Load() {
+		fsys.mu.Unlock()
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrClosed}
+	}
+	if dl, ok := fsys.dirs[name]; ok {
+		if time.Now().Before(dl.expires) {
+			fsys.mu.Unlock()
+			return append([]fs.DirEntry(nil), dl.entries...), nil
+		}
+		delete(fsys.dirs, name)
+	}
+	fsys.mu.Unlock()
+
+	var entries []fs.DirEntry
+	var err error
+	if fsys.dirHandleTTL > 0 {
+		entries, err = fsys.readDir(name)
+	} else {
+		entries, err = fs.ReadDir(fsys.base(), name)
+	}
+	if err != nil || fsys.dirTTL == 0 {
+		return entries, err
+	}
+
+	fsys.mu.Lock()
+	if fsys.dirs == nil {
+		fsys.dirs = make(map[string]dirList)
+	}
+	fsys.dirs[name] = dirList{
+		entries: append([]fs.DirEntry(nil), entries...),
+		expires: time.Now().Add(fsys.dirTTL),
+	}
+	fsys.mu.Unlock()
+	return entries, nil
+}
@@ -0,0 +1,45 @@
+package singleopen
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestReadDir(t *testing.T) {
+	mfs := fstest.MapFS{"dir/file1": &fstest.MapFile{}}
+	fsys, err := New(mfs, WithReadDirCache(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := fs.ReadDir(fsys, "dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "file1" {
+		t.Fatalf("got entries %v", entries)
+	}
+
+	mfs["dir/file2"] = &fstest.MapFile{}
+	entries, err = fsys.ReadDir("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("got %d entries, want cached listing with 1 entry", len(entries))
+	}
+
+	fsys.mu.Lock()
+	dl := fsys.dirs["dir"]
+	dl.expires = time.Now()
+	fsys.dirs["dir"] = dl
+	fsys.mu.Unlock()
+	entries, err = fsys.ReadDir("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("got %d entries, want 2 after expiry", len(entries))
+	}
+}
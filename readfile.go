@@ -0,0 +1,57 @@
+package singleopen
+
+import (
+	"io"
+	"io/fs"
+	"math"
+)
+
+var _ fs.ReadFileFS = (*FS)(nil)
+
+// ReadFile reads the named file and returns its contents.
+// If the file implements io.ReaderAt, the shared file is
+// read in one allocation using its size. Otherwise ReadFile
+// falls back to the underlying file system.
+func (fsys *FS) ReadFile(name string) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fr, ok := f.(io.ReaderAt)
+	if !ok {
+		if rfs, ok := fsys.base().(fs.ReadFileFS); ok {
+			return rfs.ReadFile(name)
+		}
+		return io.ReadAll(f)
+	}
+
+	fi, ok := fsys.cachedStat(name)
+	if !ok {
+		fi, err = f.Stat()
+		if err != nil {
+			return nil, err
+		}
+	}
+	size := fi.Size()
+	data := make([]byte, size)
+	n, err := fr.ReadAt(data, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if int64(n) < size {
+		return data[:n], nil // file shrunk
+	}
+
+	// check if file has grown
+	var b [1]byte
+	if n, _ := fr.ReadAt(b[:], size); n == 0 {
+		return data, nil
+	}
+	rest, err := io.ReadAll(io.NewSectionReader(fr, size, math.MaxInt64-size))
+	if err != nil {
+		return nil, err
+	}
+	return append(data, rest...), nil
+}
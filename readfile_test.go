@@ -0,0 +1,32 @@
+package singleopen
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadFile(t *testing.T) {
+	mfs := fstest.MapFS{"file": &fstest.MapFile{Data: []byte("hello")}}
+	fsys := &FS{FS: mfs}
+	f, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	data, err := fs.ReadFile(fsys, "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("got %q, want %q", data, "hello")
+	}
+	if s := fsys.Stats(); s.Hits != 1 {
+		t.Errorf("got %d hits, want shared file to be reused", s.Hits)
+	}
+	if _, err := fsys.ReadFile("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got error %v, want fs.ErrNotExist", err)
+	}
+}
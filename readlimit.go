@@ -0,0 +1,29 @@
+package singleopen
+
+import "fmt"
+
+// WithReadConcurrency returns an Option that limits the number
+// of concurrent reads of the underlying file of a shared file
+// to n. On spinning disks many parallel reads of the same file
+// make the disk seek back and forth, which collapses the
+// throughput. Files read into memory are not limited.
+func WithReadConcurrency(n int) Option {
+	return func(f *FS) error {
+		if n <= 0 {
+			return fmt.Errorf("singleopen: invalid read concurrency %d", n)
+		}
+		f.readConc = n
+		return nil
+	}
+}
+
+// limitReads sets the read semaphore of f if reads are limited.
+func (fsys *FS) limitReads(f *file) {
+	if fsys.readConc == 0 {
+		return
+	}
+	if _, ok := f.File.(*memFile); ok {
+		return
+	}
+	f.readSem = make(chan struct{}, fsys.readConc)
+}
@@ -0,0 +1,44 @@
+package singleopen
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadConcurrency(t *testing.T) {
+	sfs := newSlowFS(fstest.MapFS{
+		"file": &fstest.MapFile{Data: bytes.Repeat([]byte("x"), 100)},
+	}, 8)
+	fsys, err := New(sfs, WithReadConcurrency(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(off int64) {
+			defer wg.Done()
+			buf := make([]byte, 10)
+			if _, err := fsys.ReadAt("file", buf, off); err != nil {
+				t.Error(err)
+			}
+		}(int64(i * 10))
+	}
+	// two reads run and the others wait for them
+	for i := 0; i < 2; i++ {
+		<-sfs.started
+	}
+	close(sfs.release)
+	wg.Wait()
+	if n := sfs.reads.Load(); n != 8 {
+		t.Errorf("got %d reads, want 8", n)
+	}
+	if n := sfs.peak.Load(); n > 2 {
+		t.Errorf("got %d concurrent reads, want at most 2", n)
+	}
+	if _, err := New(sfs, WithReadConcurrency(0)); err == nil {
+		t.Error("expected error for invalid concurrency")
+	}
+}
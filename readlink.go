@@ -0,0 +1,55 @@
+//go:build go1.25
+
+package singleopen
+
+import "io/fs"
+
+var (
+	_ fs.ReadLinkFS = (*FS)(nil)
+	_ fs.ReadLinkFS = (*SubFS)(nil)
+)
+
+// ReadLink returns the destination of the named symbolic
+// link. ReadLink delegates to the underlying file system.
+func (fsys *FS) ReadLink(name string) (string, error) {
+	if fsys.isClosed() {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrClosed}
+	}
+	return fs.ReadLink(fsys.base(), name)
+}
+
+// Lstat returns a FileInfo describing the named file without
+// following symbolic links. Lstat delegates to the underlying
+// file system, if it does not implement fs.ReadLinkFS Lstat
+// is identical to Stat.
+func (fsys *FS) Lstat(name string) (fs.FileInfo, error) {
+	if _, ok := fsys.base().(fs.ReadLinkFS); !ok {
+		return fsys.Stat(name)
+	}
+	if fsys.isClosed() {
+		return nil, &fs.PathError{Op: "lstat", Path: name, Err: fs.ErrClosed}
+	}
+	return fs.Lstat(fsys.base(), name)
+}
+
+// ReadLink returns the destination of the named symbolic
+// link, see (*FS).ReadLink.
+func (s *SubFS) ReadLink(name string) (string, error) {
+	full, err := s.fullName("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	target, err := s.fsys.ReadLink(full)
+	return target, s.fixErr(err)
+}
+
+// Lstat returns a FileInfo describing the named file without
+// following symbolic links, see (*FS).Lstat.
+func (s *SubFS) Lstat(name string) (fs.FileInfo, error) {
+	full, err := s.fullName("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := s.fsys.Lstat(full)
+	return fi, s.fixErr(err)
+}
@@ -0,0 +1,30 @@
+//go:build go1.25
+
+package singleopen
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadLink(t *testing.T) {
+	fsys := &FS{FS: fstest.MapFS{
+		"file": &fstest.MapFile{},
+		"link": &fstest.MapFile{Data: []byte("file"), Mode: fs.ModeSymlink},
+	}}
+	target, err := fs.ReadLink(fsys, "link")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != "file" {
+		t.Errorf("got target %q, want file", target)
+	}
+	fi, err := fs.Lstat(fsys, "link")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode()&fs.ModeSymlink == 0 {
+		t.Error("Lstat followed symbolic link")
+	}
+}
@@ -0,0 +1,56 @@
+package singleopen
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// ErrNegativeRefCount is returned (wrapped) by Close when the
+// reference count of a file drops below zero, which is a bug.
+var ErrNegativeRefCount = errors.New("singleopen: negative reference count")
+
+// RefCountPolicy is what Close does when the reference count
+// of a file drops below zero.
+type RefCountPolicy int
+
+const (
+	RefCountPanic RefCountPolicy = iota // panic, the default
+	RefCountError                       // call Hooks.OnRefCountError, return an error
+	RefCountClamp                       // log at error level, reset to zero
+)
+
+// WithRefCountPolicy returns an Option that sets what Close
+// does when the reference count of a file drops below zero.
+// With RefCountError and RefCountClamp the count is reset to
+// zero and the file is otherwise left alone.
+func WithRefCountPolicy(p RefCountPolicy) Option {
+	return func(f *FS) error {
+		if p < RefCountPanic || p > RefCountClamp {
+			return fmt.Errorf("singleopen: invalid reference count policy %d", p)
+		}
+		f.refPolicy = p
+		return nil
+	}
+}
+
+// negativeRefCount handles the negative reference count refc
+// of f according to the policy, after the count is reset to
+// zero.
+func (f *file) negativeRefCount(refc int64) error {
+	switch f.fsys.refPolicy {
+	case RefCountError:
+		err := &fs.PathError{Op: "close", Path: f.name, Err: ErrNegativeRefCount}
+		f.fsys.hooks.refCountError(f.name, err)
+		return err
+	case RefCountClamp:
+		l := f.fsys.logger
+		if l == nil {
+			l = defaultLogger()
+		}
+		l.Error("singleopen: negative reference count", "name", f.name, "refc", refc)
+		return nil
+	default:
+		panic(ErrNegativeRefCount)
+	}
+}
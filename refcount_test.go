@@ -0,0 +1,52 @@
+package singleopen
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRefCountPolicy(t *testing.T) {
+	var hookErr error
+	fsys, err := New(fstest.MapFS{"file": &fstest.MapFile{}},
+		WithRefCountPolicy(RefCountError),
+		WithHooks(Hooks{
+			OnRefCountError: func(name string, err error) { hookErr = err },
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	ff := f.(*fileReaderAt).file
+	if err := ff.negativeRefCount(-1); !errors.Is(err, ErrNegativeRefCount) {
+		t.Errorf("got %v, want ErrNegativeRefCount", err)
+	}
+	if !errors.Is(hookErr, ErrNegativeRefCount) {
+		t.Errorf("hook got %v, want ErrNegativeRefCount", hookErr)
+	}
+
+	fsys.refPolicy = RefCountClamp
+	fsys.logger = discardLogger{}
+	if err := ff.negativeRefCount(-1); err != nil {
+		t.Errorf("got %v, want nil", err)
+	}
+
+	fsys.refPolicy = RefCountPanic
+	defer func() {
+		if r := recover(); r != ErrNegativeRefCount {
+			t.Errorf("got panic %v, want ErrNegativeRefCount", r)
+		}
+	}()
+	ff.negativeRefCount(-1)
+}
+
+// discardLogger discards all messages.
+type discardLogger struct{}
+
+func (discardLogger) Debug(msg string, args ...any) {}
+func (discardLogger) Warn(msg string, args ...any)  {}
+func (discardLogger) Error(msg string, args ...any) {}
@@ -0,0 +1,90 @@
+package singleopen
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"syscall"
+
+	"github.com/dwlnetnl/singleopen/internal/compat"
+)
+
+// isStaleHandle reports whether err indicates that the handle
+// of a file became unusable while the file itself may not be,
+// like a stale NFS handle.
+func isStaleHandle(err error) bool {
+	return errors.Is(err, syscall.ESTALE) || errors.Is(err, syscall.EBADF)
+}
+
+// pread reads from the shared file at off. If the handle went
+// stale, the file is reopened and the read is replayed once.
+func (f *file) pread(ctx context.Context, p []byte, off int64) (n int, err error) {
+	defer func() {
+		f.fsys.breaker.report(f.name, err)
+		if werr := f.wait(ctx, n); werr != nil && (err == nil || err == io.EOF) {
+			err = werr
+		}
+	}()
+	if f.readSem != nil {
+		f.readSem <- struct{}{}
+		defer func() { <-f.readSem }()
+	}
+	f.swap.RLock()
+	ff := f.File
+	n, err = ff.(io.ReaderAt).ReadAt(p, off)
+	f.swap.RUnlock()
+	if err == nil || !isStaleHandle(err) {
+		return n, err
+	}
+	if rerr := f.reopen(ff); rerr != nil {
+		return n, err
+	}
+	f.swap.RLock()
+	defer f.swap.RUnlock()
+	ra, ok := f.File.(io.ReaderAt)
+	if !ok {
+		return n, err
+	}
+	return ra.ReadAt(p, off)
+}
+
+// reopen replaces the stale handle old by a newly opened one.
+// It does nothing if old is already replaced by a concurrent
+// reader. The replacement must support the same reads as old.
+func (f *file) reopen(old fs.File) error {
+	f.swap.Lock()
+	defer f.swap.Unlock()
+	if f.File != old {
+		return nil
+	}
+	f.fsys.debug("reopen stale file handle", f.name)
+	ff, err := f.fsys.openFile(context.Background(), f.name)
+	if err != nil {
+		return err
+	}
+	_, oldRA := old.(io.ReaderAt)
+	_, newRA := ff.(io.ReaderAt)
+	_, seeker := ff.(io.Seeker)
+	if oldRA && !newRA || !oldRA && !seeker {
+		ff.Close()
+		return compat.ErrUnsupported
+	}
+	if !oldRA {
+		// replay sequential reads at the current offset
+		if _, err := ff.(io.Seeker).Seek(f.pos, io.SeekStart); err != nil {
+			ff.Close()
+			return err
+		}
+	}
+	if f.fsys.flocks {
+		// the file is read, so it has readers
+		if err := flock(context.Background(), ff, lockShared); err != nil {
+			ff.Close()
+			return err
+		}
+	}
+	old.Close() // handle is stale, ignore error
+	f.File = ff
+	return nil
+}
@@ -0,0 +1,66 @@
+package singleopen
+
+import (
+	"io"
+	"io/fs"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"testing/fstest"
+)
+
+// staleFS returns files whose handle goes stale after the
+// first read.
+type staleFS struct {
+	fs.FS
+	opens atomic.Int32
+}
+
+func (s *staleFS) Open(name string) (fs.File, error) {
+	f, err := s.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if s.opens.Add(1) > 1 {
+		return f, nil
+	}
+	return &staleFile{File: f}, nil
+}
+
+type staleFile struct {
+	fs.File
+	reads int
+}
+
+func (f *staleFile) ReadAt(p []byte, off int64) (int, error) {
+	if f.reads++; f.reads > 1 {
+		return 0, &fs.PathError{Op: "read", Err: syscall.ESTALE}
+	}
+	return f.File.(io.ReaderAt).ReadAt(p, off)
+}
+
+func TestStaleHandle(t *testing.T) {
+	sfs := &staleFS{FS: fstest.MapFS{"file": &fstest.MapFile{Data: []byte("data")}}}
+	fsys := FS{FS: sfs}
+	f1, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f1.Close()
+	f2, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f2.Close()
+
+	p := make([]byte, 2)
+	for i, f := range []fs.File{f1, f2, f1} {
+		n, err := f.(io.ReaderAt).ReadAt(p, 2)
+		if err != nil || string(p[:n]) != "ta" {
+			t.Errorf("read %d: got %q, %v, want ta", i, p[:n], err)
+		}
+	}
+	if n := sfs.opens.Load(); n != 2 {
+		t.Errorf("got %d opens, want stale handle to be reopened once", n)
+	}
+}
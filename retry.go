@@ -0,0 +1,76 @@
+package singleopen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+)
+
+// OpenRetry configures retries of failed opens on the
+// underlying file system. Retries happen inside the shared
+// open, so callers waiting on the same file see one result.
+type OpenRetry struct {
+	// Attempts is the maximum number of opens, including the
+	// first one.
+	Attempts int
+
+	// Backoff returns the time to wait before the given retry,
+	// starting at 1. If nil, retries double a wait of 10ms.
+	Backoff func(retry int) time.Duration
+
+	// Retryable reports whether an open failing with err is
+	// retried. If nil, all errors except fs.ErrNotExist,
+	// fs.ErrPermission, fs.ErrInvalid and context errors are
+	// retried.
+	Retryable func(err error) bool
+}
+
+// WithOpenRetry returns an Option that retries failed opens
+// as configured by r.
+func WithOpenRetry(r OpenRetry) Option {
+	return func(f *FS) error {
+		if r.Attempts < 1 {
+			return fmt.Errorf("singleopen: invalid open attempts %d", r.Attempts)
+		}
+		f.retry = r
+		return nil
+	}
+}
+
+func (r *OpenRetry) backoff(retry int) time.Duration {
+	if r.Backoff != nil {
+		return r.Backoff(retry)
+	}
+	return 10 * time.Millisecond << (retry - 1)
+}
+
+func (r *OpenRetry) retryable(err error) bool {
+	if r.Retryable != nil {
+		return r.Retryable(err)
+	}
+	return !errors.Is(err, fs.ErrNotExist) &&
+		!errors.Is(err, fs.ErrPermission) &&
+		!errors.Is(err, fs.ErrInvalid) &&
+		!isContextErr(err)
+}
+
+// openRetry opens name, retrying failed opens as configured
+// by WithOpenRetry.
+func (fsys *FS) openRetry(ctx context.Context, name string) (fs.File, error) {
+	f, err := fsys.openRecover(ctx, name)
+	for retry := 1; err != nil && retry < fsys.retry.Attempts && fsys.retry.retryable(err); retry++ {
+		d := fsys.retry.backoff(retry)
+		fsys.debug("retry open", name, "retry", retry, "backoff", d, "err", err)
+		t := time.NewTimer(d)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, err
+		}
+		f, err = fsys.openRecover(ctx, name)
+	}
+	return f, err
+}
@@ -0,0 +1,42 @@
+package singleopen
+
+import (
+	"errors"
+	"io/fs"
+	"syscall"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestOpenRetry(t *testing.T) {
+	ffs := &flakyFS{FS: fstest.MapFS{"file": &fstest.MapFile{}}}
+	ffs.n.Store(2)
+	fsys, err := New(ffs, WithOpenRetry(OpenRetry{
+		Attempts: 3,
+		Backoff:  func(int) time.Duration { return time.Millisecond },
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if n := ffs.opens.Load(); n != 3 {
+		t.Errorf("got %d opens, want 3", n)
+	}
+
+	ffs.opens.Store(0)
+	ffs.n.Store(3)
+	if _, err := fsys.Open("file"); !errors.Is(err, syscall.EIO) {
+		t.Errorf("got %v, want EIO after all attempts", err)
+	}
+	if _, err := fsys.Open("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %v, want not exist", err)
+	}
+	if _, err := New(ffs, WithOpenRetry(OpenRetry{})); err == nil {
+		t.Error("expected error for zero attempts")
+	}
+}
@@ -0,0 +1,32 @@
+package singleopen
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// ReuseFilter reports whether the named file is shared. The
+// file info is from Stat of the underlying file system.
+type ReuseFilter func(name string, fi fs.FileInfo) bool
+
+// WithReuseFilter returns an Option that only shares the
+// files accepted by filter. Other files are opened on the
+// underlying file system on every open, like directories:
+// they are not reference counted, cached or counted by
+// WithMaxOpen, and closing them closes the opened file.
+func WithReuseFilter(filter ReuseFilter) Option {
+	return func(f *FS) error {
+		if filter == nil {
+			return errors.New("singleopen: nil reuse filter")
+		}
+		f.reuse = filter
+		return nil
+	}
+}
+
+// shares reports whether the named file with file info fi
+// is shared.
+func (fsys *FS) shares(name string, fi fs.FileInfo) bool {
+	return !fi.IsDir() && !fsys.tooSmall(fi) &&
+		(fsys.reuse == nil || fsys.reuse(name, fi))
+}
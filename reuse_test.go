@@ -0,0 +1,54 @@
+package singleopen
+
+import (
+	"bytes"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReuseFilter(t *testing.T) {
+	mfs := fstest.MapFS{
+		"blob":   &fstest.MapFile{Data: bytes.Repeat([]byte("x"), 100)},
+		"config": &fstest.MapFile{Data: []byte("x")},
+	}
+	for _, tt := range []struct {
+		name string
+		fsys fs.FS
+	}{
+		{"StatFS", mfs},
+		{"FS", streamFS{mfs}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys, err := New(tt.fsys, WithKeepLast(2), WithReuseFilter(func(name string, fi fs.FileInfo) bool {
+				return fi.Size() >= 100
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range []string{"blob", "config"} {
+				f1, err := fsys.Open(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				f2, err := fsys.Open(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				shared := name == "blob"
+				if got := fsys.RefCount(name) == 2; got != shared {
+					t.Errorf("%s: got shared %v, want %v", name, got, shared)
+				}
+				if err := f1.Close(); err != nil {
+					t.Error(err)
+				}
+				if err := f2.Close(); err != nil {
+					t.Error(err)
+				}
+			}
+			if n := fsys.CacheLen(); n != 1 {
+				t.Errorf("got %d cached files, want 1", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package singleopen
+
+import "fmt"
+
+// defaultFileLimit is the limit on open files used when the
+// system has no such limit.
+const defaultFileLimit = 8192
+
+// WithAutoLimits returns an Option that sizes MaxOpen and
+// KeepLast to the fractions openFrac and cacheFrac of the
+// limit on open files of the process. A fraction of zero
+// leaves the corresponding setting alone. On systems without
+// such limit, a limit of 8192 files is assumed.
+func WithAutoLimits(openFrac, cacheFrac float64) Option {
+	return func(f *FS) error {
+		if openFrac < 0 || openFrac > 1 || cacheFrac < 0 || cacheFrac > 1 {
+			return fmt.Errorf("singleopen: invalid limit fractions %v and %v", openFrac, cacheFrac)
+		}
+		limit, err := fileLimit()
+		if err != nil {
+			return fmt.Errorf("singleopen: get file limit: %w", err)
+		}
+		if openFrac > 0 {
+			f.MaxOpen(atLeastOne(float64(limit) * openFrac))
+		}
+		if cacheFrac > 0 {
+			f.keepLast = atLeastOne(float64(limit) * cacheFrac)
+		}
+		return nil
+	}
+}
+
+// atLeastOne returns n truncated to an int of at least 1.
+func atLeastOne(n float64) int {
+	if n < 1 {
+		return 1
+	}
+	return int(n)
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package singleopen
+
+// fileLimit returns the assumed limit on open files.
+func fileLimit() (uint64, error) {
+	return defaultFileLimit, nil
+}
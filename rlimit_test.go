@@ -0,0 +1,27 @@
+package singleopen
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestAutoLimits(t *testing.T) {
+	limit, err := fileLimit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys, err := New(fstest.MapFS{}, WithAutoLimits(0.5, 0.25))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+	if want := atLeastOne(float64(limit) * 0.5); fsys.limit.max != want {
+		t.Errorf("got max open %d, want %d", fsys.limit.max, want)
+	}
+	if want := atLeastOne(float64(limit) * 0.25); fsys.cache.maxEntries != want {
+		t.Errorf("got keep last %d, want %d", fsys.cache.maxEntries, want)
+	}
+	if _, err := New(fstest.MapFS{}, WithAutoLimits(2, 0)); err == nil {
+		t.Error("expected error for invalid fraction")
+	}
+}
@@ -0,0 +1,20 @@
+//go:build unix
+
+package singleopen
+
+import (
+	"math"
+	"syscall"
+)
+
+// fileLimit returns the soft limit on open files.
+func fileLimit() (uint64, error) {
+	var rl syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rl); err != nil {
+		return 0, err
+	}
+	if rl.Cur == math.MaxUint64 { // RLIM_INFINITY
+		return defaultFileLimit, nil
+	}
+	return uint64(rl.Cur), nil
+}
@@ -0,0 +1,31 @@
+//go:build go1.25
+
+package singleopen
+
+import (
+	"io/fs"
+	"os"
+)
+
+// RootFS returns a WriteFS of the directory of r, which
+// implements RenameFS too. It requires Go 1.25.
+func RootFS(r *os.Root) WriteFS {
+	return rootFS{r.FS(), r}
+}
+
+type rootFS struct {
+	fs.FS
+	r *os.Root
+}
+
+func (r rootFS) OpenFile(name string, flag int, perm fs.FileMode) (fs.File, error) {
+	return r.r.OpenFile(name, flag, perm)
+}
+
+func (r rootFS) Rename(oldname, newname string) error {
+	return r.r.Rename(oldname, newname)
+}
+
+func (r rootFS) Remove(name string) error {
+	return r.r.Remove(name)
+}
@@ -0,0 +1,68 @@
+package singleopen
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// maxSendfile is the maximum size of a single sendfile call.
+const maxSendfile = 4 << 20
+
+// sendFile sends src from off to w using sendfile(2) if src
+// is an *os.File and w is a socket. It reports whether the
+// copy is handled. The file offset of src is not changed.
+func sendFile(w io.Writer, src fs.File, off int64) (n int64, handled bool, err error) {
+	of, ok := osFile(src)
+	if !ok {
+		return 0, false, nil
+	}
+	sc, ok := w.(syscall.Conn)
+	if !ok {
+		return 0, false, nil
+	}
+	dst, err := sc.SyscallConn()
+	if err != nil {
+		return 0, false, nil
+	}
+	rc, err := of.SyscallConn()
+	if err != nil {
+		return 0, false, nil
+	}
+
+	var serr error
+	handled = true
+	cerr := rc.Control(func(infd uintptr) {
+		werr := dst.Write(func(outfd uintptr) bool {
+			for {
+				m, err := syscall.Sendfile(int(outfd), int(infd), &off, maxSendfile)
+				if m > 0 {
+					n += int64(m)
+				}
+				switch {
+				case err == syscall.EAGAIN:
+					return false // wait until writable
+				case err == syscall.EINTR:
+					continue
+				case err != nil:
+					if n == 0 && (err == syscall.EINVAL || err == syscall.ENOSYS) {
+						handled = false // not supported for this pair
+						return true
+					}
+					serr = os.NewSyscallError("sendfile", err)
+					return true
+				case m == 0:
+					return true // end of file
+				}
+			}
+		})
+		if serr == nil {
+			serr = werr
+		}
+	})
+	if serr == nil {
+		serr = cerr
+	}
+	return n, handled, serr
+}
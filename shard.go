@@ -0,0 +1,115 @@
+package singleopen
+
+import (
+	"fmt"
+	"hash/maphash"
+	"sync"
+)
+
+// shard holds the shared files of part of the file names so
+// opens of different files don't contend on one lock. The
+// lock of a shard is taken before FS.mu, never after.
+//
+// Files are looked up and referenced without holding the lock
+// as long as their reference count is not zero. Files are
+// added and removed, and references of files in the close
+// cache are taken, while holding the lock.
+type shard struct {
+	mu    sync.Mutex // serializes changes of files and of opened and stale of its files
+	files sync.Map   // name → *file
+}
+
+// ref takes a reference to the shared file of name without
+// holding the lock. It fails if the file is being closed.
+func (sh *shard) ref(name string) (*file, bool) {
+	v, ok := sh.files.Load(name)
+	if !ok {
+		return nil, false
+	}
+	f := v.(*file)
+	for {
+		n := f.refc.Load()
+		if n <= 0 {
+			return nil, false
+		}
+		if f.refc.CompareAndSwap(n, n+1) {
+			f.opens.Add(1)
+			return f, true
+		}
+	}
+}
+
+// load returns the shared file of name.
+func (sh *shard) load(name string) (*file, bool) {
+	v, ok := sh.files.Load(name)
+	if !ok {
+		return nil, false
+	}
+	return v.(*file), true
+}
+
+// each calls fn for each shared file.
+func (sh *shard) each(fn func(f *file)) {
+	sh.files.Range(func(_, v any) bool {
+		fn(v.(*file))
+		return true
+	})
+}
+
+// WithShards returns an Option that spreads the shared files
+// over n shards by hash of their name. Use more shards when
+// many distinct files are opened concurrently. The default
+// is a single shard.
+func WithShards(n int) Option {
+	return func(f *FS) error {
+		if n < 1 {
+			return fmt.Errorf("singleopen: invalid shard count %d", n)
+		}
+		f.shardN = n
+		return nil
+	}
+}
+
+// shard returns the shard of name.
+func (fsys *FS) shard(name string) *shard {
+	fsys.shardInit.Do(func() {
+		n := fsys.shardN
+		if n < 1 {
+			n = 1
+		}
+		fsys.shards = make([]shard, n)
+		fsys.seed = maphash.MakeSeed()
+	})
+	if len(fsys.shards) == 1 {
+		return &fsys.shards[0]
+	}
+	return &fsys.shards[maphash.String(fsys.seed, name)%uint64(len(fsys.shards))]
+}
+
+// lockShards locks all shards in order.
+func (fsys *FS) lockShards() {
+	fsys.shard("") // initialize
+	for i := range fsys.shards {
+		fsys.shards[i].mu.Lock()
+	}
+}
+
+// unlockShards unlocks all shards locked by lockShards.
+func (fsys *FS) unlockShards() {
+	for i := range fsys.shards {
+		fsys.shards[i].mu.Unlock()
+	}
+}
+
+// sharedFiles returns the shared files of all shards.
+func (fsys *FS) sharedFiles() []*file {
+	fsys.shard("") // initialize
+	var files []*file
+	for i := range fsys.shards {
+		sh := &fsys.shards[i]
+		sh.each(func(f *file) {
+			files = append(files, f)
+		})
+	}
+	return files
+}
@@ -0,0 +1,44 @@
+package singleopen
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+func TestShards(t *testing.T) {
+	mfs := fstest.MapFS{}
+	for i := 0; i < 16; i++ {
+		mfs[fmt.Sprint("file", i)] = &fstest.MapFile{Data: []byte("data")}
+	}
+	fsys, err := New(mfs, WithShards(4), WithKeepLast(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			data, err := fsys.ReadFile(name)
+			if err != nil || string(data) != "data" {
+				t.Errorf("got %q, %v", data, err)
+			}
+		}(fmt.Sprint("file", i%16))
+	}
+	wg.Wait()
+	s := fsys.Stats()
+	if s.Opens != 64 || s.Shared != 0 || s.Cached != 8 {
+		t.Errorf("got %+v", s)
+	}
+	if err := fsys.InvalidateAll(); err != nil {
+		t.Error(err)
+	}
+	if err := fsys.Close(); err != nil {
+		t.Error(err)
+	}
+	if _, err := New(mfs, WithShards(0)); err == nil {
+		t.Error("expected error for invalid shard count")
+	}
+}
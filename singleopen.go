@@ -3,14 +3,20 @@
 package singleopen
 
 import (
+	"context"
+	"crypto"
 	"errors"
+	"fmt"
+	"hash/maphash"
 	"io"
 	"io/fs"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"golang.org/x/sync/singleflight"
 
-	"github.com/dwlnetnl/singleopen/internal/lru"
+	"github.com/dwlnetnl/singleopen/cache"
 )
 
 // FS is a file system that reuses file handles.
@@ -18,254 +24,943 @@ type FS struct {
 	// FS is the underlying file system used to open files.
 	// The underlying file system should return files that
 	// implement io.ReaderAt. If the file is just a fs.File
-	// calls to Read will be synchronised.
+	// calls to Read will be synchronised. It must not be
+	// changed once fsys is used, use SwapFS instead.
 	FS fs.FS
 
-	opener singleflight.Group
+	swapped   atomic.Pointer[fs.FS] // set by SwapFS
+	opener    singleflight.Group
+	digester  singleflight.Group // see Digest
+	limit     limiter
+	stats     stats
+	closed    atomic.Bool // set while holding mu
+	shards    []shard     // immutable after first use
+	seed      maphash.Seed
+	shardInit sync.Once
+
+	waiters  atomic.Int32 // calls to WaitIdle and evictInline
+	evicting atomic.Int64 // evicted files not closed yet
+	// closed when a file is released, see WaitIdle; not
+	// protected by mu as the closer signals it holding mu
+	idleMu   sync.Mutex
+	released chan struct{}
+
 	mu     sync.Mutex // protects all below
-	files  map[string]*file
-	cache  *lru.Cache
-	closer chan *file
+	cache  *closeCache
+	closer *closer
+	// closed to stop idleCloser
+	idleStop chan struct{}
+	gen      uint64 // generation, incremented by InvalidateAll
+	pins     map[string]fs.File
+	dirs     map[string]dirList
+	statc    *cache.Cache[string, statEntry]
+	globs    *cache.Cache[string, globResult]
+	missing  *cache.Cache[string, time.Time] // expiry of files known to not exist
+	failures *cache.Cache[string, failure]
+	digests  *cache.Cache[string, map[crypto.Hash]digestEntry]
+	inodes   map[string]string // key of name to key of inode
+	// shared directories, see WithDirHandles
+	dirHandles map[string]*dirHandle
+	// keys of files open for writing, see OpenFile
+	writers map[string]struct{}
+	// files shared by appenders, see OpenAppend
+	appends map[string]*appendFile
+	// semaphore of Prefetch
+	prefetching chan struct{}
+
+	keepLast   int           // set by options, applied by New
+	keepBytes  int64         // set by options, applied by New
+	maxIdle    time.Duration // set by options, applied by New
+	expvar     string        // immutable after New
+	tracer     Tracer        // immutable after New
+	logger     logger        // immutable after New
+	hooks      Hooks         // immutable after New
+	policy     CachePolicy   // immutable after New
+	dirTTL     time.Duration // immutable after New
+	statTTL    time.Duration // immutable after New
+	globTTL    time.Duration // immutable after New
+	missingTTL time.Duration // immutable after New
+	errWindow  time.Duration // immutable after New
+	warmN      int           // immutable after New
+	checkFresh bool          // immutable after New
+	retry      OpenRetry     // immutable after New
+	breaker    *breaker      // immutable after New
+	shardN     int           // immutable after New
+	advice     Advice        // immutable after New
+	dontNeed   bool          // immutable after New
+	readahead  int64         // immutable after New, 0 is disabled
+	coalesce   bool          // immutable after New
+	inline     int64         // immutable after New
+
+	inlineBudget int64         // immutable after New, zero is the default
+	spool        bool          // immutable after New
+	spoolDir     string        // immutable after New
+	spoolMem     int64         // immutable after New
+	dup          bool          // immutable after New
+	leaks        bool          // immutable after New
+	stacks       bool          // immutable after New
+	strict       bool          // immutable after New
+	readConc     int           // immutable after New
+	throttles    []throttle    // immutable after New
+	opening      chan struct{} // immutable after New
+	openQueue    bool          // immutable after New
+
+	errCacheable func(error) bool // immutable after New
+	refPolicy    RefCountPolicy   // immutable after New
+	keyFn        KeyFunc          // immutable after New
+	inodeSharing bool             // immutable after New
+	reuse        ReuseFilter      // immutable after New
+	partitions   []partition      // immutable after New
+	minSize      int64            // immutable after New
+	minInline    bool             // immutable after New
+	dirHandleTTL time.Duration    // immutable after New
+	dirListings  bool             // immutable after New
+	appendSync   SyncPolicy       // immutable after New
+	flocks       bool             // immutable after New
+	snapshots    bool             // immutable after New
+	openTimeout  time.Duration    // immutable after New
+	pool         *Pool            // immutable after New
+	tenant       *tenant          // immutable after New
+	priority     PriorityFunc     // immutable after New
+
+	newPolicy func() CachePolicy // immutable after New, see WithCachePolicyFunc
 }
 
+// ErrInUse is returned (wrapped) by Close when files
+// are still referenced.
+var ErrInUse = errors.New("singleopen: files in use")
+
 var _ fs.FS = (*FS)(nil)
 
 // Open opens a file or returns the already open file.
 // Only regular files are being reused. Before opening
 // Stat is being called to determine the kind of file.
+// If the process runs out of file descriptors, files in
+// the close cache are closed and the open is retried.
 func (fsys *FS) Open(name string) (fs.File, error) {
-	fsys.mu.Lock()
-	f, ok := fsys.files[name]
+	return fsys.OpenContext(context.Background(), name)
+}
+
+// OpenContextFS is the interface implemented by a file system
+// that supports cancellation of Open.
+type OpenContextFS interface {
+	fs.FS
+
+	// OpenContext opens the named file. The context is
+	// used to cancel the open, not any operation on the
+	// returned file.
+	OpenContext(ctx context.Context, name string) (fs.File, error)
+}
+
+// OpenContext is like Open but stops waiting for the file
+// to be opened when ctx is done. If the underlying file
+// system implements OpenContextFS, ctx is passed to it.
+//
+// The context of the first caller is used when concurrent
+// calls share an open of the same file. If that context is
+// done, waiting callers with live contexts retry the open.
+// The context is not used by reads of the returned file, see
+// ReaderAtContext.
+func (fsys *FS) OpenContext(ctx context.Context, name string) (fs.File, error) {
+	var info OpenInfo
+	var f fs.File
+	var err error
+	if fsys.tracer == nil {
+		f, err = fsys.openContext(ctx, name, &info)
+	} else {
+		tctx, end := fsys.tracer.StartOpen(ctx, name)
+		f, err = fsys.openContext(tctx, name, &info)
+		end(info, err)
+	}
+	return f, err
+}
+
+func (fsys *FS) openContext(ctx context.Context, name string, info *OpenInfo) (fs.File, error) {
+	f, dir, err := fsys.openShared(ctx, name, info)
+	if err != nil {
+		return nil, err
+	}
+	if dir != nil {
+		return dir, nil
+	}
+	if err := f.lockShared(ctx); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := fsys.checkWriter(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if fsys.dup {
+		if df, ok := f.dupHandle(); ok {
+			return df, nil
+		}
+	}
+	return f.handle(), nil
+}
+
+// openShared returns a reference to the shared file of name
+// or, if name is a directory or not shared otherwise, the
+// file opened on the underlying file system.
+func (fsys *FS) openShared(ctx context.Context, name string, info *OpenInfo) (*file, fs.File, error) {
+	if fsys.closed.Load() {
+		return nil, nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrClosed}
+	}
+	fsys.stats.opens.Add(1)
+	key := fsys.key(name)
+	var fi fs.FileInfo
+	if fsys.inodeSharing {
+		fi, key = fsys.inodeKey(ctx, name, key)
+	}
+	sh := fsys.shard(key)
+	f, ok := sh.ref(key)
+	if !ok {
+		// the file may be moving to the close cache
+		sh.mu.Lock()
+		if f, ok = sh.ref(key); ok {
+			sh.mu.Unlock()
+		}
+	}
 	if ok {
-		f.refc++
-		fsys.mu.Unlock()
-		if ra, ok := f.File.(io.ReaderAt); ok {
-			return &fileReaderAt{f, ra, 0}, nil
+		fsys.stats.hits.Add(1)
+		info.Hit = true
+		if fsys.checkFresh && !fsys.isFresh(f) {
+			return fsys.reopen(ctx, f, info)
 		}
-		return f, nil
+		fsys.debug("reuse shared file", name)
+		fsys.hooks.hit(name, false)
+		fsys.link(f, name)
+		return f, nil, nil
 	}
 
 	// get file from close cache
+	fsys.mu.Lock()
 	if fsys.cache != nil {
-		cv, ok := fsys.cache.Get(name)
+		f, ok := fsys.cache.get(key)
 		if ok {
-			f := cv.(*file)
-			f.refc++ // increment before cache removal
-			fsys.cache.Remove(name)
-			fsys.files[name] = f
+			f.refc.Add(1)
+			f.opens.Add(1)
+			fsys.cache.remove(key)
 			fsys.mu.Unlock()
-			if ra, ok := f.File.(io.ReaderAt); ok {
-				return &fileReaderAt{f, ra, 0}, nil
+			sh.files.Store(key, f)
+			sh.mu.Unlock()
+			fsys.stats.cacheHits.Add(1)
+			info.CacheHit = true
+			if fsys.checkFresh && !fsys.isFresh(f) {
+				return fsys.reopen(ctx, f, info)
 			}
-			return f, nil
+			fsys.debug("reuse cached file", name)
+			fsys.hooks.hit(name, true)
+			fsys.link(f, name)
+			return f, nil, nil
 		}
 	}
 
-	// unlock while opening file
+	missing := fsys.isMissing(name)
+	failed := fsys.cachedError(name)
 	fsys.mu.Unlock()
+	sh.mu.Unlock()
+	if missing {
+		return nil, nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if failed != nil {
+		return nil, nil, failed
+	}
+	if err := fsys.breaker.allow(name); err != nil {
+		return nil, nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
 
 	// call stat to detect if a directory is being opened
 	// use fs support for stat
-	if sfs, ok := fsys.FS.(fs.StatFS); ok {
-		fi, err := sfs.Stat(name)
-		if err != nil {
-			if errors.Is(err, (*fs.PathError)(nil)) {
-				return nil, err
+	if canStat(fsys.base()) {
+		if fi == nil {
+			fi, _ = fsys.cachedStat(name)
+		}
+		if fi == nil {
+			var err error
+			fi, err = fsys.statBase(ctx, name)
+			if err != nil {
+				fsys.openFailed(name, err)
+				if errors.Is(err, (*fs.PathError)(nil)) {
+					return nil, nil, err
+				}
+				return nil, nil, &fs.PathError{Op: "open", Path: name, Err: err}
 			}
-			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+			fsys.storeStat(name, fi)
 		}
-		if fi.IsDir() {
-			return fsys.FS.Open(name)
+		if fi.IsDir() && fsys.dirHandleTTL > 0 {
+			d, err := fsys.openDir(ctx, name, fi, nil)
+			return nil, d, err
 		}
-		f, err := fsys.open(name)
-		if err != nil {
-			return nil, err
+		if !fsys.shares(name, fi) {
+			ff, err := fsys.openFile(ctx, name)
+			if err != nil {
+				return nil, nil, err
+			}
+			ff, err = fsys.unshared(name, ff, fi)
+			return nil, ff, err
 		}
-		if ra, ok := f.File.(io.ReaderAt); ok {
-			return &fileReaderAt{f, ra, 0}, nil
+		f, err := fsys.open(ctx, name, key, fi, info)
+		if err != nil {
+			fsys.openFailed(name, err)
+			return nil, nil, err
 		}
-		return f, nil
+		fsys.link(f, name)
+		return f, nil, nil
 	}
 
 	// do stat on opened file
-	f, err := fsys.open(name)
+	f, err := fsys.open(ctx, name, key, fi, info)
 	if err != nil {
-		return nil, err
+		fsys.openFailed(name, err)
+		return nil, nil, err
 	}
-	fi, err := f.Stat()
+	fi, err = f.Stat()
 	if err != nil {
-		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		return nil, nil, &fs.PathError{Op: "open", Path: name, Err: err}
 	}
-	if fi.IsDir() {
+	fsys.mu.Lock()
+	f.size = fi.Size()
+	f.mod = fi.ModTime()
+	fsys.mu.Unlock()
+	if !fsys.shares(name, fi) {
 		// remove from reusable files and close cache
+		sh.mu.Lock()
+		fsys.unshare(f)
 		fsys.mu.Lock()
-		delete(fsys.files, name)
 		if fsys.cache != nil {
-			fsys.cache.Remove(name)
+			fsys.cache.remove(key)
 		}
 		fsys.mu.Unlock()
-		// strip file reuse wrapper
+		sh.mu.Unlock()
+		// strip file reuse wrapper, unshared files are not counted
 		ff := f.File
-		f = nil
-		return ff, nil
+		if fi.IsDir() && fsys.dirHandleTTL > 0 {
+			// the shared directory keeps the reservation
+			d, err := fsys.openDir(ctx, name, fi, ff)
+			return nil, d, err
+		}
+		if f.inline {
+			fsys.releaseInline(f)
+		} else {
+			fsys.limiter().release()
+		}
+		ff, err = fsys.unshared(name, ff, fi)
+		return nil, ff, err
 	}
-	if ra, ok := f.File.(io.ReaderAt); ok {
-		return &fileReaderAt{f, ra, 0}, nil
+	fsys.link(f, name)
+	return f, nil, nil
+}
+
+// openFile opens name on the underlying file system.
+func (fsys *FS) openFile(ctx context.Context, name string) (fs.File, error) {
+	if err := fsys.acquireOpen(ctx); err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
 	}
-	return f, nil
+	defer fsys.releaseOpen()
+	return fsys.openBase(ctx, name)
 }
 
-func (fsys *FS) open(name string) (*file, error) {
-	v, err, shared := fsys.opener.Do(name, func() (interface{}, error) {
-		ff, err := fsys.FS.Open(name)
+// open opens name on the underlying file system, sharing the
+// open with concurrent callers of key. The file info fi is
+// passed to hooks and may be nil.
+func (fsys *FS) open(ctx context.Context, name, key string, fi fs.FileInfo, info *OpenInfo) (*file, error) {
+	fsys.mu.Lock()
+	gen := fsys.gen
+	fsys.mu.Unlock()
+	ch := fsys.opener.DoChan(flightKey(key, gen), func() (interface{}, error) {
+		if err := fsys.limiter().acquire(ctx, fsys.evict); err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		ff, err := fsys.openRetry(ctx, name)
+		fsys.breaker.report(name, err)
+		fsys.hooks.open(name, fi, err)
 		if err != nil {
+			fsys.limiter().release()
 			return nil, err
 		}
+		fsys.adviseOpen(name, ff)
+		sh := fsys.shard(key)
 		f := &file{
-			File: ff,
-			fsys: fsys,
-			name: name,
-			refc: 1,
+			File:  ff,
+			fsys:  fsys,
+			shard: sh,
+			name:  name,
+			key:   key,
+			since: time.Now(),
+			prio:  fsys.priorityOf(name),
 		}
-		fsys.mu.Lock()
-		if fsys.files == nil {
-			fsys.files = make(map[string]*file)
+		f.refc.Store(1) // taken over by the first caller
+		if fsys.checkFresh || (fsys.inline > 0 || fsys.spool) && fi == nil {
+			// capture the current state of the opened file
+			fi, _ = ff.Stat()
+		}
+		if fi != nil {
+			f.size = fi.Size()
+			f.mod = fi.ModTime()
+		}
+		if fsys.canInline(fi) && fsys.reserveInline(fi.Size()) {
+			if mf, ok := fsys.inlineFile(ff, fi); !ok {
+				fsys.releaseInline(f)
+			} else {
+				if err := ff.Close(); err != nil {
+					fsys.closeError(&fs.PathError{Op: "close", Path: name, Err: err})
+				}
+				fsys.limiter().release()
+				f.File = mf
+				f.inline = true
+				fsys.debug("inline file", name, "size", fi.Size())
+			}
 		}
-		fsys.files[name] = f
+		if fsys.spool {
+			if err := fsys.spoolOpen(f, fi); err != nil {
+				ff.Close()
+				fsys.limiter().release()
+				return nil, err
+			}
+		}
+		fsys.limitReads(f)
+		f.throttle = fsys.throttleOf(name)
+		fsys.stats.misses.Add(1)
+		sh.mu.Lock()
+		fsys.mu.Lock()
+		stale := fsys.gen != gen
 		fsys.mu.Unlock()
+		if stale {
+			// invalidated while opening, don't share
+			f.stale = true
+		} else {
+			sh.files.Store(key, f)
+		}
+		sh.mu.Unlock()
+		fsys.debug("open file", name)
 		return f, nil
 	})
-	if err != nil {
-		return nil, err
-	}
 
-	f := v.(*file)
-	if shared {
-		// increment reference count, file open was shared
-		fsys.mu.Lock()
-		if f.refc == 0 {
-			// retry, file is already closed
-			fsys.mu.Unlock()
-			return fsys.open(name)
+	var res singleflight.Result
+	select {
+	case res = <-ch:
+	case <-ctx.Done():
+		// release the reference of this call in the background
+		go func() {
+			res := <-ch
+			if res.Err != nil {
+				return
+			}
+			if f := res.Val.(*file); fsys.acquire(f) {
+				f.Close()
+			}
+		}()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ctx.Err()}
+	}
+	if res.Err != nil {
+		if isContextErr(res.Err) && !isTimeout(res.Err) && ctx.Err() == nil {
+			// retry, context of shared open is done
+			return fsys.open(ctx, name, key, fi, info)
 		}
-		f.refc++
-		fsys.mu.Unlock()
+		return nil, res.Err
 	}
 
+	f := res.Val.(*file)
+	if !fsys.acquire(f) {
+		// retry, file is already closed
+		return fsys.open(ctx, name, key, fi, info)
+	}
+	info.Shared = res.Shared
 	return f, nil
 }
 
+// acquire takes a reference to f as returned by an open call.
+// The first caller takes over the reference of the open call,
+// others increment the reference count. It reports false if
+// the file is already closed.
+func (fsys *FS) acquire(f *file) bool {
+	f.shard.mu.Lock()
+	defer f.shard.mu.Unlock()
+	if !f.opened {
+		f.opened = true
+		f.opens.Add(1)
+		return true
+	}
+	if f.refc.Load() == 0 {
+		return false
+	}
+	f.refc.Add(1)
+	f.opens.Add(1)
+	return true
+}
+
+func isContextErr(err error) bool {
+	return errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
+
 // KeepLast enables a cache that keeps the last n recently
 // closed files open. If n <= 0, the cache is disabled.
+// KeepLast has no effect after Close.
 func (fsys *FS) KeepLast(n int) {
 	fsys.mu.Lock()
+	if fsys.closed.Load() {
+		fsys.mu.Unlock()
+		return
+	}
 	if n <= 0 {
 		// disable close cache by removing the reference while
 		// holding the lock and clearing the cache (and closing
 		// cached files on eviction) without holding the lock
-		cc := fsys.cache
-		fsys.cache = nil // disable sends on fsys.closer
-		close(fsys.closer)
-		fsys.closer = nil
+		cc, done := fsys.takeCache()
 		fsys.mu.Unlock()
-		// from now on just close files on cache eviction
-		cc.OnEvicted = func(key lru.Key, value interface{}) {
-			f := value.(*file)
-			f.File.Close()
+		if cc != nil {
+			fsys.debug("disable close cache", "")
+		}
+		for _, err := range fsys.clearCache(cc, done) {
+			fsys.closeError(err)
 		}
-		cc.Clear()
 		return
 	}
 
 	defer fsys.mu.Unlock()
 	if fsys.cache == nil {
-		fsys.cache = &lru.Cache{
-			MaxEntries: n,
-			OnEvicted: func(key lru.Key, value interface{}) {
-				// fsys.mu is held in this function
-				f := value.(*file)
-				if f.refc == 0 {
-					f.fsys.closer <- f
-				}
-			},
+		fsys.enableCache()
+		fsys.cache.maxEntries = n
+		fsys.debug("enable close cache", "", "size", n)
+		return
+	}
+
+	if fsys.cache.maxEntries < n {
+		fsys.cache.maxEntries = n
+		fsys.debug("resize close cache", "", "size", n)
+	}
+}
+
+// KeepBytes enables a cache that keeps recently closed
+// files open as long as their total size is at most n bytes.
+// The size of a file is taken from Stat when it's opened.
+// If n <= 0, the size limit is removed and, if no limit is
+// set by KeepLast, the cache is disabled. KeepBytes has no
+// effect after Close.
+func (fsys *FS) KeepBytes(n int64) {
+	fsys.mu.Lock()
+	if fsys.closed.Load() {
+		fsys.mu.Unlock()
+		return
+	}
+	if n <= 0 {
+		if fsys.cache != nil && fsys.cache.maxEntries == 0 {
+			cc, done := fsys.takeCache()
+			fsys.mu.Unlock()
+			fsys.debug("disable close cache", "")
+			for _, err := range fsys.clearCache(cc, done) {
+				fsys.closeError(err)
+			}
+			return
 		}
-		fsys.closer = make(chan *file, n)
-		go fsys.fileCloser()
+		if fsys.cache != nil {
+			fsys.cache.maxSize = 0
+		}
+		fsys.mu.Unlock()
 		return
 	}
 
-	if fsys.cache.MaxEntries < n {
-		fsys.cache.MaxEntries = n
+	defer fsys.mu.Unlock()
+	if fsys.cache == nil {
+		fsys.enableCache()
+	}
+	fsys.cache.maxSize = n
+	fsys.cache.trim()
+	fsys.debug("set close cache byte size", "", "bytes", n)
+}
+
+// enableCache enables the close cache without limits.
+// fsys.mu must be held.
+func (fsys *FS) enableCache() {
+	c := newCloser()
+	fsys.cache = newCloseCache(fsys.policy, fsys.newPolicy, fsys.partitions, func(f *file) {
+		// fsys.mu is held in this function
+		fsys.stats.evictions.Add(1)
+		fsys.debug("evict file", f.name)
+		fsys.evicting.Add(1)
+		c.add(f)
+	})
+	fsys.closer = c
+	go fsys.fileCloser(c)
+}
+
+// takeCache disables the close cache and stops the file closer.
+// It returns the cache and a channel that is closed when the file
+// closer is done. fsys.mu must be held.
+func (fsys *FS) takeCache() (*closeCache, <-chan struct{}) {
+	cc, c := fsys.cache, fsys.closer
+	if cc == nil {
+		return nil, nil
+	}
+	fsys.cache = nil // disable evictions to the closer
+	fsys.closer = nil
+	c.stop()
+	return cc, c.done
+}
+
+// clearCache waits for the file closer to be done and closes all
+// files in the cache returned by takeCache. fsys.mu must not be held.
+func (fsys *FS) clearCache(cc *closeCache, done <-chan struct{}) []error {
+	if cc == nil {
+		return nil
 	}
+	<-done
+	var errs []error
+	cc.clear(func(f *file) {
+		if err := f.close(); err != nil {
+			errs = append(errs, err)
+		}
+	})
+	return errs
+}
+
+// closer is a queue of evicted files that are closed in the
+// background by fileCloser. Files are added holding FS.mu,
+// so adding never blocks and hooks called while closing may
+// call back into the FS.
+type closer struct {
+	mu      sync.Mutex // protects files and stopped
+	files   []*file
+	stopped bool
+	wake    chan struct{} // signaled when files are added or stopped
+	done    chan struct{} // closed when fileCloser returns
+}
+
+func newCloser() *closer {
+	return &closer{
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+}
+
+// add queues f to be closed.
+func (c *closer) add(f *file) {
+	c.mu.Lock()
+	c.files = append(c.files, f)
+	c.mu.Unlock()
+	c.signal()
 }
 
-func (fsys *FS) fileCloser() {
-	for f := range fsys.closer {
-		f.close()
+// stop makes fileCloser return after closing the queued files.
+func (c *closer) stop() {
+	c.mu.Lock()
+	c.stopped = true
+	c.mu.Unlock()
+	c.signal()
+}
+
+func (c *closer) signal() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// take waits for queued files and returns them. It reports
+// false when the closer is stopped and no file is queued.
+func (c *closer) take() ([]*file, bool) {
+	for {
+		c.mu.Lock()
+		files, stopped := c.files, c.stopped
+		c.files = nil
+		c.mu.Unlock()
+		if len(files) > 0 {
+			return files, true
+		}
+		if stopped {
+			return nil, false
+		}
+		<-c.wake
+	}
+}
+
+func (fsys *FS) fileCloser(c *closer) {
+	defer close(c.done)
+	for {
+		files, ok := c.take()
+		if !ok {
+			return
+		}
+		for _, f := range files {
+			fsys.hooks.evict(f.name)
+			if err := f.close(); err != nil {
+				fsys.closeError(err)
+			}
+			fsys.evicting.Add(-1)
+			fsys.wakeIdle()
+		}
+	}
+}
+
+func (fsys *FS) isClosed() bool {
+	return fsys.closed.Load()
+}
+
+// Close disables the close cache, unpins all pinned files,
+// closes all unreferenced files
+// and stops the background goroutine used to close evicted files.
+// Files that are still referenced remain usable and are closed
+// when their last reference is closed; the returned error then
+// wraps ErrInUse. Errors from closing files are joined.
+//
+// After Close, Open returns an error wrapping fs.ErrClosed.
+func (fsys *FS) Close() error {
+	fsys.mu.Lock()
+	if fsys.closed.Load() {
+		fsys.mu.Unlock()
+		return fs.ErrClosed
+	}
+	fsys.closed.Store(true)
+	pins := fsys.pins
+	fsys.pins = nil
+	fsys.mu.Unlock()
+	fsys.leavePool()
+
+	var errs []error
+	for _, f := range pins {
+		if err := f.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	inUse := len(fsys.sharedFiles())
+	fsys.mu.Lock()
+	fsys.stopIdleCloser()
+	fsys.clearMetadata()
+	cc, done := fsys.takeCache()
+	fsys.mu.Unlock()
+
+	errs = append(errs, fsys.clearCache(cc, done)...)
+	if err := fsys.invalidateDirs(); err != nil {
+		errs = append(errs, err)
+	}
+	if inUse > 0 {
+		errs = append(errs, fmt.Errorf("%w: %d files still open", ErrInUse, inUse))
 	}
+	return errors.Join(errs...)
 }
 
 type file struct {
 	fs.File
-	fsys *FS
-	name string
-	refc int // protected by fsys.mu
-	read sync.Mutex
+	fsys   *FS
+	shard  *shard
+	name   string
+	key    string       // sharing key, see WithKeyFunc
+	size   int64        // from Stat, protected by fsys.mu
+	mod    time.Time    // from Stat, protected by fsys.mu
+	idle   time.Time    // added to close cache, protected by fsys.mu
+	since  time.Time    // opened on the underlying file system
+	opens  atomic.Int64 // number of opens served
+	refc   atomic.Int64 // changed to or from zero while holding shard.mu
+	opened bool         // reference of open call is taken, protected by shard.mu
+	stale  bool         // invalidated, protected by shard.mu
+	read   sync.Mutex   // serializes Read
+	pos    int64        // offset of Read, protected by read
+	swap   sync.RWMutex // guards replacing File on a stale handle
+	inline bool         // read into memory, immutable after open
+	prio   Priority     // immutable after open, see WithPriority
+	rmu    sync.Mutex   // protects reads
+	reads  map[readRange]*readCall
+
+	readSem  chan struct{} // limits reads, immutable after open
+	throttle RateLimiter   // immutable after open
+
+	smu   sync.Mutex // protects sites
+	sites map[*openSite]struct{}
+
+	// keys of the names of f shared by inode, protected by
+	// fsys.mu, see WithInodeSharing
+	links []string
+
+	flmu    sync.Mutex // protects flocked
+	flocked bool       // locked shared, see WithFileLocks
 }
 
 var _ fs.File = (*file)(nil)
 
+// handle returns the value handed out to callers of Open.
+func (f *file) handle() fs.File {
+	if f.fsys.leaks || f.fsys.stacks || f.fsys.strict {
+		return f.trackedHandle()
+	}
+	if f.isReaderAt() {
+		return &fileReaderAt{file: f}
+	}
+	return f
+}
+
 func (f *file) Read(b []byte) (int, error) {
 	f.read.Lock()
 	defer f.read.Unlock()
-	return f.File.Read(b)
+	f.swap.RLock()
+	ff := f.File
+	n, err := ff.Read(b)
+	f.swap.RUnlock()
+	if err != nil && isStaleHandle(err) && f.reopen(ff) == nil {
+		f.swap.RLock()
+		n, err = f.File.Read(b)
+		f.swap.RUnlock()
+	}
+	f.pos += int64(n)
+	f.fsys.breaker.report(f.name, err)
+	if werr := f.wait(context.Background(), n); werr != nil && (err == nil || err == io.EOF) {
+		err = werr
+	}
+	return n, err
+}
+
+// Unwrap returns the file opened on the underlying file
+// system, like an *os.File to use its file descriptor. The
+// file is shared: it must not be closed, and reading or
+// seeking changes the offset for all users of the file. It
+// is replaced by a newly opened file when its handle goes
+// stale. Unwrap is part of the stable API of the returned
+// files, test for it with an interface{ Unwrap() fs.File }.
+func (f *file) Unwrap() fs.File {
+	f.swap.RLock()
+	defer f.swap.RUnlock()
+	return f.File
+}
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	f.swap.RLock()
+	defer f.swap.RUnlock()
+	return f.File.Stat()
 }
 
 func (f *file) Close() error {
-	f.fsys.mu.Lock()
-	if f.refc == 0 {
-		f.fsys.mu.Unlock()
+	// release a reference that is not the last without locking
+	for n := f.refc.Load(); n > 1; n = f.refc.Load() {
+		if f.refc.CompareAndSwap(n, n-1) {
+			return nil
+		}
+	}
+	f.shard.mu.Lock()
+	if f.refc.Load() == 0 {
+		f.shard.mu.Unlock()
 		return fs.ErrClosed
 	}
-	f.refc--
-	if f.refc < 0 {
-		panic("negative reference count")
+	refc := f.refc.Add(-1)
+	if refc < 0 {
+		f.refc.Store(0)
+		f.shard.mu.Unlock()
+		return f.negativeRefCount(refc)
 	}
-	if f.refc == 0 {
+	if refc == 0 {
 		closeFile := true
-		if f.fsys.cache != nil {
-			f.fsys.cache.Add(f.name, f)
+		f.fsys.mu.Lock()
+		if f.fsys.cache != nil && !f.stale {
+			f.idle = time.Now()
+			f.fsys.cache.add(f)
 			closeFile = false
 		}
-		delete(f.fsys.files, f.name)
 		f.fsys.mu.Unlock()
+		f.fsys.unshare(f)
 		if !closeFile {
+			f.unlock()
+			f.shard.mu.Unlock()
 			return nil
 		}
+		f.shard.mu.Unlock()
 		return f.close()
 	}
-	f.fsys.mu.Unlock()
+	f.shard.mu.Unlock()
 	return nil
 }
 
+// unshare removes f from the shared files. The shard of f
+// must be locked.
+func (fsys *FS) unshare(f *file) {
+	f.shard.files.CompareAndDelete(f.key, f)
+	fsys.wakeIdle()
+}
+
 func (f *file) close() error {
+	f.fsys.unlink(f)
+	f.fsys.adviseClose(f.name, f.File)
 	err := f.File.Close()
+	if err != nil {
+		err = &fs.PathError{Op: "close", Path: f.name, Err: err}
+	}
+	if f.inline {
+		f.fsys.releaseInline(f)
+	} else {
+		f.fsys.limiter().release()
+	}
+	f.fsys.hooks.close(f.name, err)
 	f.File = nil // panic on use after close
 	return err
 }
 
 type fileReaderAt struct {
 	*file
-	io.ReaderAt
 	offset int64
+	seq    sequential
+
+	tracked bool // see WithLeakDetection
+	site    *openSite
+	cleanup cleanup
+	closed  atomic.Pointer[closeSite] // see WithStrictClose
 }
 
 var (
 	_ fs.File     = (*fileReaderAt)(nil)
 	_ io.ReaderAt = (*fileReaderAt)(nil)
 	_ io.Seeker   = (*fileReaderAt)(nil)
+
+	_ ReaderAtContext = (*fileReaderAt)(nil)
 )
 
+// Close releases the reference to the shared file, later
+// calls return fs.ErrClosed.
+func (f *fileReaderAt) Close() error {
+	cs := f.closing()
+	if cs == nil {
+		cs = &closeSite{} // not recorded
+	}
+	if !f.closed.CompareAndSwap(nil, cs) {
+		return f.closed.Load().again()
+	}
+	if f.tracked {
+		stopCleanup(f, f.cleanup)
+		f.untrack(f.site)
+	}
+	return f.file.Close()
+}
+
+// errClosed returns the error of op on f if it's closed.
+func (f *fileReaderAt) errClosed(op string) error {
+	if f.closed.Load() == nil {
+		return nil
+	}
+	return &fs.PathError{Op: op, Path: f.name, Err: fs.ErrClosed}
+}
+
+func (f *fileReaderAt) Stat() (fs.FileInfo, error) {
+	if err := f.errClosed("stat"); err != nil {
+		return nil, err
+	}
+	return f.file.Stat()
+}
+
+func (f *fileReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	return f.ReadAtContext(context.Background(), p, off)
+}
+
+func (f *fileReaderAt) ReadAtContext(ctx context.Context, p []byte, off int64) (int, error) {
+	if err := f.errClosed("read"); err != nil {
+		return 0, err
+	}
+	n, err := f.file.readAt(ctx, p, off)
+	f.readahead(off, n)
+	if f.fsys.tracer != nil {
+		f.fsys.tracer.Read(ctx, f.name, off, n, err)
+	}
+	return n, err
+}
+
 func (f *fileReaderAt) Read(p []byte) (n int, err error) {
 	n, err = f.ReadAt(p, f.offset)
 	f.offset += int64(n)
@@ -1,12 +1,24 @@
 package singleopen
 
 import (
+	"context"
+	"errors"
+	"io"
 	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"testing/fstest"
+	"time"
 )
 
 func TestFS(t *testing.T) {
+	fsys := &FS{FS: fstest.MapFS{
+		"sub/dir/file1": &fstest.MapFile{},
+	}}
 	mustOpen := func(fsys fs.FS, name string) fs.File {
 		t.Helper()
 		f, err := fsys.Open(name)
@@ -22,9 +34,9 @@ func TestFS(t *testing.T) {
 			t.Fatal(err)
 		}
 	}
-	wantRefCount := func(f fs.File, want int) {
+	wantRefCount := func(want int) {
 		t.Helper()
-		got := f.(*fileReaderAt).refc
+		got := fsys.RefCount("sub/dir/file1")
 		if got != want {
 			t.Errorf("got ref count %d, want: %d", got, want)
 		}
@@ -33,9 +45,6 @@ func TestFS(t *testing.T) {
 		return f1.(*fileReaderAt).file == f2.(*fileReaderAt).file
 	}
 
-	fsys := &FS{FS: fstest.MapFS{
-		"sub/dir/file1": &fstest.MapFile{},
-	}}
 	if err := fstest.TestFS(fsys, "sub/dir/file1"); err != nil {
 		t.Fatal(err)
 	}
@@ -43,7 +52,7 @@ func TestFS(t *testing.T) {
 	f1 := mustOpen(fsys, "sub/dir/file1")
 	// t.Fatal("\n" + spew.Sdump(f1))
 	f2 := mustOpen(fsys, "sub/dir/file1")
-	wantRefCount(f1, 2)
+	wantRefCount(2)
 	if !sameFile(f1, f2) {
 		t.Error("f1 != f2")
 	}
@@ -51,7 +60,7 @@ func TestFS(t *testing.T) {
 	fsys.KeepLast(8)
 	mustClose(f1)
 	mustClose(f2)
-	wantRefCount(f1, 0)
+	wantRefCount(0)
 
 	sub, err := fs.Sub(fsys, "sub")
 	if err != nil {
@@ -65,7 +74,7 @@ func TestFS(t *testing.T) {
 	if !sameFile(f3, f4) {
 		t.Error("f3 != f4")
 	}
-	wantRefCount(f3, 2)
+	wantRefCount(2)
 	mustClose(f3)
 
 	subdir, err := fs.Sub(sub, "dir")
@@ -74,7 +83,7 @@ func TestFS(t *testing.T) {
 	}
 	f5 := mustOpen(subdir, "file1")
 	f6 := mustOpen(subdir, "file1")
-	wantRefCount(f3, 3)
+	wantRefCount(3)
 	if !sameFile(f5, f6) {
 		t.Error("f5 != f6")
 	}
@@ -87,9 +96,278 @@ func TestFS(t *testing.T) {
 	mustClose(f4)
 	mustClose(f5)
 	mustClose(f6)
-	wantRefCount(f3, 0)
+	wantRefCount(0)
 
 	if f3.Close() != fs.ErrClosed {
 		t.Error("file is not closed")
 	}
 }
+
+func TestClosedHandle(t *testing.T) {
+	fsys := &FS{FS: fstest.MapFS{
+		"file1": &fstest.MapFile{Data: []byte("file1")},
+		"file2": &fstest.MapFile{Data: []byte("file2")},
+	}}
+	f1, err := fsys.Open("file1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f2, err := fsys.Open("file2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f2.Close()
+
+	p := make([]byte, 5)
+	if _, err := f1.(io.ReaderAt).ReadAt(p, 0); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("got %v reading closed file, want ErrClosed", err)
+	}
+	if _, err := f1.Read(p); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("got %v reading closed file, want ErrClosed", err)
+	}
+	if err := f1.Close(); err != fs.ErrClosed {
+		t.Errorf("got %v on second close, want ErrClosed", err)
+	}
+	if n := fsys.RefCount("file2"); n != 1 {
+		t.Errorf("got ref count %d of other file, want 1", n)
+	}
+	if n, err := f2.Read(p); err != nil || string(p[:n]) != "file2" {
+		t.Errorf("got %q, %v, want file2", p[:n], err)
+	}
+}
+
+func TestFSClose(t *testing.T) {
+	fsys, err := New(fstest.MapFS{
+		"file1": &fstest.MapFile{},
+		"file2": &fstest.MapFile{},
+	}, WithKeepLast(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f1, err := fsys.Open("file1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f2, err := fsys.Open("file2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f1.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	err = fsys.Close()
+	if !errors.Is(err, ErrInUse) {
+		t.Errorf("got error %v, want ErrInUse", err)
+	}
+	if fsys.cache != nil || fsys.closer != nil {
+		t.Error("close cache not disabled")
+	}
+	if err := f2.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.Open("file1"); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("got error %v, want fs.ErrClosed", err)
+	}
+	if err := fsys.Close(); err != fs.ErrClosed {
+		t.Errorf("got error %v on second close, want fs.ErrClosed", err)
+	}
+}
+
+// blockFS blocks Open until release is closed.
+type blockFS struct {
+	fs.FS
+	release chan struct{}
+}
+
+func (b blockFS) Open(name string) (fs.File, error) {
+	<-b.release
+	return b.FS.Open(name)
+}
+
+func TestOpenContext(t *testing.T) {
+	bfs := blockFS{
+		FS:      fstest.MapFS{"file": &fstest.MapFile{}},
+		release: make(chan struct{}),
+	}
+	fsys := &FS{FS: bfs}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := fsys.OpenContext(ctx, "file")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want context.Canceled", err)
+	}
+
+	close(bfs.release)
+	f, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	// the abandoned open releases its reference in the background
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := fsys.WaitIdle(ctx); err != nil {
+		t.Errorf("abandoned open is not released: %v", err)
+	}
+}
+
+// countFS fails opens with EMFILE if max files are open.
+type countFS struct {
+	fs.FS
+	mu  sync.Mutex
+	n   int
+	max int
+}
+
+func (c *countFS) Open(name string) (fs.File, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.n >= c.max {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: syscall.EMFILE}
+	}
+	f, err := c.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	c.n++
+	return countFile{f, c}, nil
+}
+
+type countFile struct {
+	fs.File
+	c *countFS
+}
+
+func (f countFile) ReadAt(p []byte, off int64) (int, error) {
+	return f.File.(io.ReaderAt).ReadAt(p, off)
+}
+
+func (f countFile) Close() error {
+	f.c.mu.Lock()
+	f.c.n--
+	f.c.mu.Unlock()
+	return f.File.Close()
+}
+
+// flakyFS fails the first n opens.
+type flakyFS struct {
+	fs.FS
+	n     atomic.Int32
+	opens atomic.Int32
+}
+
+func (f *flakyFS) Open(name string) (fs.File, error) {
+	if f.opens.Add(1) <= f.n.Load() {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: syscall.EIO}
+	}
+	return f.FS.Open(name)
+}
+
+func BenchmarkOpenHit(b *testing.B) {
+	fsys := &FS{FS: fstest.MapFS{"file": &fstest.MapFile{}}}
+	f, err := fsys.Open("file")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer f.Close()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			f, err := fsys.Open("file")
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			f.Close()
+		}
+	})
+}
+
+func TestUnwrap(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fsys := &FS{FS: os.DirFS(dir)}
+	f, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	u, ok := f.(interface{ Unwrap() fs.File })
+	if !ok {
+		t.Fatalf("%T has no Unwrap", f)
+	}
+	of, ok := u.Unwrap().(*os.File)
+	if !ok {
+		t.Fatalf("got %T, want *os.File", u.Unwrap())
+	}
+	if of.Name() != filepath.Join(dir, "file") {
+		t.Errorf("got %s", of.Name())
+	}
+	if got, ok := osFile(f); !ok || got != of {
+		t.Error("osFile does not unwrap")
+	}
+}
+
+// slowFS returns files whose reads are counted and wait until
+// release is closed.
+type slowFS struct {
+	fs.FS
+	started chan struct{} // receives when a read starts
+	release chan struct{} // closed to let reads complete
+	reads   atomic.Int32
+	active  atomic.Int32 // reads in progress
+	peak    atomic.Int32 // maximum of active
+}
+
+func newSlowFS(fsys fs.FS, reads int) *slowFS {
+	return &slowFS{
+		FS:      fsys,
+		started: make(chan struct{}, reads),
+		release: make(chan struct{}),
+	}
+}
+
+func (s *slowFS) Open(name string) (fs.File, error) {
+	f, err := s.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &slowFile{f, s}, nil
+}
+
+type slowFile struct {
+	fs.File
+	s *slowFS
+}
+
+func (f *slowFile) ReadAt(p []byte, off int64) (int, error) {
+	f.s.reads.Add(1)
+	n := f.s.active.Add(1)
+	defer f.s.active.Add(-1)
+	for m := f.s.peak.Load(); n > m && !f.s.peak.CompareAndSwap(m, n); m = f.s.peak.Load() {
+	}
+	f.s.started <- struct{}{}
+	<-f.s.release
+	return f.File.(io.ReaderAt).ReadAt(p, off)
+}
+
+// streamFS hides io.ReaderAt of the files of FS.
+type streamFS struct {
+	fs.FS
+}
+
+func (s streamFS) Open(name string) (fs.File, error) {
+	f, err := s.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return struct{ fs.File }{f}, nil
+}
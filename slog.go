@@ -0,0 +1,29 @@
+//go:build go1.21
+
+package singleopen
+
+import (
+	"errors"
+	"log/slog"
+)
+
+// WithLogger returns an Option that logs opens, reuse of
+// files, evictions and changes to the close cache at debug
+// level to l. Errors from closing files in the background
+// are logged at warn level. It requires Go 1.21, before it
+// messages are logged by package log.
+func WithLogger(l *slog.Logger) Option {
+	return func(f *FS) error {
+		if l == nil {
+			return errors.New("singleopen: nil logger")
+		}
+		f.logger = l
+		return nil
+	}
+}
+
+// defaultLogger returns the logger of messages that are
+// logged without WithLogger.
+func defaultLogger() logger {
+	return slog.Default()
+}
@@ -0,0 +1,30 @@
+//go:build !go1.21
+
+package singleopen
+
+import (
+	"fmt"
+	"log"
+)
+
+// defaultLogger returns the logger of messages that are
+// logged without WithLogger.
+func defaultLogger() logger {
+	return stdLogger{}
+}
+
+// stdLogger logs to the standard logger in the format of the
+// text handler of log/slog.
+type stdLogger struct{}
+
+func (stdLogger) Debug(msg string, args ...any) { stdLog("DEBUG", msg, args) }
+func (stdLogger) Warn(msg string, args ...any)  { stdLog("WARN", msg, args) }
+func (stdLogger) Error(msg string, args ...any) { stdLog("ERROR", msg, args) }
+
+func stdLog(level, msg string, args []any) {
+	s := level + " " + msg
+	for i := 0; i+1 < len(args); i += 2 {
+		s += fmt.Sprintf(" %v=%v", args[i], args[i+1])
+	}
+	log.Print(s)
+}
@@ -0,0 +1,61 @@
+package singleopen
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// ErrReading is returned (wrapped) by OpenFile and OpenAppend
+// when the file has readers, see WithSnapshotReads.
+var ErrReading = errors.New("singleopen: file is open for reading")
+
+// WithSnapshotReads returns an Option that makes a reader see
+// the file as it was opened until it's closed. A shared file
+// that has readers can't be opened for writing by OpenFile or
+// OpenAppend, and Open of a file that's open for writing
+// fails with an error wrapping ErrWriting. WriteFileAtomic
+// replaces files with readers, they keep reading the old file.
+// Files that are not shared are not tracked, see WithMinSize
+// and WithReuseFilter.
+func WithSnapshotReads() Option {
+	return func(f *FS) error {
+		f.snapshots = true
+		return nil
+	}
+}
+
+// hasReaders reports whether the shared file of name has
+// readers.
+func (fsys *FS) hasReaders(name string) bool {
+	fsys.mu.Lock()
+	key := fsys.sharedKey(name)
+	fsys.mu.Unlock()
+	f, ok := fsys.shard(key).load(key)
+	return ok && f.refc.Load() > 0
+}
+
+// checkReaders returns an error if the file name to be written
+// has readers. The caller must be the writer of name, so a
+// concurrent Open sees it, see checkWriter.
+func (fsys *FS) checkReaders(name string) error {
+	if fsys.snapshots && fsys.hasReaders(name) {
+		return &fs.PathError{Op: "open", Path: name, Err: ErrReading}
+	}
+	return nil
+}
+
+// checkWriter returns an error if the shared file f, which the
+// caller has a reference to, has a writer.
+func (fsys *FS) checkWriter(f *file) error {
+	if !fsys.snapshots {
+		return nil
+	}
+	key := fsys.key(f.name)
+	fsys.mu.Lock()
+	_, ok := fsys.writers[key]
+	fsys.mu.Unlock()
+	if ok {
+		return &fs.PathError{Op: "open", Path: f.name, Err: ErrWriting}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+//go:build go1.25
+
+package singleopen
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotReads(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+	fsys, err := New(RootFS(root), WithSnapshotReads())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	r, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.OpenFile("file", os.O_WRONLY|os.O_TRUNC, 0); !errors.Is(err, ErrReading) {
+		t.Errorf("writer with reader: got %v, want ErrReading", err)
+	}
+	if _, err := fsys.OpenAppend("file", 0o644); !errors.Is(err, ErrReading) {
+		t.Errorf("appender with reader: got %v, want ErrReading", err)
+	}
+	if err := fsys.WriteFileAtomic("file", strings.NewReader("new"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := io.ReadAll(r); err != nil || string(b) != "old" {
+		t.Errorf("got %q, %v from reader, want old", b, err)
+	}
+	r.Close()
+
+	w, err := fsys.OpenFile("file", os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		t.Fatalf("writer after reader closed: %v", err)
+	}
+	if _, err := fsys.Open("file"); !errors.Is(err, ErrWriting) {
+		t.Errorf("open with writer: got %v, want ErrWriting", err)
+	}
+	w.Close()
+	if b, err := fsys.ReadFile("file"); err != nil || string(b) != "" {
+		t.Errorf("got %q, %v after writer closed, want empty file", b, err)
+	}
+	if n := fsys.Stats().OpenFiles; n != 0 {
+		t.Errorf("got %d open files, want 0", n)
+	}
+}
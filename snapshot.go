@@ -0,0 +1,101 @@
+package singleopen
+
+import (
+	"sort"
+	"time"
+)
+
+// FileInfoSnapshot is the state of a shared or cached file at
+// the time of OpenFiles.
+type FileInfoSnapshot struct {
+	Name   string        `json:"name"`
+	Refs   int64         `json:"refs"`  // references, zero if cached
+	Opens  int64         `json:"opens"` // opens served
+	Size   int64         `json:"size"`
+	Cached bool          `json:"cached"` // in the close cache
+	Pinned bool          `json:"pinned"`
+	Inline bool          `json:"inline"`          // read into memory
+	Age    time.Duration `json:"age"`             // since opened
+	Idle   time.Duration `json:"idle,omitempty"`  // since cached
+	Sites  []OpenSite    `json:"sites,omitempty"` // see WithOpenStacks
+}
+
+// OpenFiles returns the state of the shared files and the
+// files in the close cache, sorted by name. Files may be
+// opened and closed concurrently, the result is a snapshot.
+func (fsys *FS) OpenFiles() []FileInfoSnapshot {
+	var shared []*file
+	fsys.shard("") // initialize
+	for i := range fsys.shards {
+		fsys.shards[i].each(func(f *file) {
+			shared = append(shared, f)
+		})
+	}
+	var files []FileInfoSnapshot
+	now := time.Now()
+	add := func(f *file, cached bool) {
+		_, pinned := fsys.pins[f.name]
+		s := FileInfoSnapshot{
+			Name:   f.name,
+			Refs:   f.refc.Load(),
+			Opens:  f.opens.Load(),
+			Size:   f.size,
+			Cached: cached,
+			Pinned: pinned,
+			Inline: f.inline,
+			Age:    now.Sub(f.since),
+			Sites:  f.openSites(),
+		}
+		if cached {
+			s.Idle = now.Sub(f.idle)
+		}
+		files = append(files, s)
+	}
+	fsys.mu.Lock()
+	for _, f := range shared {
+		add(f, false)
+	}
+	if fsys.cache != nil {
+		for _, f := range fsys.cache.files {
+			add(f, true)
+		}
+	}
+	fsys.mu.Unlock()
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+	return files
+}
+
+// Range calls fn for each shared file and then for each file
+// in the close cache, with its number of references, until fn
+// returns false. Files opened or closed concurrently may be
+// missed or seen twice. Range doesn't hold locks while calling
+// fn, fn may use fsys.
+func (fsys *FS) Range(fn func(name string, refc int, cached bool) bool) {
+	fsys.shard("") // initialize
+	for i := range fsys.shards {
+		stop := false
+		fsys.shards[i].files.Range(func(_, v any) bool {
+			f := v.(*file)
+			stop = !fn(f.name, int(f.refc.Load()), false)
+			return !stop
+		})
+		if stop {
+			return
+		}
+	}
+	fsys.mu.Lock()
+	var cached []string
+	if fsys.cache != nil {
+		for _, f := range fsys.cache.files {
+			cached = append(cached, f.name)
+		}
+	}
+	fsys.mu.Unlock()
+	for _, name := range cached {
+		if !fn(name, 0, true) {
+			return
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package singleopen
+
+import (
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestOpenFiles(t *testing.T) {
+	fsys, err := New(fstest.MapFS{
+		"a": &fstest.MapFile{},
+		"b": &fstest.MapFile{},
+		"c": &fstest.MapFile{},
+	}, WithKeepLast(2), WithShards(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var open []fs.File
+	for _, name := range []string{"c", "a", "a", "b"} {
+		f, err := fsys.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		open = append(open, f)
+	}
+	fsys.Pin("b")
+	files := fsys.OpenFiles()
+	if len(files) != 3 || files[0].Name != "a" || files[0].Refs != 2 || !files[1].Pinned {
+		t.Errorf("got %+v", files)
+	}
+
+	refs := make(map[string]int)
+	fsys.Range(func(name string, refc int, cached bool) bool {
+		refs[name] = refc
+		return true
+	})
+	if want := map[string]int{"a": 2, "b": 2, "c": 1}; !reflect.DeepEqual(refs, want) {
+		t.Errorf("got %v, want %v", refs, want)
+	}
+	n := 0
+	fsys.Range(func(string, int, bool) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Errorf("Range called fn %d times after it returned false", n)
+	}
+
+	open[0].Close() // c is cached
+	var cached []string
+	fsys.Range(func(name string, refc int, c bool) bool {
+		if c {
+			cached = append(cached, name)
+		}
+		return true
+	})
+	if !reflect.DeepEqual(cached, []string{"c"}) {
+		t.Errorf("got cached files %v, want c", cached)
+	}
+	for _, f := range open[1:] {
+		f.Close()
+	}
+}
@@ -0,0 +1,85 @@
+package singleopen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// WithSpool returns an Option that spools regular files that
+// don't implement io.ReaderAt once when they are opened, so
+// concurrent readers don't share a single offset. Files up to
+// maxMem bytes are spooled into memory, larger files into a
+// temporary file in dir, or os.TempDir if dir is empty. The
+// temporary file is removed when the shared file is closed.
+func WithSpool(dir string, maxMem int64) Option {
+	return func(f *FS) error {
+		if maxMem < 0 {
+			return fmt.Errorf("singleopen: invalid spool memory size %d", maxMem)
+		}
+		f.spool = true
+		f.spoolDir = dir
+		f.spoolMem = maxMem
+		return nil
+	}
+}
+
+// spoolOpen replaces the file of f by its spool if it needs
+// to be spooled. The file of f is closed when it's spooled.
+func (fsys *FS) spoolOpen(f *file, fi fs.FileInfo) error {
+	if _, ok := f.File.(io.ReaderAt); ok || fi == nil || !fi.Mode().IsRegular() {
+		return nil
+	}
+	sf, err := fsys.spoolFile(f.File, fi)
+	if err != nil {
+		return &fs.PathError{Op: "open", Path: f.name, Err: err}
+	}
+	if err := f.File.Close(); err != nil {
+		fsys.closeError(&fs.PathError{Op: "close", Path: f.name, Err: err})
+	}
+	f.File = sf
+	fsys.debug("spool file", f.name, "size", fi.Size())
+	return nil
+}
+
+// spoolFile reads r into memory or, when it exceeds the
+// memory size, into a temporary file.
+func (fsys *FS) spoolFile(r io.Reader, fi fs.FileInfo) (fs.File, error) {
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, io.LimitReader(r, fsys.spoolMem+1))
+	if err != nil {
+		return nil, err
+	}
+	if n <= fsys.spoolMem {
+		return &memFile{Reader: bytes.NewReader(buf.Bytes()), fi: fi}, nil
+	}
+	tmp, err := os.CreateTemp(fsys.spoolDir, "singleopen-*")
+	if err != nil {
+		return nil, err
+	}
+	sf := &spoolFile{File: tmp, fi: fi}
+	if _, err := buf.WriteTo(tmp); err != nil {
+		return nil, errors.Join(err, sf.Close())
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		return nil, errors.Join(err, sf.Close())
+	}
+	return sf, nil
+}
+
+// spoolFile is a file spooled into a temporary file.
+type spoolFile struct {
+	*os.File
+	fi fs.FileInfo
+}
+
+func (f *spoolFile) Stat() (fs.FileInfo, error) {
+	return f.fi, nil
+}
+
+func (f *spoolFile) Close() error {
+	return errors.Join(f.File.Close(), os.Remove(f.File.Name()))
+}
@@ -0,0 +1,63 @@
+package singleopen
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dwlnetnl/singleopen/internal/compat"
+)
+
+func TestSpool(t *testing.T) {
+	large := bytes.Repeat([]byte("large"), 100)
+	sfs := streamFS{fstest.MapFS{
+		"small": &fstest.MapFile{Data: []byte("small")},
+		"large": &fstest.MapFile{Data: large},
+	}}
+	fsys, err := New(sfs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.OpenReaderAt("small"); !errors.Is(err, compat.ErrUnsupported) {
+		t.Errorf("got %v, want ErrUnsupported without spool", err)
+	}
+
+	dir := t.TempDir()
+	fsys, err = New(sfs, WithSpool(dir, 8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string][]byte{"small": []byte("small"), "large": large} {
+		f1, err := fsys.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f2, err := fsys.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// readers have their own offset
+		for _, f := range []fs.File{f1, f2} {
+			data, err := io.ReadAll(f)
+			if err != nil || !bytes.Equal(data, want) {
+				t.Errorf("%s: got %q, %v, want %q", name, data, err, want)
+			}
+		}
+		fi, err := f1.Stat()
+		if err != nil || fi.Name() != name || fi.Size() != int64(len(want)) {
+			t.Errorf("%s: got stat %v, %v", name, fi, err)
+		}
+		f1.Close()
+		f2.Close()
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("got %d spool files left, want 0", len(entries))
+	}
+	if _, err := New(sfs, WithSpool("", -1)); err == nil {
+		t.Error("expected error for invalid memory size")
+	}
+}
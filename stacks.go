@@ -0,0 +1,130 @@
+package singleopen
+
+import (
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WithOpenStacks returns an Option that records the stack of
+// every Open until the returned file is closed. The stacks
+// of files that are not closed yet are reported by OpenFiles
+// and DebugHandler, to find the code that forgets to close a
+// file. Recording a stack is expensive, it's meant for
+// debugging.
+func WithOpenStacks() Option {
+	return func(f *FS) error {
+		f.stacks = true
+		return nil
+	}
+}
+
+// OpenSite is a stack of Open calls whose files are not
+// closed yet, see WithOpenStacks.
+type OpenSite struct {
+	Stack string `json:"stack"`
+	Count int    `json:"count"` // files opened at Stack
+}
+
+// openSite is the stack of an Open or Close call.
+type openSite struct {
+	pcs []uintptr
+}
+
+// callers returns the stack of the caller of the package,
+// skipping the frames inside the package.
+func callers() *openSite {
+	pcs := make([]uintptr, 64)
+	pcs = pcs[:runtime.Callers(2, pcs)]
+	frames := runtime.CallersFrames(pcs)
+	skip := 0
+	for {
+		fr, more := frames.Next()
+		name, ok := strings.CutPrefix(fr.Function, pkgPrefix)
+		if !ok || name == "" || name[0] != '(' && !isLower(name[0]) {
+			break
+		}
+		skip++
+		if !more {
+			break
+		}
+	}
+	if skip > len(pcs) {
+		skip = len(pcs)
+	}
+	return &openSite{pcs: pcs[skip:]}
+}
+
+const pkgPrefix = "github.com/dwlnetnl/singleopen."
+
+// track records the stack of the caller of Open as an open
+// site of f. It returns nil if stacks aren't recorded.
+func (f *file) track() *openSite {
+	if !f.fsys.stacks {
+		return nil
+	}
+	s := callers()
+	f.smu.Lock()
+	if f.sites == nil {
+		f.sites = make(map[*openSite]struct{})
+	}
+	f.sites[s] = struct{}{}
+	f.smu.Unlock()
+	return s
+}
+
+// untrack removes the open site s of f.
+func (f *file) untrack(s *openSite) {
+	if s == nil {
+		return
+	}
+	f.smu.Lock()
+	delete(f.sites, s)
+	f.smu.Unlock()
+}
+
+// openSites returns the open sites of f, most frequent first.
+func (f *file) openSites() []OpenSite {
+	f.smu.Lock()
+	counts := make(map[string]int, len(f.sites))
+	for s := range f.sites {
+		counts[s.String()]++
+	}
+	f.smu.Unlock()
+	var sites []OpenSite
+	for stack, n := range counts {
+		sites = append(sites, OpenSite{Stack: stack, Count: n})
+	}
+	sort.Slice(sites, func(i, j int) bool {
+		if sites[i].Count != sites[j].Count {
+			return sites[i].Count > sites[j].Count
+		}
+		return sites[i].Stack < sites[j].Stack
+	})
+	return sites
+}
+
+// String formats the stack like a goroutine trace.
+func (s *openSite) String() string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(s.pcs)
+	for {
+		fr, more := frames.Next()
+		if fr.Function != "" {
+			b.WriteString(fr.Function)
+			b.WriteString("\n\t")
+			b.WriteString(fr.File)
+			b.WriteByte(':')
+			b.WriteString(strconv.Itoa(fr.Line))
+			b.WriteByte('\n')
+		}
+		if !more {
+			return b.String()
+		}
+	}
+}
+
+func isLower(c byte) bool {
+	return 'a' <= c && c <= 'z'
+}
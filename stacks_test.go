@@ -0,0 +1,41 @@
+package singleopen
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestOpenStacks(t *testing.T) {
+	fsys, err := New(fstest.MapFS{
+		"file": &fstest.MapFile{Data: []byte("data")},
+	}, WithOpenStacks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []fs.File
+	for i := 0; i < 2; i++ {
+		f, err := fsys.Open("file")
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+	}
+	files[0].Close()
+	st := fsys.OpenFiles()
+	if len(st) != 1 || len(st[0].Sites) != 1 {
+		t.Fatalf("got %+v, want one open site", st)
+	}
+	site := st[0].Sites[0]
+	if site.Count != 1 {
+		t.Errorf("got count %d, want 1", site.Count)
+	}
+	if !strings.HasPrefix(site.Stack, "github.com/dwlnetnl/singleopen.TestOpenStacks\n") {
+		t.Errorf("stack doesn't start at caller of Open:\n%s", site.Stack)
+	}
+	files[1].Close()
+	if st := fsys.OpenFiles(); len(st) != 0 {
+		t.Errorf("got %+v after close, want none", st)
+	}
+}
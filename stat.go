@@ -0,0 +1,113 @@
+package singleopen
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/dwlnetnl/singleopen/cache"
+)
+
+var (
+	_ fs.StatFS     = (*FS)(nil)
+	_ StatContextFS = (*FS)(nil)
+)
+
+// StatContextFS is the interface implemented by a file system
+// that supports cancellation of Stat.
+type StatContextFS interface {
+	fs.FS
+
+	// StatContext returns a FileInfo describing the named
+	// file. The context is used to cancel the call.
+	StatContext(ctx context.Context, name string) (fs.FileInfo, error)
+}
+
+// maxStats is the maximum number of cached results of Stat.
+const maxStats = 4096
+
+// statEntry is a cached result of Stat.
+type statEntry struct {
+	fi      fs.FileInfo
+	expires time.Time
+}
+
+// WithStatCache returns an Option that caches the results
+// of Stat, including those of the Stat calls done by Open,
+// for ttl. At most 4096 results are cached, the least recently
+// used are dropped first.
+func WithStatCache(ttl time.Duration) Option {
+	return func(f *FS) error {
+		if ttl <= 0 {
+			return fmt.Errorf("singleopen: invalid stat cache ttl %v", ttl)
+		}
+		f.statTTL = ttl
+		return nil
+	}
+}
+
+// Stat returns a FileInfo describing the named file. Stat
+// delegates to the underlying file system and caches the
+// result if enabled with WithStatCache.
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	return fsys.StatContext(context.Background(), name)
+}
+
+// StatContext is like Stat but passes ctx to the underlying
+// file system if it implements StatContextFS.
+func (fsys *FS) StatContext(ctx context.Context, name string) (fs.FileInfo, error) {
+	if fsys.isClosed() {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrClosed}
+	}
+	if fi, ok := fsys.cachedStat(name); ok {
+		return fi, nil
+	}
+	fsys.mu.Lock()
+	missing := fsys.isMissing(name)
+	fsys.mu.Unlock()
+	if missing {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	fi, err := fsys.statBase(ctx, name)
+	if err != nil {
+		fsys.storeMissing(name, err)
+		return nil, err
+	}
+	fsys.storeStat(name, fi)
+	return fi, nil
+}
+
+// cachedStat returns the cached file info of name.
+func (fsys *FS) cachedStat(name string) (fs.FileInfo, bool) {
+	if fsys.statTTL == 0 {
+		return nil, false
+	}
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if fsys.statc == nil {
+		return nil, false
+	}
+	se, ok := fsys.statc.Get(name)
+	if !ok {
+		return nil, false
+	}
+	if !time.Now().Before(se.expires) {
+		fsys.statc.Remove(name)
+		return nil, false
+	}
+	return se.fi, true
+}
+
+// storeStat caches the file info of name.
+func (fsys *FS) storeStat(name string, fi fs.FileInfo) {
+	if fsys.statTTL == 0 {
+		return
+	}
+	fsys.mu.Lock()
+	if fsys.statc == nil {
+		fsys.statc = cache.New[string, statEntry](maxStats)
+	}
+	fsys.statc.Add(name, statEntry{fi, time.Now().Add(fsys.statTTL)})
+	fsys.mu.Unlock()
+}
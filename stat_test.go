@@ -0,0 +1,102 @@
+package singleopen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestStatCache(t *testing.T) {
+	mfs := fstest.MapFS{"file": &fstest.MapFile{Data: []byte("a")}}
+	fsys, err := New(mfs, WithStatCache(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	// stat result of open is reused
+	mfs["file"] = &fstest.MapFile{Data: []byte("abc")}
+	fi, err := fsys.Stat("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != 1 {
+		t.Errorf("got size %d, want cached size 1", fi.Size())
+	}
+
+	if _, err := fsys.Stat("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got error %v, want fs.ErrNotExist", err)
+	}
+}
+
+// statCtxFS implements StatContextFS but not fs.StatFS.
+type statCtxFS struct {
+	fs   fstest.MapFS
+	ctxs chan context.Context
+}
+
+func (s statCtxFS) Open(name string) (fs.File, error) {
+	return s.fs.Open(name)
+}
+
+func (s statCtxFS) StatContext(ctx context.Context, name string) (fs.FileInfo, error) {
+	s.ctxs <- ctx
+	return s.fs.Stat(name)
+}
+
+func TestStatContext(t *testing.T) {
+	type key struct{}
+	sfs := statCtxFS{
+		fs: fstest.MapFS{
+			"file": &fstest.MapFile{Data: []byte("data")},
+		},
+		ctxs: make(chan context.Context, 1),
+	}
+	fsys, err := New(sfs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.WithValue(context.Background(), key{}, "open")
+	f, err := fsys.OpenContext(ctx, "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if got := (<-sfs.ctxs).Value(key{}); got != "open" {
+		t.Errorf("open passed context with %v, want open", got)
+	}
+	ctx = context.WithValue(context.Background(), key{}, "stat")
+	if _, err := fsys.StatContext(ctx, "file"); err != nil {
+		t.Fatal(err)
+	}
+	if got := (<-sfs.ctxs).Value(key{}); got != "stat" {
+		t.Errorf("stat passed context with %v, want stat", got)
+	}
+}
+
+func TestStatCacheLimit(t *testing.T) {
+	mfs := fstest.MapFS{}
+	for i := 0; i < maxStats+10; i++ {
+		mfs[fmt.Sprint("file", i)] = &fstest.MapFile{}
+	}
+	fsys, err := New(mfs, WithStatCache(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name := range mfs {
+		if _, err := fsys.Stat(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := fsys.statc.Len(); n != maxStats {
+		t.Errorf("got %d cached stats, want %d", n, maxStats)
+	}
+}
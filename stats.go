@@ -0,0 +1,67 @@
+package singleopen
+
+import "sync/atomic"
+
+// Stats holds statistics of a FS.
+type Stats struct {
+	Opens     int64 // calls to Open
+	Hits      int64 // opens that reused a shared file
+	CacheHits int64 // opens that reused a file from the close cache
+	Misses    int64 // opens of files on the underlying file system
+	Evictions int64 // files evicted from the close cache
+	CloseErrs int64 // errors closing files in the background
+
+	OpenFiles  int   // open files on the underlying file system
+	Shared     int   // files that are referenced
+	Pinned     int   // files pinned by Pin
+	Cached     int   // files in the close cache
+	CachedSize int64 // total size of files in the close cache
+	RefCount   int   // total references to shared files
+	Inlined    int   // files read into memory, see WithInline
+	InlineSize int64 // total size of inlined files
+}
+
+// stats holds the counters of Stats.
+type stats struct {
+	opens     atomic.Int64
+	hits      atomic.Int64
+	cacheHits atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
+	closeErrs atomic.Int64
+
+	inlined    atomic.Int64
+	inlineSize atomic.Int64
+}
+
+// Stats returns statistics of fsys.
+func (fsys *FS) Stats() Stats {
+	s := Stats{
+		Opens:     fsys.stats.opens.Load(),
+		Hits:      fsys.stats.hits.Load(),
+		CacheHits: fsys.stats.cacheHits.Load(),
+		Misses:    fsys.stats.misses.Load(),
+		Evictions: fsys.stats.evictions.Load(),
+		CloseErrs: fsys.stats.closeErrs.Load(),
+
+		Inlined:    int(fsys.stats.inlined.Load()),
+		InlineSize: fsys.stats.inlineSize.Load(),
+	}
+	fsys.shard("") // initialize
+	for i := range fsys.shards {
+		sh := &fsys.shards[i]
+		sh.each(func(f *file) {
+			s.Shared++
+			s.RefCount += int(f.refc.Load())
+		})
+	}
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	s.Pinned = len(fsys.pins)
+	if fsys.cache != nil {
+		s.Cached = fsys.cache.len()
+		s.CachedSize = fsys.cache.size
+	}
+	s.OpenFiles = s.Shared + s.Cached
+	return s
+}
@@ -0,0 +1,37 @@
+package singleopen
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestStats(t *testing.T) {
+	fsys, err := New(fstest.MapFS{
+		"file1": &fstest.MapFile{},
+		"file2": &fstest.MapFile{},
+	}, WithKeepLast(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	f1, _ := fsys.Open("file1")
+	f2, _ := fsys.Open("file1")
+	got := fsys.Stats()
+	want := Stats{Opens: 2, Hits: 1, Misses: 1, OpenFiles: 1, Shared: 1, RefCount: 2}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	f1.Close()
+	f2.Close()
+	f1, _ = fsys.Open("file1")
+	f1.Close()
+	f2, _ = fsys.Open("file2")
+	f2.Close() // evicts file1
+	got = fsys.Stats()
+	want = Stats{Opens: 4, Hits: 1, CacheHits: 1, Misses: 2, Evictions: 1, OpenFiles: 1, Cached: 1}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
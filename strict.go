@@ -0,0 +1,46 @@
+package singleopen
+
+import "io/fs"
+
+// WithStrictClose returns an Option that records the stack of
+// the first Close of every file returned by Open. Closing a
+// file again is reported to Hooks.OnDoubleClose with the
+// stacks of both Close calls and logged at warn level, besides
+// returning fs.ErrClosed.
+func WithStrictClose() Option {
+	return func(f *FS) error {
+		f.strict = true
+		return nil
+	}
+}
+
+// closeSite is the first Close of a handle.
+type closeSite struct {
+	fsys  *FS
+	name  string
+	stack *openSite // nil if not recorded
+}
+
+// closing returns the site of the caller of Close, or nil
+// if close sites aren't recorded.
+func (f *file) closing() *closeSite {
+	if !f.fsys.strict {
+		return nil
+	}
+	return &closeSite{fsys: f.fsys, name: f.name, stack: callers()}
+}
+
+// again reports another Close of the handle first closed at
+// cs and returns fs.ErrClosed.
+func (cs *closeSite) again() error {
+	if cs == nil || cs.stack == nil {
+		return fs.ErrClosed
+	}
+	first, second := cs.stack.String(), callers().String()
+	cs.fsys.hooks.doubleClose(cs.name, first, second)
+	if cs.fsys.logger != nil {
+		cs.fsys.logger.Warn("singleopen: file is closed twice",
+			"name", cs.name, "first", first, "second", second)
+	}
+	return fs.ErrClosed
+}
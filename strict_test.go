@@ -0,0 +1,49 @@
+package singleopen
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestStrictClose(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		fsys fs.FS
+	}{
+		{"ReaderAt", fstest.MapFS{"file": &fstest.MapFile{}}},
+		{"Reader", streamFS{fstest.MapFS{"file": &fstest.MapFile{}}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var first, second string
+			fsys, err := New(tt.fsys, WithStrictClose(), WithHooks(Hooks{
+				OnDoubleClose: func(name, f, s string) { first, second = f, s },
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := fsys.Open("file")
+			if err != nil {
+				t.Fatal(err)
+			}
+			closeFirst := func() error { return f.Close() }
+			if err := closeFirst(); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.Close(); !errors.Is(err, fs.ErrClosed) {
+				t.Errorf("got %v on second close, want ErrClosed", err)
+			}
+			if !strings.HasPrefix(first, "github.com/dwlnetnl/singleopen.TestStrictClose.func1.") {
+				t.Errorf("first close stack doesn't start at caller:\n%s", first)
+			}
+			if !strings.HasPrefix(second, "github.com/dwlnetnl/singleopen.TestStrictClose.func1\n") {
+				t.Errorf("second close stack doesn't start at caller:\n%s", second)
+			}
+			if n := fsys.RefCount("file"); n != 0 {
+				t.Errorf("got %d references, want 0", n)
+			}
+		})
+	}
+}
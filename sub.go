@@ -0,0 +1,193 @@
+package singleopen
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// SubFS is a view of a subtree of a FS. It shares the open
+// files and the close cache with the FS it is created from.
+type SubFS struct {
+	fsys *FS
+	dir  string
+}
+
+var (
+	_ fs.SubFS      = (*FS)(nil)
+	_ fs.FS         = (*SubFS)(nil)
+	_ fs.ReadDirFS  = (*SubFS)(nil)
+	_ fs.ReadFileFS = (*SubFS)(nil)
+	_ fs.StatFS     = (*SubFS)(nil)
+	_ fs.GlobFS     = (*SubFS)(nil)
+	_ fs.SubFS      = (*SubFS)(nil)
+)
+
+// Sub returns a SubFS corresponding to the subtree rooted
+// at dir.
+func (fsys *FS) Sub(dir string) (fs.FS, error) {
+	if !fs.ValidPath(dir) {
+		return nil, &fs.PathError{Op: "sub", Path: dir, Err: fs.ErrInvalid}
+	}
+	if dir == "." {
+		return fsys, nil
+	}
+	return &SubFS{fsys, dir}, nil
+}
+
+// FS returns the FS the subtree belongs to.
+func (s *SubFS) FS() *FS {
+	return s.fsys
+}
+
+// Stats returns the statistics of the FS the subtree
+// belongs to.
+func (s *SubFS) Stats() Stats {
+	return s.fsys.Stats()
+}
+
+// fullName maps name to the name in the parent FS.
+func (s *SubFS) fullName(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return path.Join(s.dir, name), nil
+}
+
+// shorten maps name in the parent FS to the name in s.
+func (s *SubFS) shorten(name string) (string, bool) {
+	if name == s.dir {
+		return ".", true
+	}
+	if len(name) > len(s.dir) && name[len(s.dir)] == '/' && strings.HasPrefix(name, s.dir) {
+		return name[len(s.dir)+1:], true
+	}
+	return "", false
+}
+
+// fixErr shortens any reported names in PathErrors.
+func (s *SubFS) fixErr(err error) error {
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		if short, ok := s.shorten(pe.Path); ok {
+			pe.Path = short
+		}
+	}
+	return err
+}
+
+// Open opens the named file, see (*FS).Open.
+func (s *SubFS) Open(name string) (fs.File, error) {
+	return s.OpenContext(context.Background(), name)
+}
+
+// OpenContext opens the named file, see (*FS).OpenContext.
+func (s *SubFS) OpenContext(ctx context.Context, name string) (fs.File, error) {
+	full, err := s.fullName("open", name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := s.fsys.OpenContext(ctx, full)
+	return f, s.fixErr(err)
+}
+
+// ReadDir reads the named directory, see (*FS).ReadDir.
+func (s *SubFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	full, err := s.fullName("read", name)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.fsys.ReadDir(full)
+	return entries, s.fixErr(err)
+}
+
+// ReadFile reads the named file, see (*FS).ReadFile.
+func (s *SubFS) ReadFile(name string) ([]byte, error) {
+	full, err := s.fullName("read", name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.fsys.ReadFile(full)
+	return data, s.fixErr(err)
+}
+
+// ReadAt reads from the named file at off, see (*FS).ReadAt.
+func (s *SubFS) ReadAt(name string, p []byte, off int64) (int, error) {
+	full, err := s.fullName("read", name)
+	if err != nil {
+		return 0, err
+	}
+	n, err := s.fsys.ReadAt(full, p, off)
+	return n, s.fixErr(err)
+}
+
+// OpenReaderAt opens the named file for positional reads,
+// see (*FS).OpenReaderAt.
+func (s *SubFS) OpenReaderAt(name string) (ReaderAtCloser, error) {
+	full, err := s.fullName("open", name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := s.fsys.OpenReaderAt(full)
+	if err != nil {
+		return nil, s.fixErr(err)
+	}
+	return f, nil
+}
+
+// OpenSection opens a view of part of the named file, see
+// (*FS).OpenSection.
+func (s *SubFS) OpenSection(name string, off, n int64) (*Section, error) {
+	full, err := s.fullName("open", name)
+	if err != nil {
+		return nil, err
+	}
+	sec, err := s.fsys.OpenSection(full, off, n)
+	return sec, s.fixErr(err)
+}
+
+// Stat returns a FileInfo of the named file, see (*FS).Stat.
+func (s *SubFS) Stat(name string) (fs.FileInfo, error) {
+	full, err := s.fullName("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := s.fsys.Stat(full)
+	return fi, s.fixErr(err)
+}
+
+// Glob returns the names of all files matching pattern,
+// see (*FS).Glob.
+func (s *SubFS) Glob(pattern string) ([]string, error) {
+	// check pattern is well-formed
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	if pattern == "." {
+		return []string{"."}, nil
+	}
+	full := s.dir + "/" + pattern
+	list, err := s.fsys.Glob(full)
+	for i, name := range list {
+		name, ok := s.shorten(name)
+		if !ok {
+			return nil, errors.New("invalid result from inner fsys Glob: " + name + " not in " + s.dir)
+		}
+		list[i] = name
+	}
+	return list, s.fixErr(err)
+}
+
+// Sub returns a view of the subtree rooted at dir.
+func (s *SubFS) Sub(dir string) (fs.FS, error) {
+	if dir == "." {
+		return s, nil
+	}
+	full, err := s.fullName("sub", dir)
+	if err != nil {
+		return nil, err
+	}
+	return &SubFS{s.fsys, full}, nil
+}
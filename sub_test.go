@@ -0,0 +1,49 @@
+package singleopen
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSub(t *testing.T) {
+	fsys := &FS{FS: fstest.MapFS{
+		"sub/dir/file1": &fstest.MapFile{Data: []byte("data")},
+		"sub/file2":     &fstest.MapFile{},
+	}}
+	sub, err := fsys.Sub("sub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sub.(*SubFS); !ok {
+		t.Fatalf("got %T, want *SubFS", sub)
+	}
+	if err := fstest.TestFS(sub, "dir/file1", "file2"); err != nil {
+		t.Fatal(err)
+	}
+
+	f1, err := fsys.Open("sub/dir/file1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f1.Close()
+	subdir, err := fs.Sub(sub, "dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f2, err := subdir.Open("file1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f2.Close()
+	if f1.(*fileReaderAt).file != f2.(*fileReaderAt).file {
+		t.Error("file is not shared with sub tree")
+	}
+
+	_, err = subdir.Open("missing")
+	var pe *fs.PathError
+	if !errors.As(err, &pe) || pe.Path != "missing" {
+		t.Errorf("got error %v, want path error for missing", err)
+	}
+}
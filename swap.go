@@ -0,0 +1,36 @@
+package singleopen
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// SwapFS replaces the underlying file system by newFS for
+// future opens. Files opened on the old file system remain
+// usable until they are closed. If invalidate is true, all
+// files are invalidated like InvalidateAll, so files of the
+// old file system are not reused either; otherwise shared and
+// cached files are reused until they are closed or evicted.
+// If fsys falls back, see WithFallback, newFS falls back too.
+func (fsys *FS) SwapFS(newFS fs.FS, invalidate bool) error {
+	if newFS == nil {
+		return errors.New("singleopen: nil file system")
+	}
+	if fb, ok := fsys.base().(*fallbackFS); ok {
+		newFS = &fallbackFS{FS: newFS, fallback: fb.fallback, retry: fb.retry}
+	}
+	fsys.swapped.Store(&newFS)
+	fsys.debug("swap file system", "")
+	if invalidate {
+		return fsys.InvalidateAll()
+	}
+	return nil
+}
+
+// base returns the underlying file system.
+func (fsys *FS) base() fs.FS {
+	if p := fsys.swapped.Load(); p != nil {
+		return *p
+	}
+	return fsys.FS
+}
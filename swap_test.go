@@ -0,0 +1,40 @@
+package singleopen
+
+import (
+	"io"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSwapFS(t *testing.T) {
+	fsys, err := New(fstest.MapFS{
+		"file": &fstest.MapFile{Data: []byte("old")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer old.Close()
+	newFS := fstest.MapFS{
+		"file": &fstest.MapFile{Data: []byte("new")},
+	}
+	if err := fsys.SwapFS(newFS, false); err != nil {
+		t.Fatal(err)
+	}
+	// shared file of the old file system is reused
+	if b, err := fsys.ReadFile("file"); err != nil || string(b) != "old" {
+		t.Errorf("got %q, %v before invalidation, want old", b, err)
+	}
+	if err := fsys.SwapFS(newFS, true); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := fsys.ReadFile("file"); err != nil || string(b) != "new" {
+		t.Errorf("got %q, %v after invalidation, want new", b, err)
+	}
+	if b, err := io.ReadAll(old); err != nil || string(b) != "old" {
+		t.Errorf("got %q, %v from old file, want old", b, err)
+	}
+}
@@ -0,0 +1,279 @@
+// Package tarfs provides the members of a tar archive opened
+// through a singleopen.FS as a file system, like the layers
+// of a container image, without unpacking the archive. The
+// archive is indexed once, members are read from the shared
+// archive file:
+//
+//	t, err := tarfs.Open(fsys, "layer.tar")
+//	defer t.Close()
+//	data, err := fs.ReadFile(t, "etc/os-release")
+//
+// Regular files, hard links to them and directories are
+// served. Other members, like symbolic links, are skipped.
+package tarfs
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dwlnetnl/singleopen"
+	"github.com/dwlnetnl/singleopen/internal/compat"
+)
+
+// FS is a file system of the members of a tar archive.
+type FS struct {
+	ra    io.ReaderAt
+	f     fs.File // archive
+	index map[string]*entry
+}
+
+var (
+	_ fs.StatFS    = (*FS)(nil)
+	_ fs.ReadDirFS = (*FS)(nil)
+)
+
+// entry is an indexed member.
+type entry struct {
+	fi       fs.FileInfo
+	off      int64    // offset of the data of a file
+	children []string // names of the entries of a directory
+}
+
+// Open opens and indexes the tar archive name of fsys. The
+// archive file must implement io.ReaderAt. The archive is
+// referenced until the FS is closed, members must not be read
+// after Close.
+func Open(fsys *singleopen.FS, name string) (*FS, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	ra, ok := f.(io.ReaderAt)
+	if !ok {
+		f.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: compat.ErrUnsupported}
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	index, err := buildIndex(io.NewSectionReader(ra, 0, fi.Size()))
+	if err != nil {
+		f.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &FS{ra: ra, f: f, index: index}, nil
+}
+
+// buildIndex reads the headers of the archive in r. Members
+// that appear later replace earlier ones.
+func buildIndex(r *io.SectionReader) (map[string]*entry, error) {
+	index := map[string]*entry{
+		".": {fi: dirInfo(".")},
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := cleanName(hdr.Name)
+		if !fs.ValidPath(name) || name == "." {
+			continue
+		}
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+			// the data follows the header
+			off, err := r.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return nil, err
+			}
+			index[name] = &entry{fi: hdr.FileInfo(), off: off}
+		case tar.TypeLink:
+			target, ok := index[cleanName(hdr.Linkname)]
+			if !ok || target.fi.IsDir() {
+				continue
+			}
+			index[name] = &entry{fi: linkInfo{target.fi, path.Base(name)}, off: target.off}
+		case tar.TypeDir:
+			if e, ok := index[name]; ok && e.fi.IsDir() {
+				e.fi = hdr.FileInfo()
+				continue
+			}
+			index[name] = &entry{fi: hdr.FileInfo()}
+		default:
+			continue
+		}
+		addParents(index, name)
+	}
+	for _, e := range index {
+		sort.Strings(e.children)
+		e.children = compact(e.children)
+	}
+	return index, nil
+}
+
+// addParents adds name to its parent directory, creating the
+// missing parents. A file that is a parent is replaced.
+func addParents(index map[string]*entry, name string) {
+	for name != "." {
+		dir := path.Dir(name)
+		e, ok := index[dir]
+		if !ok || !e.fi.IsDir() {
+			e = &entry{fi: dirInfo(dir)}
+			index[dir] = e
+		}
+		e.children = append(e.children, path.Base(name))
+		name = dir
+	}
+}
+
+func cleanName(name string) string {
+	return path.Clean(strings.TrimPrefix(name, "/"))
+}
+
+func (t *FS) lookup(op, name string) (*entry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	e, ok := t.index[name]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return e, nil
+}
+
+// Open opens the member name. A file is read from the shared
+// archive file.
+func (t *FS) Open(name string) (fs.File, error) {
+	e, err := t.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if e.fi.IsDir() {
+		return &dir{t: t, name: name, e: e}, nil
+	}
+	return &file{SectionReader: io.NewSectionReader(t.ra, e.off, e.fi.Size()), fi: e.fi}, nil
+}
+
+func (t *FS) Stat(name string) (fs.FileInfo, error) {
+	e, err := t.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return e.fi, nil
+}
+
+func (t *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	e, err := t.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !e.fi.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return t.entries(name, e.children), nil
+}
+
+// entries returns the directory entries of the children of
+// directory name.
+func (t *FS) entries(name string, children []string) []fs.DirEntry {
+	list := make([]fs.DirEntry, len(children))
+	for i, c := range children {
+		list[i] = fs.FileInfoToDirEntry(t.index[path.Join(name, c)].fi)
+	}
+	return list
+}
+
+// Close releases the reference to the archive file.
+func (t *FS) Close() error {
+	return t.f.Close()
+}
+
+// file is a member file.
+type file struct {
+	*io.SectionReader
+	fi fs.FileInfo
+}
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	return f.fi, nil
+}
+
+func (f *file) Close() error {
+	return nil
+}
+
+// dir is a member directory.
+type dir struct {
+	t    *FS
+	name string
+	e    *entry
+	off  int
+}
+
+var _ fs.ReadDirFile = (*dir)(nil)
+
+func (d *dir) Stat() (fs.FileInfo, error) {
+	return d.e.fi, nil
+}
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *dir) Close() error {
+	return nil
+}
+
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.e.children[d.off:]
+	if n > 0 && len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > 0 && n < len(rest) {
+		rest = rest[:n]
+	}
+	d.off += len(rest)
+	return d.t.entries(d.name, rest), nil
+}
+
+// dirInfo is the file info of a directory that is implied by
+// the members.
+type dirInfo string
+
+func (d dirInfo) Name() string       { return path.Base(string(d)) }
+func (d dirInfo) Size() int64        { return 0 }
+func (d dirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0o555 }
+func (d dirInfo) ModTime() time.Time { return time.Time{} }
+func (d dirInfo) IsDir() bool        { return true }
+func (d dirInfo) Sys() any           { return nil }
+
+// linkInfo is the file info of a hard link.
+type linkInfo struct {
+	fs.FileInfo
+	name string
+}
+
+func (l linkInfo) Name() string { return l.name }
+
+// compact removes consecutive duplicates of the sorted names.
+func compact(names []string) []string {
+	out := names[:0]
+	for i, name := range names {
+		if i == 0 || name != names[i-1] {
+			out = append(out, name)
+		}
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+package tarfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dwlnetnl/singleopen"
+)
+
+func archive(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	add := func(hdr *tar.Header, data string) {
+		hdr.Size = int64(len(data))
+		if hdr.Mode == 0 {
+			hdr.Mode = 0o644
+		}
+		if err := w.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(data))
+	}
+	add(&tar.Header{Name: "etc/", Typeflag: tar.TypeDir, Mode: 0o755}, "")
+	add(&tar.Header{Name: "etc/os-release", Typeflag: tar.TypeReg}, "old")
+	add(&tar.Header{Name: "./usr/bin/app", Typeflag: tar.TypeReg, Mode: 0o755}, "binary")
+	add(&tar.Header{Name: "usr/bin/link", Typeflag: tar.TypeLink, Linkname: "usr/bin/app"}, "")
+	add(&tar.Header{Name: "usr/bin/sym", Typeflag: tar.TypeSymlink, Linkname: "app"}, "")
+	add(&tar.Header{Name: "etc/os-release", Typeflag: tar.TypeReg}, "new")
+	add(&tar.Header{Name: "../escape", Typeflag: tar.TypeReg}, "escape")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestTar(t *testing.T) {
+	fsys, err := singleopen.New(fstest.MapFS{
+		"layer.tar": &fstest.MapFile{Data: archive(t)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tfs, err := Open(fsys, "layer.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tfs.Close()
+	if err := fstest.TestFS(tfs, "etc/os-release", "usr/bin/app", "usr/bin/link"); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"etc/os-release": "new",
+		"usr/bin/app":    "binary",
+		"usr/bin/link":   "binary",
+	} {
+		data, err := fs.ReadFile(tfs, name)
+		if err != nil || string(data) != want {
+			t.Errorf("%s: got %q, %v, want %q", name, data, err, want)
+		}
+	}
+	for _, name := range []string{"usr/bin/sym", "escape"} {
+		if _, err := tfs.Stat(name); err == nil {
+			t.Errorf("%s: member is served", name)
+		}
+	}
+	f, err := tfs.Open("usr/bin/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	buf := make([]byte, 3)
+	if _, err := f.(io.ReaderAt).ReadAt(buf, 3); err != nil || string(buf) != "ary" {
+		t.Errorf("got %q, %v, want ary", buf, err)
+	}
+	if s := fsys.Stats(); s.Misses != 1 {
+		t.Errorf("got %d misses, want archive opened once", s.Misses)
+	}
+}
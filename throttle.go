@@ -0,0 +1,90 @@
+package singleopen
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+)
+
+// RateLimiter limits the rate of bytes read, it's implemented
+// by *rate.Limiter of golang.org/x/time/rate.
+type RateLimiter interface {
+	// WaitN blocks until n bytes may be read. It's an error if
+	// n exceeds Burst.
+	WaitN(ctx context.Context, n int) error
+
+	// Burst returns the maximum number of bytes of WaitN.
+	Burst() int
+}
+
+// WithThrottle returns an Option that limits the rate of bytes
+// read from shared files in the directory prefix by l, so for
+// example a backup walking the file system can't starve other
+// readers. An empty prefix throttles all files. When prefixes
+// overlap, the longest one applies. Reads are throttled after
+// they complete, the kernel copies of WriteTo are disabled for
+// throttled files. Descriptors of WithDup are not throttled.
+// Reads by ReaderAtContext stop waiting when their context
+// is done. The error of the rate limiter is returned
+// by the read.
+func WithThrottle(prefix string, l RateLimiter) Option {
+	return func(f *FS) error {
+		if prefix == "." {
+			prefix = ""
+		}
+		if prefix != "" && !fs.ValidPath(prefix) {
+			return fmt.Errorf("singleopen: invalid throttle prefix %q", prefix)
+		}
+		if l == nil {
+			return fmt.Errorf("singleopen: nil rate limiter for prefix %q", prefix)
+		}
+		f.throttles = append(f.throttles, throttle{prefix, l})
+		return nil
+	}
+}
+
+// throttle is a rate limiter of a directory prefix.
+type throttle struct {
+	prefix string
+	l      RateLimiter
+}
+
+// throttleOf returns the rate limiter of name or nil.
+func (fsys *FS) throttleOf(name string) RateLimiter {
+	var match *throttle
+	for i, t := range fsys.throttles {
+		if !inDir(name, t.prefix) {
+			continue
+		}
+		if match == nil || len(t.prefix) > len(match.prefix) {
+			match = &fsys.throttles[i]
+		}
+	}
+	if match == nil {
+		return nil
+	}
+	return match.l
+}
+
+// wait waits until n bytes read from f are allowed or ctx is
+// done, it returns the error of the rate limiter.
+func (f *file) wait(ctx context.Context, n int) error {
+	if f.throttle == nil {
+		return nil
+	}
+	burst := f.throttle.Burst()
+	if burst < 1 {
+		burst = 1
+	}
+	for n > 0 {
+		m := n
+		if m > burst {
+			m = burst
+		}
+		if err := f.throttle.WaitN(ctx, m); err != nil {
+			return &fs.PathError{Op: "read", Path: f.name, Err: err}
+		}
+		n -= m
+	}
+	return nil
+}
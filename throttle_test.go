@@ -0,0 +1,117 @@
+package singleopen
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+// byteLimiter counts the bytes it's waited for.
+type byteLimiter struct {
+	mu    sync.Mutex
+	n     int
+	burst int
+}
+
+func (l *byteLimiter) WaitN(ctx context.Context, n int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if n > l.burst {
+		return fmt.Errorf("wait of %d bytes exceeds burst", n)
+	}
+	l.n += n
+	return nil
+}
+
+func (l *byteLimiter) Burst() int {
+	return l.burst
+}
+
+func TestThrottle(t *testing.T) {
+	all := &byteLimiter{burst: 4}
+	bulk := &byteLimiter{burst: 4}
+	fsys, err := New(fstest.MapFS{
+		"file":      &fstest.MapFile{Data: []byte("0123456789")},
+		"bulk/file": &fstest.MapFile{Data: []byte("0123456789")},
+		"bulkfile":  &fstest.MapFile{Data: []byte("0123456789")},
+	}, WithThrottle("", all), WithThrottle("bulk", bulk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"file", "bulk/file", "bulkfile"} {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil || string(data) != "0123456789" {
+			t.Errorf("%s: got %q, %v", name, data, err)
+		}
+	}
+	var buf bytes.Buffer
+	if _, err := fsys.CopyFile(&buf, "bulk/file"); err != nil {
+		t.Fatal(err)
+	}
+	if all.n != 20 || bulk.n != 20 {
+		t.Errorf("got %d and %d bytes throttled, want 20 and 20", all.n, bulk.n)
+	}
+	if _, err := New(fsys, WithThrottle("/abs", all)); err == nil {
+		t.Error("expected error for invalid prefix")
+	}
+}
+
+// ctxLimiter allows all reads until ctx is done.
+type ctxLimiter struct{}
+
+func (ctxLimiter) WaitN(ctx context.Context, n int) error {
+	return ctx.Err()
+}
+
+func (ctxLimiter) Burst() int {
+	return 4
+}
+
+func TestThrottleContext(t *testing.T) {
+	fsys, err := New(fstest.MapFS{
+		"file": &fstest.MapFile{Data: []byte("0123456789")},
+	}, WithThrottle("", ctxLimiter{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	f, err := fsys.OpenContext(ctx, "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	buf := make([]byte, 4)
+	r := f.(ReaderAtContext)
+	if _, err := r.ReadAtContext(ctx, buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	n, err := r.ReadAtContext(ctx, buf, 0)
+	if n != 4 || !errors.Is(err, context.Canceled) {
+		t.Errorf("got %d, %v, want 4, %v", n, err, context.Canceled)
+	}
+	if _, err := f.(io.ReaderAt).ReadAt(buf, 0); err != nil {
+		t.Errorf("ReadAt after cancel of OpenContext: %v", err)
+	}
+	if _, err := fsys.ReadAt("file", buf, 0); err != nil {
+		t.Errorf("ReadAt: %v", err)
+	}
+}
+
+func TestThrottleError(t *testing.T) {
+	fsys, err := New(fstest.MapFS{
+		"file": &fstest.MapFile{Data: []byte("0123456789")},
+	}, WithThrottle("", &byteLimiter{burst: 0}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.ReadFile(fsys, "file"); err == nil {
+		t.Error("expected error of rate limiter")
+	}
+}
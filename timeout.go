@@ -0,0 +1,103 @@
+package singleopen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+)
+
+// WithOpenTimeout returns an Option that bounds every open and
+// stat on the underlying file system to d. A call that takes
+// longer fails with a PathError wrapping an error that is
+// context.DeadlineExceeded, also for callers that share the
+// open. The call itself can't be stopped if the underlying
+// file system ignores the context, like on a hung network
+// mount: it's left running and a file it opens late is closed.
+func WithOpenTimeout(d time.Duration) Option {
+	return func(f *FS) error {
+		if d <= 0 {
+			return fmt.Errorf("singleopen: invalid open timeout %v", d)
+		}
+		f.openTimeout = d
+		return nil
+	}
+}
+
+// timeoutError is the error of a call that timed out, see
+// WithOpenTimeout.
+type timeoutError struct {
+	d time.Duration
+}
+
+func (e *timeoutError) Error() string {
+	return "singleopen: timed out after " + e.d.String()
+}
+
+func (e *timeoutError) Timeout() bool { return true }
+
+func (e *timeoutError) Is(target error) bool {
+	return target == context.DeadlineExceeded
+}
+
+// isTimeout reports whether err is of a call that timed out.
+func isTimeout(err error) bool {
+	var te *timeoutError
+	return errors.As(err, &te)
+}
+
+// openBase opens name on the underlying file system.
+func (fsys *FS) openBase(ctx context.Context, name string) (fs.File, error) {
+	return bounded(fsys, ctx, "open", name, func(ctx context.Context) (fs.File, error) {
+		return openContext(ctx, fsys.base(), name)
+	}, func(f fs.File) {
+		if err := f.Close(); err != nil {
+			fsys.closeError(&fs.PathError{Op: "close", Path: name, Err: err})
+		}
+	})
+}
+
+// statBase stats name on the underlying file system.
+func (fsys *FS) statBase(ctx context.Context, name string) (fs.FileInfo, error) {
+	return bounded(fsys, ctx, "stat", name, func(ctx context.Context) (fs.FileInfo, error) {
+		return statContext(ctx, fsys.base(), name)
+	}, nil)
+}
+
+// bounded returns the result of fn, or an error if it takes
+// longer than the open timeout. A result returned late is
+// passed to discard if it's not nil.
+func bounded[T any](fsys *FS, ctx context.Context, op, name string, fn func(context.Context) (T, error), discard func(T)) (T, error) {
+	if fsys.openTimeout == 0 {
+		return fn(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, fsys.openTimeout)
+	defer cancel()
+	type result struct {
+		v   T
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		v, err := fn(ctx)
+		ch <- result{v, err}
+	}()
+	select {
+	case res := <-ch:
+		return res.v, res.err
+	case <-ctx.Done():
+	}
+	go func() {
+		if res := <-ch; res.err == nil && discard != nil {
+			discard(res.v)
+		}
+	}()
+	var zero T
+	err := context.Cause(ctx)
+	if err == context.DeadlineExceeded {
+		err = &timeoutError{fsys.openTimeout}
+		fsys.debug("timed out", name, "op", op, "timeout", fsys.openTimeout)
+	}
+	return zero, &fs.PathError{Op: op, Path: name, Err: err}
+}
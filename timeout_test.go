@@ -0,0 +1,42 @@
+package singleopen
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestOpenTimeout(t *testing.T) {
+	bfs := blockFS{
+		FS:      fstest.MapFS{"file": &fstest.MapFile{Data: []byte("data")}},
+		release: make(chan struct{}),
+	}
+	fsys, err := New(bfs, WithOpenTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := fsys.Open("file")
+			var pe *fs.PathError
+			if !errors.Is(err, context.DeadlineExceeded) || !errors.As(err, &pe) {
+				t.Errorf("got %v, want PathError wrapping DeadlineExceeded", err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(bfs.release)
+	if b, err := fsys.ReadFile("file"); err != nil || string(b) != "data" {
+		t.Errorf("got %q, %v after release, want data", b, err)
+	}
+	if _, err := New(bfs, WithOpenTimeout(0)); err == nil {
+		t.Error("invalid timeout: got nil error")
+	}
+}
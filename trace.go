@@ -0,0 +1,39 @@
+package singleopen
+
+import (
+	"context"
+	"errors"
+)
+
+// OpenInfo describes how an open was satisfied.
+type OpenInfo struct {
+	Hit      bool // a shared file is reused
+	CacheHit bool // a file from the close cache is reused
+	Shared   bool // the underlying open is shared with concurrent callers
+}
+
+// A Tracer traces opens and reads of a FS.
+type Tracer interface {
+	// StartOpen is called when name is being opened. The
+	// returned context is used for the open and the returned
+	// function is called when the open is done.
+	StartOpen(ctx context.Context, name string) (context.Context, func(OpenInfo, error))
+
+	// Read is called after n bytes are read at offset off
+	// from name. The context is the one passed to
+	// ReadAtContext, see ReaderAtContext, or the background
+	// context. Reads are only traced for files that implement io.ReaderAt.
+	Read(ctx context.Context, name string, off int64, n int, err error)
+}
+
+// WithTracer returns an Option that traces opens and reads
+// with t.
+func WithTracer(t Tracer) Option {
+	return func(f *FS) error {
+		if t == nil {
+			return errors.New("singleopen: nil tracer")
+		}
+		f.tracer = t
+		return nil
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/dwlnetnl/singleopen/uring
+
+go 1.25.0
+
+require (
+	github.com/dwlnetnl/singleopen v0.0.0-20261015110229-08cca6dca4c9
+	golang.org/x/sys v0.30.0
+)
+
+require golang.org/x/sync v0.0.0-20210220032951-036812b2e83c // indirect
@@ -0,0 +1,298 @@
+package uring
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	opRead         = 22 // IORING_OP_READ
+	enterGetEvents = 1  // IORING_ENTER_GETEVENTS
+	offSQRing      = 0
+	offCQRing      = 0x8000000
+	offSQEs        = 0x10000000
+	sqeSize        = 64
+	cqeSize        = 16
+)
+
+var errClosed = errors.New("uring: closed")
+
+// params is struct io_uring_params.
+type params struct {
+	sqEntries    uint32
+	cqEntries    uint32
+	flags        uint32
+	sqThreadCPU  uint32
+	sqThreadIdle uint32
+	features     uint32
+	wqFD         uint32
+	resv         [3]uint32
+	sqOff        sqOffsets
+	cqOff        cqOffsets
+}
+
+// sqOffsets is struct io_sqring_offsets.
+type sqOffsets struct {
+	head, tail, ringMask, ringEntries, flags, dropped, array, resv1 uint32
+	userAddr                                                        uint64
+}
+
+// cqOffsets is struct io_cqring_offsets.
+type cqOffsets struct {
+	head, tail, ringMask, ringEntries, overflow, cqes, flags, resv1 uint32
+	userAddr                                                        uint64
+}
+
+// sqe is struct io_uring_sqe for a read.
+type sqe struct {
+	opcode      uint8
+	flags       uint8
+	ioprio      uint16
+	fd          int32
+	off         uint64
+	addr        uint64
+	len         uint32
+	rwFlags     uint32
+	userData    uint64
+	bufIndex    uint16
+	personality uint16
+	spliceFDIn  int32
+	addr3       uint64
+	pad         uint64
+}
+
+// cqe is struct io_uring_cqe.
+type cqe struct {
+	userData uint64
+	res      int32
+	flags    uint32
+}
+
+// request is a read submitted to the ring.
+type request struct {
+	fd   int32
+	buf  []byte
+	off  int64
+	res  int32
+	err  error
+	done chan struct{}
+}
+
+// ring is an io_uring with a goroutine that submits requests
+// in batches and completes them.
+type ring struct {
+	fd      int
+	entries uint32
+	sqMem   []byte
+	cqMem   []byte
+	sqeMem  []byte
+
+	sqHead, sqTail, sqMask *uint32
+	sqArray                unsafe.Pointer
+	cqHead, cqTail, cqMask *uint32
+	cqes                   unsafe.Pointer
+
+	reqs      chan *request
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newRing(entries uint32) (*ring, error) {
+	var p params
+	fd, _, errno := unix.Syscall(unix.SYS_IO_URING_SETUP, uintptr(entries), uintptr(unsafe.Pointer(&p)), 0)
+	if errno != 0 {
+		return nil, fmt.Errorf("uring: setup: %w (%w)", errno, errors.ErrUnsupported)
+	}
+	r := &ring{
+		fd:      int(fd),
+		entries: p.sqEntries,
+		reqs:    make(chan *request),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	err := r.mmap(&p)
+	if err != nil {
+		r.unmap()
+		unix.Close(r.fd)
+		return nil, err
+	}
+	go r.loop()
+	return r, nil
+}
+
+func (r *ring) mmap(p *params) error {
+	var err error
+	sqSize := int(p.sqOff.array + p.sqEntries*4)
+	r.sqMem, err = unix.Mmap(r.fd, offSQRing, sqSize, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE)
+	if err != nil {
+		return os.NewSyscallError("mmap", err)
+	}
+	cqSize := int(p.cqOff.cqes + p.cqEntries*cqeSize)
+	r.cqMem, err = unix.Mmap(r.fd, offCQRing, cqSize, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE)
+	if err != nil {
+		return os.NewSyscallError("mmap", err)
+	}
+	r.sqeMem, err = unix.Mmap(r.fd, offSQEs, int(p.sqEntries*sqeSize), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE)
+	if err != nil {
+		return os.NewSyscallError("mmap", err)
+	}
+	sq := unsafe.Pointer(&r.sqMem[0])
+	r.sqHead = (*uint32)(unsafe.Add(sq, p.sqOff.head))
+	r.sqTail = (*uint32)(unsafe.Add(sq, p.sqOff.tail))
+	r.sqMask = (*uint32)(unsafe.Add(sq, p.sqOff.ringMask))
+	r.sqArray = unsafe.Add(sq, p.sqOff.array)
+	cq := unsafe.Pointer(&r.cqMem[0])
+	r.cqHead = (*uint32)(unsafe.Add(cq, p.cqOff.head))
+	r.cqTail = (*uint32)(unsafe.Add(cq, p.cqOff.tail))
+	r.cqMask = (*uint32)(unsafe.Add(cq, p.cqOff.ringMask))
+	r.cqes = unsafe.Add(cq, p.cqOff.cqes)
+	return nil
+}
+
+func (r *ring) unmap() {
+	for _, b := range [][]byte{r.sqMem, r.cqMem, r.sqeMem} {
+		if b != nil {
+			unix.Munmap(b)
+		}
+	}
+}
+
+// readAt reads len(p) bytes from fd at off, short reads are
+// continued until p is full or the end of file.
+func (r *ring) readAt(fd int32, p []byte, off int64) (n int, err error) {
+	var pin runtime.Pinner
+	defer pin.Unpin()
+	for n < len(p) {
+		b := p[n:]
+		pin.Pin(&b[0])
+		req := &request{fd: fd, buf: b, off: off + int64(n), done: make(chan struct{})}
+		select {
+		case r.reqs <- req:
+		case <-r.stop:
+			return n, errClosed
+		}
+		<-req.done
+		switch {
+		case req.err != nil:
+			return n, req.err
+		case req.res < 0:
+			return n, os.NewSyscallError("read", unix.Errno(-req.res))
+		case req.res == 0:
+			return n, io.EOF
+		}
+		n += int(req.res)
+	}
+	return n, nil
+}
+
+// loop submits requests and completes them until the ring
+// is closed.
+func (r *ring) loop() {
+	defer close(r.done)
+	inflight := make(map[uint64]*request)
+	var id uint64
+	for {
+		var pending []*request
+		if len(inflight) == 0 {
+			select {
+			case req := <-r.reqs:
+				pending = append(pending, req)
+			case <-r.stop:
+				return
+			}
+		}
+		// batch requests that are waiting
+	drain:
+		for uint32(len(pending)+len(inflight)) < r.entries {
+			select {
+			case req := <-r.reqs:
+				pending = append(pending, req)
+			default:
+				break drain
+			}
+		}
+
+		tail := atomic.LoadUint32(r.sqTail)
+		mask := atomic.LoadUint32(r.sqMask)
+		for _, req := range pending {
+			id++
+			idx := tail & mask
+			e := (*sqe)(unsafe.Add(unsafe.Pointer(&r.sqeMem[0]), uintptr(idx)*sqeSize))
+			*e = sqe{
+				opcode:   opRead,
+				fd:       req.fd,
+				off:      uint64(req.off),
+				addr:     uint64(uintptr(unsafe.Pointer(&req.buf[0]))),
+				len:      uint32(min(len(req.buf), 1<<30)),
+				userData: id,
+			}
+			*(*uint32)(unsafe.Add(r.sqArray, uintptr(idx)*4)) = idx
+			inflight[id] = req
+			tail++
+		}
+		atomic.StoreUint32(r.sqTail, tail)
+
+		if err := r.enter(uint32(len(pending))); err != nil {
+			for id, req := range inflight {
+				req.err = err
+				close(req.done)
+				delete(inflight, id)
+			}
+			continue
+		}
+		r.reap(inflight)
+	}
+}
+
+// enter submits n entries and waits for a completion.
+func (r *ring) enter(n uint32) error {
+	for {
+		_, _, errno := unix.Syscall6(unix.SYS_IO_URING_ENTER, uintptr(r.fd), uintptr(n), 1, enterGetEvents, 0, 0)
+		switch errno {
+		case 0:
+			return nil
+		case unix.EINTR:
+			// submitted entries are consumed, only wait
+			n = 0
+			continue
+		default:
+			return os.NewSyscallError("io_uring_enter", errno)
+		}
+	}
+}
+
+// reap completes the requests of all available completions.
+func (r *ring) reap(inflight map[uint64]*request) {
+	head := atomic.LoadUint32(r.cqHead)
+	tail := atomic.LoadUint32(r.cqTail)
+	mask := atomic.LoadUint32(r.cqMask)
+	for ; head != tail; head++ {
+		c := (*cqe)(unsafe.Add(r.cqes, uintptr(head&mask)*cqeSize))
+		if req, ok := inflight[c.userData]; ok {
+			delete(inflight, c.userData)
+			req.res = c.res
+			close(req.done)
+		}
+	}
+	atomic.StoreUint32(r.cqHead, head)
+}
+
+func (r *ring) close() error {
+	err := errClosed
+	r.closeOnce.Do(func() {
+		close(r.stop)
+		<-r.done
+		r.unmap()
+		err = unix.Close(r.fd)
+	})
+	return err
+}
@@ -0,0 +1,23 @@
+//go:build !linux
+
+package uring
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ring is not available on this platform.
+type ring struct{}
+
+func newRing(entries uint32) (*ring, error) {
+	return nil, fmt.Errorf("uring: io_uring requires linux: %w", errors.ErrUnsupported)
+}
+
+func (r *ring) readAt(fd int32, p []byte, off int64) (int, error) {
+	return 0, errors.ErrUnsupported
+}
+
+func (r *ring) close() error {
+	return nil
+}
@@ -0,0 +1,97 @@
+// Package uring provides a file system whose files are read
+// through an io_uring on Linux. Use it as the underlying file
+// system of a singleopen.FS to submit concurrent positional
+// reads of shared files in batches:
+//
+//	u, err := uring.New(os.DirFS(dir), 256)
+//	fsys, err := singleopen.New(u)
+package uring
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// FS is a file system that reads files opened from an
+// underlying file system through a single io_uring.
+type FS struct {
+	fsys fs.FS
+	ring *ring
+}
+
+var _ fs.FS = (*FS)(nil)
+
+// New returns a FS that reads files of fsys that are an
+// *os.File through an io_uring with room for entries
+// concurrent reads. It returns an error wrapping
+// errors.ErrUnsupported if io_uring is not available.
+func New(fsys fs.FS, entries uint32) (*FS, error) {
+	if fsys == nil {
+		return nil, errors.New("uring: nil file system")
+	}
+	if entries == 0 {
+		return nil, errors.New("uring: invalid entry count 0")
+	}
+	r, err := newRing(entries)
+	if err != nil {
+		return nil, err
+	}
+	return &FS{fsys: fsys, ring: r}, nil
+}
+
+// Open opens the named file. If the file is an *os.File,
+// ReadAt of the returned file reads through the io_uring.
+func (u *FS) Open(name string) (fs.File, error) {
+	f, err := u.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if of, ok := f.(*os.File); ok {
+		return &file{File: of, ring: u.ring}, nil
+	}
+	return f, nil
+}
+
+// Close releases the io_uring. Reads of open files fail
+// after Close.
+func (u *FS) Close() error {
+	return u.ring.close()
+}
+
+// file is an *os.File that reads at offsets through a ring.
+type file struct {
+	*os.File
+	ring *ring
+}
+
+// Unwrap returns the underlying *os.File.
+func (f *file) Unwrap() fs.File {
+	return f.File
+}
+
+// ReadAt reads len(p) bytes at offset off through the ring.
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, &fs.PathError{Op: "read", Path: f.Name(), Err: fs.ErrInvalid}
+	}
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var (
+		n    int
+		rerr error
+	)
+	err = rc.Control(func(fd uintptr) {
+		n, rerr = f.ring.readAt(int32(fd), p, off)
+	})
+	if err != nil {
+		return 0, &fs.PathError{Op: "read", Path: f.Name(), Err: err}
+	}
+	if rerr != nil && rerr != io.EOF {
+		rerr = &fs.PathError{Op: "read", Path: f.Name(), Err: rerr}
+	}
+	return n, rerr
+}
@@ -0,0 +1,80 @@
+package uring
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/dwlnetnl/singleopen"
+)
+
+func TestReadAt(t *testing.T) {
+	dir := t.TempDir()
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+	if err := os.WriteFile(filepath.Join(dir, "file"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	u, err := New(os.DirFS(dir), 16)
+	if errors.Is(err, errors.ErrUnsupported) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer u.Close()
+	fsys, err := singleopen.New(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 64 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			off := int64(i * 1000)
+			p := make([]byte, 1500)
+			n, err := fsys.ReadAt("file", p, off)
+			want := data[off:min(off+1500, int64(len(data)))]
+			if err != nil && err != io.EOF || !bytes.Equal(p[:n], want) {
+				t.Errorf("read at %d: got %d bytes, %v", off, n, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := fsys.ReadFile("file")
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("got %d bytes, %v", len(got), err)
+	}
+	if _, err := fsys.ReadAt("missing", nil, 0); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestClose(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "file"), []byte("data"), 0o644)
+	u, err := New(os.DirFS(dir), 4)
+	if errors.Is(err, errors.ErrUnsupported) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := u.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := u.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.(io.ReaderAt).ReadAt(make([]byte, 4), 0); err == nil {
+		t.Error("expected error after Close")
+	}
+}
@@ -0,0 +1,59 @@
+package singleopen
+
+import (
+	"context"
+	"errors"
+)
+
+// WaitIdle waits until no file is referenced and then closes
+// the files in the close cache, including files that are
+// evicted but not closed yet. Pinned files are referenced, so
+// they must be unpinned first. Files opened while waiting are
+// waited for as well. It returns the error of ctx if it's
+// done first, otherwise the errors from closing files joined.
+func (fsys *FS) WaitIdle(ctx context.Context) error {
+	fsys.waiters.Add(1)
+	defer fsys.waiters.Add(-1)
+	var errs []error
+	for {
+		released := fsys.releasedChan()
+		if len(fsys.sharedFiles()) == 0 {
+			if err := fsys.Prune(); err != nil {
+				errs = append(errs, err)
+			}
+			if fsys.evicting.Load() == 0 && len(fsys.sharedFiles()) == 0 {
+				return errors.Join(errs...)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-released:
+		}
+	}
+}
+
+// releasedChan returns a channel that is closed when a file
+// is released. fsys.waiters must be incremented while waiting.
+func (fsys *FS) releasedChan() <-chan struct{} {
+	fsys.idleMu.Lock()
+	defer fsys.idleMu.Unlock()
+	if fsys.released == nil {
+		fsys.released = make(chan struct{})
+	}
+	return fsys.released
+}
+
+// wakeIdle wakes the callers of WaitIdle and evictInline
+// after a file is released.
+func (fsys *FS) wakeIdle() {
+	if fsys.waiters.Load() == 0 {
+		return
+	}
+	fsys.idleMu.Lock()
+	if fsys.released != nil {
+		close(fsys.released)
+		fsys.released = nil
+	}
+	fsys.idleMu.Unlock()
+}
@@ -0,0 +1,49 @@
+package singleopen
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestWaitIdle(t *testing.T) {
+	fsys, err := New(fstest.MapFS{
+		"a": &fstest.MapFile{},
+		"b": &fstest.MapFile{},
+	}, WithKeepLast(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := fsys.Open("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := fsys.Open("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := fsys.WaitIdle(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v while referenced, want DeadlineExceeded", err)
+	}
+
+	done := make(chan error)
+	go func() { done <- fsys.WaitIdle(context.Background()) }()
+	select {
+	case err := <-done:
+		t.Fatalf("returned %v while referenced", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	b.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if st := fsys.Stats(); st.OpenFiles != 0 || st.Cached != 0 {
+		t.Errorf("got %+v, want no open files", st)
+	}
+}
@@ -0,0 +1,308 @@
+package singleopen
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"sync"
+)
+
+// A Walker walks file trees of a FS.
+type Walker struct {
+	// Preopen reports whether the regular file at path is
+	// preopened while walking, see Preopen. Files are opened
+	// concurrently, see WithWarmConcurrency. If Preopen is
+	// nil, no files are preopened.
+	Preopen func(path string, d fs.DirEntry) bool
+
+	// Concurrency is the number of directories walked
+	// concurrently. If it's more than one, fn is called
+	// concurrently and in no particular order, except that the
+	// entries of a directory are passed in order by a single
+	// goroutine after the directory itself. Directories are
+	// opened within the limit of WithMaxOpen and closed before
+	// their entries are walked.
+	Concurrency int
+}
+
+// WalkDir walks the file tree rooted at root like fs.WalkDir,
+// calling fn for each file or directory in the tree. Unlike
+// fs.WalkDir it keeps the directories of the walked path open
+// until their entries are walked, so they are opened once when
+// shared with WithDirHandles, and it caches the file info of
+// the entries of a directory if enabled with WithStatCache, so
+// opening a file found by the walk doesn't stat it again.
+func WalkDir(fsys *FS, root string, fn fs.WalkDirFunc) error {
+	var w Walker
+	return w.WalkDir(fsys, root, fn)
+}
+
+// WalkDir walks the file tree rooted at root, see WalkDir.
+// Errors of preopening files are joined with the error of
+// the walk.
+func (w *Walker) WalkDir(fsys *FS, root string, fn fs.WalkDirFunc) error {
+	ws := walkState{Walker: w, fsys: fsys, fn: fn}
+	if w.Preopen != nil {
+		n := fsys.warmN
+		if n == 0 {
+			n = defaultWarmConcurrency
+		}
+		ws.sem = make(chan struct{}, n)
+	}
+	var err error
+	info, serr := fsys.Stat(root)
+	switch {
+	case serr != nil:
+		err = fn(root, nil, serr)
+	case w.Concurrency > 1:
+		err = ws.walkParallel(root, fs.FileInfoToDirEntry(info))
+	default:
+		err = ws.walk(root, fs.FileInfoToDirEntry(info))
+	}
+	if err == fs.SkipDir || err == fs.SkipAll {
+		err = nil
+	}
+	ws.wg.Wait()
+	if len(ws.errs) == 0 {
+		return err
+	}
+	return errors.Join(append([]error{err}, ws.errs...)...)
+}
+
+type walkState struct {
+	*Walker
+	fsys *FS
+	fn   fs.WalkDirFunc
+	sem  chan struct{} // limits preopens
+
+	wg   sync.WaitGroup
+	mu   sync.Mutex // protects errs
+	errs []error
+}
+
+// walk walks name like fs.WalkDir.
+func (ws *walkState) walk(name string, d fs.DirEntry) error {
+	if err := ws.fn(name, d, nil); err != nil || !d.IsDir() {
+		if err == fs.SkipDir && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+
+	entries, dir, err := ws.readDir(name)
+	if dir != nil {
+		defer dir.Close()
+	}
+	if err != nil {
+		err = ws.fn(name, d, err)
+		if err != nil {
+			if err == fs.SkipDir && d.IsDir() {
+				err = nil
+			}
+			return err
+		}
+	}
+	ws.storeStats(name, entries)
+
+	for _, d1 := range entries {
+		name1 := path.Join(name, d1.Name())
+		if ws.Preopen != nil && d1.Type().IsRegular() && ws.Preopen(name1, d1) {
+			ws.preopen(name1)
+		}
+		if err := ws.walk(name1, d1); err != nil {
+			if err == fs.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// readDir reads the entries of the directory name sorted by
+// name. The directory is returned open if it's opened.
+func (ws *walkState) readDir(name string) ([]fs.DirEntry, fs.File, error) {
+	f, err := ws.fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	rd, ok := f.(fs.ReadDirFile)
+	if !ok {
+		return nil, f, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not implemented")}
+	}
+	entries, err := rd.ReadDir(-1)
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, f, err
+}
+
+// storeStats caches the file info of the entries of the
+// directory name. Symbolic links are skipped, the info of an
+// entry describes the link itself.
+func (ws *walkState) storeStats(name string, entries []fs.DirEntry) {
+	if ws.fsys.statTTL == 0 {
+		return
+	}
+	for _, d := range entries {
+		if d.Type()&fs.ModeSymlink != 0 {
+			continue
+		}
+		if fi, err := d.Info(); err == nil {
+			ws.fsys.storeStat(path.Join(name, d.Name()), fi)
+		}
+	}
+}
+
+// preopen preopens name in the background.
+func (ws *walkState) preopen(name string) {
+	ws.sem <- struct{}{}
+	ws.wg.Add(1)
+	go func() {
+		defer ws.wg.Done()
+		defer func() { <-ws.sem }()
+		if err := ws.fsys.preopen(context.Background(), name); err != nil {
+			ws.mu.Lock()
+			ws.errs = append(ws.errs, err)
+			ws.mu.Unlock()
+		}
+	}()
+}
+
+// walkJob is a directory to walk in parallel.
+type walkJob struct {
+	name string
+	d    fs.DirEntry
+}
+
+// parallelWalk is the state of a parallel walk.
+type parallelWalk struct {
+	*walkState
+	mu      sync.Mutex
+	cond    sync.Cond
+	queue   []walkJob // LIFO, walks depth first
+	pending int       // directories queued or being walked
+	err     error     // stops the walk
+}
+
+// walkParallel walks the tree rooted at name with
+// Concurrency goroutines.
+func (ws *walkState) walkParallel(name string, d fs.DirEntry) error {
+	if err := ws.fn(name, d, nil); err != nil || !d.IsDir() {
+		return err
+	}
+	pw := &parallelWalk{walkState: ws}
+	pw.cond.L = &pw.mu
+	pw.push(walkJob{name, d})
+	var wg sync.WaitGroup
+	for i := 0; i < ws.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pw.worker()
+		}()
+	}
+	wg.Wait()
+	return pw.err
+}
+
+func (pw *parallelWalk) push(j walkJob) {
+	pw.mu.Lock()
+	pw.queue = append(pw.queue, j)
+	pw.pending++
+	pw.mu.Unlock()
+	pw.cond.Signal()
+}
+
+func (pw *parallelWalk) worker() {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	for {
+		for len(pw.queue) == 0 && pw.pending > 0 && pw.err == nil {
+			pw.cond.Wait()
+		}
+		if pw.pending == 0 || pw.err != nil {
+			return
+		}
+		j := pw.queue[len(pw.queue)-1]
+		pw.queue = pw.queue[:len(pw.queue)-1]
+		pw.mu.Unlock()
+		err := pw.walkDir(j.name, j.d)
+		pw.mu.Lock()
+		if err != nil && pw.err == nil {
+			pw.err = err
+		}
+		pw.pending--
+		if pw.pending == 0 || pw.err != nil {
+			pw.cond.Broadcast()
+		}
+	}
+}
+
+// stopped reports whether the walk is stopped.
+func (pw *parallelWalk) stopped() bool {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	return pw.err != nil
+}
+
+// walkDir walks the entries of the directory name, the
+// subdirectories are queued.
+func (pw *parallelWalk) walkDir(name string, d fs.DirEntry) error {
+	entries, err := pw.readDirLimited(name)
+	if err != nil {
+		if err = pw.fn(name, d, err); err != nil {
+			if err == fs.SkipDir {
+				err = nil
+			}
+			return err
+		}
+	}
+	pw.storeStats(name, entries)
+
+	for _, d1 := range entries {
+		if pw.stopped() {
+			return nil
+		}
+		name1 := path.Join(name, d1.Name())
+		if pw.Preopen != nil && d1.Type().IsRegular() && pw.Preopen(name1, d1) {
+			pw.preopen(name1)
+		}
+		err := pw.fn(name1, d1, nil)
+		switch {
+		case err == nil && d1.IsDir():
+			pw.push(walkJob{name1, d1})
+		case err == fs.SkipDir && d1.IsDir():
+		case err == fs.SkipDir:
+			return nil
+		case err != nil:
+			return err
+		}
+	}
+	return nil
+}
+
+// readDirLimited reads the directory name within the limit of
+// open files.
+func (ws *walkState) readDirLimited(name string) ([]fs.DirEntry, error) {
+	if !canStat(ws.fsys.base()) {
+		// Open counts files before their stat shows a
+		// directory, acquiring here could deadlock
+		entries, dir, err := ws.readDir(name)
+		if dir != nil {
+			dir.Close()
+		}
+		return entries, err
+	}
+	if err := ws.fsys.limiter().acquire(context.Background(), ws.fsys.evict); err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	defer ws.fsys.limiter().release()
+	entries, dir, err := ws.readDir(name)
+	if dir != nil {
+		dir.Close()
+	}
+	return entries, err
+}
@@ -0,0 +1,157 @@
+package singleopen
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestWalkDir(t *testing.T) {
+	mfs := fstest.MapFS{
+		"a/1":      &fstest.MapFile{},
+		"a/2":      &fstest.MapFile{},
+		"b/c/3":    &fstest.MapFile{},
+		"b/skip/4": &fstest.MapFile{},
+		"5":        &fstest.MapFile{},
+	}
+	walk := func(walkDir func(fs.WalkDirFunc) error) []string {
+		var names []string
+		err := walkDir(func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			names = append(names, name)
+			if name == "b/skip" {
+				return fs.SkipDir
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return names
+	}
+	fsys, err := New(mfs, WithKeepLast(10), WithStatCache(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := walk(func(fn fs.WalkDirFunc) error { return fs.WalkDir(mfs, ".", fn) })
+	got := walk(func(fn fs.WalkDirFunc) error { return WalkDir(fsys, ".", fn) })
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got walk %v, want %v", got, want)
+	}
+	if _, ok := fsys.cachedStat("b/c/3"); !ok {
+		t.Error("file info of entry is not cached")
+	}
+
+	w := Walker{Preopen: func(name string, d fs.DirEntry) bool {
+		return strings.HasPrefix(name, "a/")
+	}}
+	if err := w.WalkDir(fsys, ".", func(string, fs.DirEntry, error) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if n := fsys.CacheLen(); n != 2 {
+		t.Errorf("got %d cached files, want 2 preopened", n)
+	}
+}
+
+// peakFS tracks the number of open files.
+type peakFS struct {
+	fs.StatFS
+	active atomic.Int32
+	peak   atomic.Int32
+}
+
+func (p *peakFS) Open(name string) (fs.File, error) {
+	f, err := p.StatFS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	n := p.active.Add(1)
+	for m := p.peak.Load(); n > m && !p.peak.CompareAndSwap(m, n); m = p.peak.Load() {
+	}
+	return &peakFile{f, p}, nil
+}
+
+type peakFile struct {
+	fs.File
+	p *peakFS
+}
+
+func (f *peakFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	return f.File.(fs.ReadDirFile).ReadDir(n)
+}
+
+func (f *peakFile) Close() error {
+	f.p.active.Add(-1)
+	return f.File.Close()
+}
+
+func TestWalkDirParallel(t *testing.T) {
+	mfs := fstest.MapFS{}
+	for i := 0; i < 20; i++ {
+		for j := 0; j < 3; j++ {
+			mfs[fmt.Sprintf("%d/%d/file", i, j)] = &fstest.MapFile{}
+		}
+	}
+	pfs := &peakFS{StatFS: mfs}
+	fsys, err := New(pfs, WithMaxOpen(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		mu    sync.Mutex
+		names []string
+	)
+	w := Walker{Concurrency: 8}
+	err = w.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		names = append(names, name)
+		mu.Unlock()
+		if name == "0" {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []string
+	fs.WalkDir(mfs, ".", func(name string, d fs.DirEntry, err error) error {
+		want = append(want, name)
+		if name == "0" {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	sort.Strings(names)
+	sort.Strings(want)
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got walk %v, want %v", names, want)
+	}
+	if n := pfs.peak.Load(); n > 2 {
+		t.Errorf("got %d directories open at once, want at most 2", n)
+	}
+
+	errStop := errors.New("stop")
+	err = w.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if name == "5/1" {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("got %v, want stop error", err)
+	}
+}
@@ -0,0 +1,13 @@
+module github.com/dwlnetnl/singleopen/watch
+
+go 1.25.0
+
+require (
+	github.com/dwlnetnl/singleopen v0.0.0-20261015110229-08cca6dca4c9
+	github.com/fsnotify/fsnotify v1.10.1
+)
+
+require (
+	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c // indirect
+	golang.org/x/sys v0.30.0 // indirect
+)
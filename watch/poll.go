@@ -0,0 +1,29 @@
+package watch
+
+import (
+	"context"
+	"time"
+
+	"github.com/dwlnetnl/singleopen"
+)
+
+// Poll refreshes fsys every interval, checking at most
+// concurrency files at once, see (*singleopen.FS).Refresh.
+// It's meant for file systems that can't notify changes.
+// Errors of a refresh are passed to onError if not nil.
+//
+// Poll blocks until ctx is done.
+func Poll(ctx context.Context, fsys *singleopen.FS, interval time.Duration, concurrency int, onError func(error)) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			if err := fsys.Refresh(ctx, concurrency); err != nil && ctx.Err() == nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Package watch invalidates files of a singleopen.FS when
+// they change on disk.
+package watch
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/dwlnetnl/singleopen"
+)
+
+// Watch watches the directory tree rooted at root, the
+// directory the underlying file system of fsys is backed by
+// (for example os.DirFS(root)), and invalidates files of fsys
+// when they are written, renamed or removed. Directories that
+// are created are watched as well. If events are lost, all
+// files are invalidated.
+//
+// Watch blocks until ctx is done or the watcher fails.
+func Watch(ctx context.Context, fsys *singleopen.FS, root string) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if err := addTree(w, root); err != nil {
+		return err
+	}
+	testHookWatching()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			handle(w, fsys, root, ev)
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				fsys.InvalidateAll()
+				continue
+			}
+			return err
+		}
+	}
+}
+
+// testHookWatching is called when the tree is watched.
+var testHookWatching = func() {}
+
+func handle(w *fsnotify.Watcher, fsys *singleopen.FS, root string, ev fsnotify.Event) {
+	if ev.Has(fsnotify.Create) {
+		// watch new directories, errors are ignored as the
+		// directory may already be removed again
+		addTree(w, ev.Name)
+	}
+	if !ev.Has(fsnotify.Create | fsnotify.Write | fsnotify.Rename | fsnotify.Remove) {
+		return
+	}
+	rel, err := filepath.Rel(root, ev.Name)
+	if err != nil {
+		return
+	}
+	name := filepath.ToSlash(rel)
+	if !fs.ValidPath(name) {
+		return
+	}
+	fsys.Invalidate(name)
+}
+
+// addTree watches dir and all directories below it.
+func addTree(w *fsnotify.Watcher, dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return w.Add(path)
+		}
+		return nil
+	})
+}
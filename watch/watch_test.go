@@ -0,0 +1,89 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/dwlnetnl/singleopen"
+)
+
+func TestWatch(t *testing.T) {
+	root := t.TempDir()
+	name := filepath.Join(root, "file")
+	if err := os.WriteFile(name, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	closed := make(chan string, 1)
+	fsys, err := singleopen.New(os.DirFS(root), singleopen.WithKeepLast(4),
+		singleopen.WithHooks(singleopen.Hooks{
+			OnClose: func(name string, err error) {
+				select {
+				case closed <- name:
+				default:
+				}
+			},
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+	if err := fsys.Preopen("file"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watching := make(chan struct{})
+	testHookWatching = func() { close(watching) }
+	defer func() { testHookWatching = func() {} }()
+	go Watch(ctx, fsys, root)
+	<-watching
+
+	// rename over the file, the cached handle refers to the old file
+	tmp := filepath.Join(root, "file.tmp")
+	if err := os.WriteFile(tmp, []byte("new"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		t.Fatal(err)
+	}
+
+	// the invalidated file is closed
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("file is not invalidated")
+	}
+	data, err := fsys.ReadFile("file")
+	if err != nil || string(data) != "new" {
+		t.Errorf("got %q, %v, want new", data, err)
+	}
+}
+
+func TestPoll(t *testing.T) {
+	mfs := fstest.MapFS{
+		"file1": &fstest.MapFile{Data: []byte("old")},
+		"file2": &fstest.MapFile{Data: []byte("old")},
+	}
+	fsys, err := singleopen.New(mfs, singleopen.WithKeepLast(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+	if err := fsys.Preopen("file1", "file2"); err != nil {
+		t.Fatal(err)
+	}
+	mfs["file1"] = &fstest.MapFile{Data: []byte("changed")}
+	delete(mfs, "file2")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	Poll(ctx, fsys, time.Millisecond, 2, func(err error) { t.Error(err) })
+	if s := fsys.Stats(); s.Cached != 0 {
+		t.Errorf("got %d cached files, want changed files invalidated", s.Cached)
+	}
+}
@@ -0,0 +1,153 @@
+package singleopen
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"sync/atomic"
+
+	"github.com/dwlnetnl/singleopen/internal/compat"
+)
+
+// WriteFS is a file system that opens files for writing.
+type WriteFS interface {
+	fs.FS
+
+	// OpenFile opens the named file with flag like os.OpenFile.
+	OpenFile(name string, flag int, perm fs.FileMode) (fs.File, error)
+}
+
+// ErrWriting is returned (wrapped) by OpenFile when the file
+// is open for writing already.
+var ErrWriting = errors.New("singleopen: file is open for writing")
+
+// writeFlags are the flags of os.OpenFile that write.
+const writeFlags = os.O_WRONLY | os.O_RDWR | os.O_APPEND | os.O_CREATE | os.O_TRUNC
+
+// OpenFile opens the named file with flag like os.OpenFile.
+// Without flags that write, the file is opened by Open. To
+// write, the underlying file system must implement WriteFS.
+// A file has at most one writer, OpenFile returns an error
+// wrapping ErrWriting while it's open. Readers keep reading
+// the file they opened, when the writer is closed the file is
+// invalidated so later opens read what's written. The writer
+// implements the methods of the file opened by WriteFS that
+// write: io.Writer, io.WriterAt, io.Seeker and Sync.
+func (fsys *FS) OpenFile(name string, flag int, perm fs.FileMode) (fs.File, error) {
+	return fsys.OpenFileContext(context.Background(), name, flag, perm)
+}
+
+// OpenFileContext is like OpenFile but stops waiting for the
+// file to be opened by OpenContext, or for the lock of
+// WithFileLocks, when ctx is done.
+func (fsys *FS) OpenFileContext(ctx context.Context, name string, flag int, perm fs.FileMode) (fs.File, error) {
+	if flag&writeFlags == 0 {
+		return fsys.OpenContext(ctx, name)
+	}
+	if fsys.closed.Load() {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrClosed}
+	}
+	wfs, ok := fsys.base().(WriteFS)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: compat.ErrUnsupported}
+	}
+	key := fsys.key(name)
+	if err := fsys.lockWriter(name, key); err != nil {
+		return nil, err
+	}
+	if err := fsys.checkReaders(name); err != nil {
+		fsys.unlockWriter(key)
+		return nil, err
+	}
+	f, err := wfs.OpenFile(name, flag, perm)
+	if err != nil {
+		fsys.unlockWriter(key)
+		return nil, err
+	}
+	if err := fsys.lockWrite(ctx, name, f, lockExclusive); err != nil {
+		f.Close()
+		fsys.unlockWriter(key)
+		return nil, err
+	}
+	fsys.debug("open file for writing", name)
+	return &writeFile{File: f, fsys: fsys, name: name, key: key}, nil
+}
+
+// lockWriter makes the caller the writer of the file key, it
+// returns an error wrapping ErrWriting if it has a writer.
+func (fsys *FS) lockWriter(name, key string) error {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if _, ok := fsys.writers[key]; ok {
+		return &fs.PathError{Op: "open", Path: name, Err: ErrWriting}
+	}
+	if fsys.writers == nil {
+		fsys.writers = make(map[string]struct{})
+	}
+	fsys.writers[key] = struct{}{}
+	return nil
+}
+
+func (fsys *FS) unlockWriter(key string) {
+	fsys.mu.Lock()
+	delete(fsys.writers, key)
+	fsys.mu.Unlock()
+}
+
+// writeFile is the single writer of a file.
+type writeFile struct {
+	fs.File
+	fsys   *FS
+	name   string
+	key    string
+	closed atomic.Bool
+}
+
+// Unwrap returns the file opened on the underlying file system.
+func (f *writeFile) Unwrap() fs.File {
+	return f.File
+}
+
+func (f *writeFile) Write(p []byte) (int, error) {
+	w, ok := f.File.(io.Writer)
+	if !ok {
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: compat.ErrUnsupported}
+	}
+	return w.Write(p)
+}
+
+func (f *writeFile) WriteAt(p []byte, off int64) (int, error) {
+	w, ok := f.File.(io.WriterAt)
+	if !ok {
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: compat.ErrUnsupported}
+	}
+	return w.WriteAt(p, off)
+}
+
+func (f *writeFile) Seek(offset int64, whence int) (int64, error) {
+	s, ok := f.File.(io.Seeker)
+	if !ok {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: compat.ErrUnsupported}
+	}
+	return s.Seek(offset, whence)
+}
+
+func (f *writeFile) Sync() error {
+	s, ok := f.File.(interface{ Sync() error })
+	if !ok {
+		return &fs.PathError{Op: "sync", Path: f.name, Err: compat.ErrUnsupported}
+	}
+	return s.Sync()
+}
+
+// Close closes the file and invalidates it, see Invalidate.
+func (f *writeFile) Close() error {
+	if f.closed.Swap(true) {
+		return fs.ErrClosed
+	}
+	err := f.File.Close()
+	f.fsys.unlockWriter(f.key)
+	return errors.Join(err, f.fsys.Invalidate(f.name))
+}
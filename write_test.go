@@ -0,0 +1,68 @@
+//go:build go1.25
+
+package singleopen
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dwlnetnl/singleopen/internal/compat"
+)
+
+func TestOpenFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+	fsys, err := New(RootFS(root), WithKeepLast(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+	if b, err := fsys.ReadFile("file"); err != nil || string(b) != "old" {
+		t.Fatalf("got %q, %v, want old", b, err)
+	}
+
+	w, err := fsys.OpenFile("file", os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.OpenFile("file", os.O_WRONLY, 0); !errors.Is(err, ErrWriting) {
+		t.Errorf("second writer: got %v, want ErrWriting", err)
+	}
+	if _, err := w.(io.Writer).Write([]byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("second close: got %v, want ErrClosed", err)
+	}
+	if b, err := fsys.ReadFile("file"); err != nil || string(b) != "new" {
+		t.Errorf("got %q, %v after writing, want new", b, err)
+	}
+	w, err = fsys.OpenFile("file", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("writer after close: %v", err)
+	}
+	w.Close()
+
+	mfs, err := New(fstest.MapFS{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mfs.OpenFile("file", os.O_WRONLY, 0); !errors.Is(err, compat.ErrUnsupported) {
+		t.Errorf("got %v, want ErrUnsupported", err)
+	}
+}
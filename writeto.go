@@ -0,0 +1,106 @@
+package singleopen
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+var _ io.WriterTo = (*fileReaderAt)(nil)
+
+// copyBufPool holds buffers of WriteTo.
+var copyBufPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 32*1024)
+		return &b
+	},
+}
+
+// WriteTo writes the file from the current offset to w. If
+// the shared file is an *os.File and the platform supports
+// it, data is copied by the kernel when w is an *os.File or
+// sent by the kernel when w is a socket. Otherwise the file
+// is copied in chunks read at the offset, the shared file
+// offset is never used.
+func (f *fileReaderAt) WriteTo(w io.Writer) (n int64, err error) {
+	if err := f.errClosed("read"); err != nil {
+		return 0, err
+	}
+	off := f.offset
+	var handled bool
+	if f.throttle == nil {
+		f.swap.RLock()
+		n, handled, err = copyFileRange(w, f.File, off)
+		if !handled {
+			n, handled, err = sendFile(w, f.File, off)
+		}
+		f.swap.RUnlock()
+	}
+	if !handled {
+		n, err = f.copyTo(w, off)
+	}
+	f.offset += n
+	if f.fsys.tracer != nil {
+		f.fsys.tracer.Read(context.Background(), f.name, off, int(n), err)
+	}
+	return n, err
+}
+
+// osFile returns the *os.File of f, unwrapping files that
+// have an Unwrap method like those returned by Open.
+func osFile(f fs.File) (*os.File, bool) {
+	for {
+		switch ff := f.(type) {
+		case *os.File:
+			return ff, true
+		case interface{ Unwrap() fs.File }:
+			f = ff.Unwrap()
+		default:
+			return nil, false
+		}
+	}
+}
+
+// copyTo copies the file from off to w in chunks.
+func (f *fileReaderAt) copyTo(w io.Writer, off int64) (n int64, err error) {
+	bp := copyBufPool.Get().(*[]byte)
+	defer copyBufPool.Put(bp)
+	buf := *bp
+	for {
+		nr, rerr := f.file.readAt(context.Background(), buf, off+n)
+		if nr > 0 {
+			nw, werr := w.Write(buf[:nr])
+			n += int64(nw)
+			if werr != nil {
+				return n, werr
+			}
+			if nw < nr {
+				return n, io.ErrShortWrite
+			}
+		}
+		if rerr == io.EOF {
+			return n, nil
+		}
+		if rerr != nil {
+			return n, rerr
+		}
+	}
+}
+
+// CopyFile copies the named file to dst and returns the
+// number of bytes copied. Files are copied without copying
+// data to user space when possible, see WriteTo of the files
+// returned by Open.
+func (fsys *FS) CopyFile(dst io.Writer, name string) (int64, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if wt, ok := f.(io.WriterTo); ok {
+		return wt.WriteTo(dst)
+	}
+	return io.Copy(dst, f)
+}
@@ -0,0 +1,99 @@
+package singleopen
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteTo(t *testing.T) {
+	dir := t.TempDir()
+	data := bytes.Repeat([]byte("0123456789"), 100000)
+	if err := os.WriteFile(filepath.Join(dir, "file"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fsys := &FS{FS: os.DirFS(dir)}
+	f, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// copy to a buffer
+	f.(io.Seeker).Seek(10, io.SeekStart)
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, f)
+	if err != nil || n != int64(len(data)-10) || !bytes.Equal(buf.Bytes(), data[10:]) {
+		t.Errorf("got %d bytes, %v", n, err)
+	}
+
+	// copy to a TCP connection
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	got := make(chan []byte)
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			got <- nil
+			return
+		}
+		b, _ := io.ReadAll(c)
+		c.Close()
+		got <- b
+	}()
+	c, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.(io.Seeker).Seek(5, io.SeekStart)
+	n, err = f.(io.WriterTo).WriteTo(c)
+	c.Close()
+	if err != nil || n != int64(len(data)-5) {
+		t.Errorf("got %d bytes, %v", n, err)
+	}
+	if b := <-got; !bytes.Equal(b, data[5:]) {
+		t.Errorf("got %d bytes over TCP, want %d", len(b), len(data)-5)
+	}
+	if off, _ := f.(io.Seeker).Seek(0, io.SeekCurrent); off != int64(len(data)) {
+		t.Errorf("got offset %d, want %d", off, len(data))
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	dir := t.TempDir()
+	data := bytes.Repeat([]byte("0123456789"), 100000)
+	if err := os.WriteFile(filepath.Join(dir, "file"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fsys := &FS{FS: os.DirFS(dir)}
+	dst, err := os.Create(filepath.Join(dir, "copy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	dst.Write([]byte("prefix"))
+	n, err := fsys.CopyFile(dst, "file")
+	if err != nil || n != int64(len(data)) {
+		t.Errorf("got %d bytes, %v", n, err)
+	}
+	got, _ := os.ReadFile(filepath.Join(dir, "copy"))
+	if !bytes.Equal(got, append([]byte("prefix"), data...)) {
+		t.Errorf("got %d bytes in copy, want %d", len(got), len(data)+6)
+	}
+
+	var buf bytes.Buffer
+	if _, err := fsys.CopyFile(&buf, "file"); err != nil || !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("got %d bytes, %v", buf.Len(), err)
+	}
+	if _, err := fsys.CopyFile(&buf, "missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %v, want not exist", err)
+	}
+}
@@ -0,0 +1,68 @@
+// Package zipfs provides the members of a zip archive opened
+// through a singleopen.FS as a file system. The archive is
+// opened and its central directory is parsed once, members
+// are read from the shared archive file:
+//
+//	z, err := zipfs.Open(fsys, "assets.zip")
+//	defer z.Close()
+//	data, err := fs.ReadFile(z, "index.html")
+package zipfs
+
+import (
+	"archive/zip"
+	"io"
+	"io/fs"
+
+	"github.com/dwlnetnl/singleopen"
+	"github.com/dwlnetnl/singleopen/internal/compat"
+)
+
+// FS is a file system of the members of a zip archive.
+type FS struct {
+	r *zip.Reader
+	f fs.File // archive
+}
+
+var _ fs.FS = (*FS)(nil)
+
+// Open opens the zip archive name of fsys. The archive file
+// must implement io.ReaderAt. The archive is referenced until
+// the FS is closed, members must not be read after Close.
+func Open(fsys *singleopen.FS, name string) (*FS, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	ra, ok := f.(io.ReaderAt)
+	if !ok {
+		f.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: compat.ErrUnsupported}
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r, err := zip.NewReader(ra, fi.Size())
+	if err != nil {
+		f.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &FS{r: r, f: f}, nil
+}
+
+// Open opens the member name, see zip.Reader.Open. The member
+// is read from the shared archive file.
+func (z *FS) Open(name string) (fs.File, error) {
+	return z.r.Open(name)
+}
+
+// Files returns the members of the archive.
+func (z *FS) Files() []*zip.File {
+	return z.r.File
+}
+
+// Close releases the reference to the archive file.
+func (z *FS) Close() error {
+	return z.f.Close()
+}
@@ -0,0 +1,75 @@
+package zipfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dwlnetnl/singleopen"
+)
+
+func archive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, data := range files {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(data))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestZip(t *testing.T) {
+	data := archive(t, map[string]string{
+		"index.html":  "index",
+		"css/app.css": "body {}",
+	})
+	fsys, err := singleopen.New(fstest.MapFS{
+		"assets.zip": &fstest.MapFile{Data: data},
+		"broken.zip": &fstest.MapFile{Data: []byte("broken")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	z, err := Open(fsys, "assets.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(z, "index.html", "css/app.css"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		got, err := fs.ReadFile(z, "css/app.css")
+		if err != nil || string(got) != "body {}" {
+			t.Errorf("got %q, %v", got, err)
+		}
+	}
+	if s := fsys.Stats(); s.Misses != 1 || s.Shared != 1 {
+		t.Errorf("got %+v, want archive opened once", s)
+	}
+	if len(z.Files()) != 2 {
+		t.Errorf("got %d files, want 2", len(z.Files()))
+	}
+	if err := z.Close(); err != nil {
+		t.Error(err)
+	}
+	if s := fsys.Stats(); s.Shared != 0 {
+		t.Errorf("got %d shared files after close, want 0", s.Shared)
+	}
+
+	if _, err := Open(fsys, "broken.zip"); !errors.Is(err, zip.ErrFormat) {
+		t.Errorf("got %v, want zip.ErrFormat", err)
+	}
+	if s := fsys.Stats(); s.Shared != 0 {
+		t.Errorf("got %d shared files after failed open, want 0", s.Shared)
+	}
+}
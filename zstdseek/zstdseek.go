@@ -0,0 +1,270 @@
+// Package zstdseek provides random access to files in the
+// zstd seekable format opened through a singleopen.FS. The
+// seek table is read once, reads decompress the frames they
+// overlap out of the shared compressed file and cache them in
+// a block cache:
+//
+//	dec, err := zstd.NewReader(nil) // github.com/klauspost/compress/zstd
+//	bc, err := blockcache.New(os.DirFS(dir), 64<<10, 256<<20)
+//	fsys, err := singleopen.New(bc)
+//	f, err := zstdseek.Open(fsys, "table.parquet.zst", dec, bc)
+//	defer f.Close()
+//
+// The package doesn't decompress frames itself, a Decoder
+// does.
+package zstdseek
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/dwlnetnl/singleopen"
+	"github.com/dwlnetnl/singleopen/blockcache"
+)
+
+// ErrNotSeekable is returned (wrapped) when a file has no
+// valid seek table.
+var ErrNotSeekable = errors.New("zstdseek: not a seekable zstd file")
+
+const (
+	skippableMagic = 0x184D2A5E
+	seekableMagic  = 0x8F92EAB1
+	footerSize     = 9
+	checksumFlag   = 1 << 7
+	reservedFlags  = 0x7C
+)
+
+// MaxFrameSize is the largest decompressed size of a frame, a
+// seek table with larger frames is rejected so a corrupt table
+// can't make reads allocate unbounded memory.
+const MaxFrameSize = 1 << 30
+
+// Decoder decompresses zstd frames, it's implemented by
+// *zstd.Decoder of github.com/klauspost/compress/zstd.
+type Decoder interface {
+	// DecodeAll decompresses src and appends it to dst.
+	DecodeAll(src, dst []byte) ([]byte, error)
+}
+
+// Frame is a compressed frame of a seekable file.
+type Frame struct {
+	Offset       int64 // offset in the compressed file
+	Size         int64 // compressed size
+	Start        int64 // offset in the decompressed contents
+	Decompressed int64 // decompressed size
+	Checksum     uint32
+}
+
+// SeekTable is the seek table of a seekable file.
+type SeekTable struct {
+	Frames    []Frame
+	Size      int64 // size of the decompressed contents
+	Checksums bool  // frames have a checksum
+}
+
+// ReadSeekTable reads the seek table at the end of the file
+// of size bytes in r.
+func ReadSeekTable(r io.ReaderAt, size int64) (*SeekTable, error) {
+	var footer [footerSize]byte
+	if size < footerSize+8 {
+		return nil, ErrNotSeekable
+	}
+	if _, err := r.ReadAt(footer[:], size-footerSize); err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(footer[5:]) != seekableMagic {
+		return nil, ErrNotSeekable
+	}
+	n := int64(binary.LittleEndian.Uint32(footer[:4]))
+	desc := footer[4]
+	if desc&reservedFlags != 0 {
+		return nil, fmt.Errorf("%w: reserved bits set", ErrNotSeekable)
+	}
+	entrySize := int64(8)
+	if desc&checksumFlag != 0 {
+		entrySize = 12
+	}
+	tableSize := n*entrySize + footerSize
+	start := size - tableSize - 8 // of the skippable frame
+	if start < 0 {
+		return nil, fmt.Errorf("%w: seek table too large", ErrNotSeekable)
+	}
+	buf := make([]byte, 8+n*entrySize)
+	if _, err := r.ReadAt(buf, start); err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(buf) != skippableMagic ||
+		int64(binary.LittleEndian.Uint32(buf[4:])) != tableSize {
+		return nil, fmt.Errorf("%w: invalid seek table frame", ErrNotSeekable)
+	}
+
+	t := &SeekTable{Frames: make([]Frame, n), Checksums: entrySize == 12}
+	var off int64
+	for i := range t.Frames {
+		e := buf[8+int64(i)*entrySize:]
+		f := Frame{
+			Offset:       off,
+			Size:         int64(binary.LittleEndian.Uint32(e)),
+			Start:        t.Size,
+			Decompressed: int64(binary.LittleEndian.Uint32(e[4:])),
+		}
+		if t.Checksums {
+			f.Checksum = binary.LittleEndian.Uint32(e[8:])
+		}
+		if f.Decompressed > MaxFrameSize {
+			return nil, fmt.Errorf("%w: frame %d of %d bytes too large", ErrNotSeekable, i, f.Decompressed)
+		}
+		t.Frames[i] = f
+		off += f.Size
+		if off > start {
+			return nil, fmt.Errorf("%w: frames exceed file size", ErrNotSeekable)
+		}
+		t.Size += f.Decompressed
+	}
+	if off != start {
+		return nil, fmt.Errorf("%w: frames don't match file size", ErrNotSeekable)
+	}
+	return t, nil
+}
+
+// ReaderAt reads the decompressed contents of a seekable file.
+type ReaderAt struct {
+	r     io.ReaderAt
+	table *SeekTable
+	dec   Decoder
+
+	frames *blockcache.FS // nil if frames aren't cached
+	key    blockcache.Key // of the file, Block is the frame
+}
+
+var _ io.ReaderAt = (*ReaderAt)(nil)
+
+// space is the blockcache.Key space of decompressed frames.
+const space = "zstdseek"
+
+// NewReaderAt returns a ReaderAt of the seekable file of size
+// bytes in r. Decompressed frames aren't cached, see Open.
+func NewReaderAt(r io.ReaderAt, size int64, dec Decoder) (*ReaderAt, error) {
+	if dec == nil {
+		return nil, errors.New("zstdseek: nil decoder")
+	}
+	t, err := ReadSeekTable(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return &ReaderAt{r: r, table: t, dec: dec}, nil
+}
+
+// Size returns the size of the decompressed contents.
+func (r *ReaderAt) Size() int64 {
+	return r.table.Size
+}
+
+// SeekTable returns the seek table of the file.
+func (r *ReaderAt) SeekTable() *SeekTable {
+	return r.table
+}
+
+func (r *ReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("zstdseek: negative offset")
+	}
+	frames := r.table.Frames
+	i := sort.Search(len(frames), func(i int) bool {
+		return frames[i].Start+frames[i].Decompressed > off
+	})
+	for ; n < len(p) && i < len(frames); i++ {
+		data, err := r.frame(i)
+		if err != nil {
+			return n, err
+		}
+		n += copy(p[n:], data[off+int64(n)-frames[i].Start:])
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// frame returns the decompressed frame i.
+func (r *ReaderAt) frame(i int) ([]byte, error) {
+	if r.frames == nil {
+		return r.decode(i)
+	}
+	k := r.key
+	k.Block = int64(i)
+	return r.frames.Load(k, func() ([]byte, error) {
+		return r.decode(i)
+	})
+}
+
+// decode reads and decompresses frame i.
+func (r *ReaderAt) decode(i int) ([]byte, error) {
+	f := r.table.Frames[i]
+	src := make([]byte, f.Size)
+	if _, err := r.r.ReadAt(src, f.Offset); err != nil {
+		return nil, err
+	}
+	data, err := r.dec.DecodeAll(src, make([]byte, 0, f.Decompressed))
+	if err != nil {
+		return nil, fmt.Errorf("zstdseek: frame %d: %w", i, err)
+	}
+	if int64(len(data)) != f.Decompressed {
+		return nil, fmt.Errorf("zstdseek: frame %d: got %d bytes, want %d", i, len(data), f.Decompressed)
+	}
+	if r.table.Checksums && uint32(xxhash.Sum64(data)) != f.Checksum {
+		return nil, fmt.Errorf("zstdseek: frame %d: checksum mismatch", i)
+	}
+	return data, nil
+}
+
+// File is a seekable file opened for random access.
+type File struct {
+	*ReaderAt
+	f fs.File
+}
+
+// Open opens the seekable file name of fsys and reads its seek
+// table, see NewReaderAt. The file must implement io.ReaderAt.
+// The shared compressed file is referenced until the File is
+// closed. If frames isn't nil, decompressed frames are cached
+// in it like blocks of the file, so the Files of a file share
+// them and a changed file doesn't read frames of its old
+// contents.
+func Open(fsys *singleopen.FS, name string, dec Decoder, frames *blockcache.FS) (*File, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	ra, ok := f.(io.ReaderAt)
+	if !ok {
+		f.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.ErrUnsupported}
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	zr, err := NewReaderAt(ra, fi.Size(), dec)
+	if err != nil {
+		f.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	if frames != nil {
+		zr.frames = frames
+		zr.key = blockcache.Key{Space: space, Name: name, Size: fi.Size(), ModTime: fi.ModTime()}
+	}
+	return &File{ReaderAt: zr, f: f}, nil
+}
+
+// Close releases the reference to the compressed file.
+func (f *File) Close() error {
+	return f.f.Close()
+}
@@ -0,0 +1,165 @@
+package zstdseek
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/dwlnetnl/singleopen"
+	"github.com/dwlnetnl/singleopen/blockcache"
+)
+
+// prefixDecoder decodes frames that are the data prefixed by
+// "Z", it stands in for zstd.
+type prefixDecoder struct {
+	calls atomic.Int32
+}
+
+func (d *prefixDecoder) DecodeAll(src, dst []byte) ([]byte, error) {
+	d.calls.Add(1)
+	if len(src) == 0 || src[0] != 'Z' {
+		return nil, errors.New("invalid frame")
+	}
+	return append(dst, src[1:]...), nil
+}
+
+// seekable encodes data in frames of at most n bytes followed
+// by the seek table.
+func seekable(data []byte, n int, checksums bool) []byte {
+	var buf, table bytes.Buffer
+	frames := 0
+	for len(data) > 0 {
+		m := min(n, len(data))
+		buf.WriteByte('Z')
+		buf.Write(data[:m])
+		table.Write(binary.LittleEndian.AppendUint32(nil, uint32(m+1)))
+		table.Write(binary.LittleEndian.AppendUint32(nil, uint32(m)))
+		if checksums {
+			table.Write(binary.LittleEndian.AppendUint32(nil, uint32(xxhash.Sum64(data[:m]))))
+		}
+		data = data[m:]
+		frames++
+	}
+	var desc byte
+	if checksums {
+		desc = checksumFlag
+	}
+	table.Write(binary.LittleEndian.AppendUint32(nil, uint32(frames)))
+	table.WriteByte(desc)
+	table.Write(binary.LittleEndian.AppendUint32(nil, seekableMagic))
+	buf.Write(binary.LittleEndian.AppendUint32(nil, skippableMagic))
+	buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(table.Len())))
+	buf.Write(table.Bytes())
+	return buf.Bytes()
+}
+
+func TestReaderAt(t *testing.T) {
+	var data []byte
+	for i := range 5000 {
+		data = fmt.Appendf(data, "row %d\n", i)
+	}
+	corrupt := seekable(data, 1000, true)
+	corrupt[10] ^= 0xff
+	bc, err := blockcache.New(fstest.MapFS{
+		"data.zst":    &fstest.MapFile{Data: seekable(data, 1000, true)},
+		"nosum.zst":   &fstest.MapFile{Data: seekable(data, 777, false)},
+		"corrupt.zst": &fstest.MapFile{Data: corrupt},
+		"plain":       &fstest.MapFile{Data: data},
+	}, 4096, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys, err := singleopen.New(bc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"data.zst", "nosum.zst"} {
+		dec := new(prefixDecoder)
+		f, err := Open(fsys, name, dec, bc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Size() != int64(len(data)) {
+			t.Errorf("%s: got size %d, want %d", name, f.Size(), len(data))
+		}
+		for range 100 {
+			off := rand.IntN(len(data))
+			n := rand.IntN(3000)
+			want := data[off:min(off+n, len(data))]
+			got := make([]byte, n)
+			m, err := f.ReadAt(got, int64(off))
+			if !bytes.Equal(got[:m], want) {
+				t.Fatalf("%s: read of %d bytes at %d differs", name, n, off)
+			}
+			if m < n && err != io.EOF {
+				t.Fatalf("%s: got %v on short read, want EOF", name, err)
+			}
+		}
+		if _, err := f.ReadAt(make([]byte, len(data)), 0); err != nil {
+			t.Fatal(err)
+		}
+		// frames are decoded once
+		if calls, frames := int(dec.calls.Load()), len(f.SeekTable().Frames); calls != frames {
+			t.Errorf("%s: got %d decodes of %d frames", name, calls, frames)
+		}
+		f.Close()
+		// frames are shared with later opens
+		calls := dec.calls.Load()
+		f, err = Open(fsys, name, dec, bc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.ReadAt(make([]byte, len(data)), 0); err != nil {
+			t.Fatal(err)
+		}
+		if got := dec.calls.Load(); got != calls {
+			t.Errorf("%s: got %d decodes after reopen, want %d", name, got, calls)
+		}
+		f.Close()
+	}
+
+	f, err := Open(fsys, "corrupt.zst", new(prefixDecoder), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.ReadAt(make([]byte, 10), 0); err == nil {
+		t.Error("expected checksum error")
+	}
+	f.Close()
+	if _, err := Open(fsys, "plain", new(prefixDecoder), nil); !errors.Is(err, ErrNotSeekable) {
+		t.Errorf("got %v, want ErrNotSeekable", err)
+	}
+	if s := fsys.Stats(); s.Shared != 0 {
+		t.Errorf("got %d shared files, want 0", s.Shared)
+	}
+}
+
+func TestSeekTableSizes(t *testing.T) {
+	data := seekable([]byte("0123456789"), 4, false)
+	table := len(data) - footerSize - 3*8
+	for _, tc := range []struct {
+		name         string
+		size, decomp uint32
+	}{
+		{"frame exceeds file", 1 << 31, 4},
+		{"frame too large", 5, MaxFrameSize + 1},
+	} {
+		b := bytes.Clone(data)
+		binary.LittleEndian.PutUint32(b[table:], tc.size)
+		binary.LittleEndian.PutUint32(b[table+4:], tc.decomp)
+		if _, err := ReadSeekTable(bytes.NewReader(b), int64(len(b))); !errors.Is(err, ErrNotSeekable) {
+			t.Errorf("%s: got %v, want ErrNotSeekable", tc.name, err)
+		}
+	}
+	if _, err := ReadSeekTable(bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Error(err)
+	}
+}